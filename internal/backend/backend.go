@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
+	"os"
 	"vault-plugin-stellar-sign/internal/backend/paths"
 	"vault-plugin-stellar-sign/internal/backend/stellar"
 )
@@ -17,6 +18,7 @@ func Factory(ctx context.Context, conf *logical.BackendConfig) (logical.Backend,
 	if err = b.Setup(ctx, conf); err != nil {
 		return nil, err
 	}
+	b.manager.SetStorage(conf.StorageView)
 	return b, nil
 }
 
@@ -26,15 +28,26 @@ func newBackend() (*Backend, error) {
 	b.Backend = &framework.Backend{
 		Help: "",
 		PathsSpecial: &logical.Paths{
-			SealWrapStorage: []string{
-				"accounts/",
-			},
+			SealWrapStorage: stellar.SealWrapPaths,
 		},
-		Secrets:     []*framework.Secret{},
 		BackendType: logical.TypeLogical,
 	}
 
-	stellarManager := stellar.NewManager(b.Logger())
+	// The framework backend sends events through the EventsSender of the backend config
+	stellarManager := stellar.NewManager(b.Logger(), b.System, b.Backend, os.Getenv(stellar.KeyEncryptionSecretEnv))
+	b.manager = stellarManager
+	b.PeriodicFunc = stellarManager.Periodic
+	// Storage migrations only run where storage is writable
+	b.InitializeFunc = func(ctx context.Context, req *logical.InitializationRequest) error {
+		if !b.WriteSafeReplicationState() {
+			return nil
+		}
+		return stellarManager.Initialize(ctx, req)
+	}
+	b.Secrets = []*framework.Secret{
+		stellarManager.ChannelSecret(),
+		stellarManager.CredsSecret(),
+	}
 
 	b.Paths = framework.PathAppend(
 		stellarPaths(stellarManager),
@@ -46,12 +59,36 @@ func newBackend() (*Backend, error) {
 // Backend implements the Backend for this plugin
 type Backend struct {
 	*framework.Backend
+	manager *stellar.Manager
 }
 
 func stellarPaths(sm *stellar.Manager) []*framework.Path {
 	return []*framework.Path{
+		paths.Config(sm),
 		paths.CreateAndList(sm),
+		// Matched before accounts/<publicKey>, which would take "bulk" as a public key
+		paths.BulkCreate(sm),
 		paths.ReadAndDelete(sm),
 		paths.Sign(sm),
+		paths.Build(sm),
+		paths.Sequence(sm),
+		paths.ListChannelPools(sm),
+		paths.ChannelPool(sm),
+		paths.ChannelLease(sm),
+		paths.ChannelBuild(sm),
+		paths.ListRoles(sm),
+		paths.ReadWriteAndDeleteRole(sm),
+		paths.SignWithRole(sm),
+		paths.Creds(sm),
+		paths.CreateAndListApprovalRequests(sm),
+		paths.ReadApprovalRequest(sm),
+		paths.ApproveRequest(sm),
+		paths.RejectRequest(sm),
+		paths.MuxedAddress(sm),
+		paths.History(sm),
+		paths.UnusedKeysReport(sm),
+		paths.Submission(sm),
+		paths.ListPolicies(sm),
+		paths.ReadWriteAndDeletePolicy(sm),
 	}
 }
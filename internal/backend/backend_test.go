@@ -2,10 +2,30 @@ package backend
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/armon/go-metrics"
 	"github.com/hashicorp/vault/sdk/logical"
 	"github.com/stellar/go/keypair"
+	"github.com/stellar/go/network"
+	"github.com/stellar/go/strkey"
+	"github.com/stellar/go/txnbuild"
+	"github.com/stellar/go/xdr"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+	"vault-plugin-stellar-sign/internal/backend/paths"
+	"vault-plugin-stellar-sign/internal/backend/stellar"
 )
 
 // TestCreateStellarAccountWithProvidedSecretKey tests account creation with a provided secret key.
@@ -22,7 +42,7 @@ func TestCreateStellarAccountWithProvidedSecretKey(t *testing.T) {
 	}
 
 	req := &logical.Request{
-		Operation: logical.UpdateOperation,
+		Operation: logical.CreateOperation,
 		Path:      "accounts",
 		Data:      data,
 		Storage:   storage,
@@ -40,7 +60,7 @@ func TestCreateStellarAccountWithoutProvidedSecretKey(t *testing.T) {
 
 	// Create a request without a provided secret key
 	req := &logical.Request{
-		Operation: logical.UpdateOperation,
+		Operation: logical.CreateOperation,
 		Path:      "accounts",
 		Data:      map[string]interface{}{},
 		Storage:   storage,
@@ -80,7 +100,7 @@ func TestListStellarAccounts(t *testing.T) {
 	// Create a couple of Stellar accounts
 	for i := 0; i < 2; i++ {
 		req := &logical.Request{
-			Operation: logical.UpdateOperation,
+			Operation: logical.CreateOperation,
 			Path:      "accounts",
 			Data:      map[string]interface{}{},
 			Storage:   storage,
@@ -103,15 +123,396 @@ func TestListStellarAccounts(t *testing.T) {
 	assert.Len(t, listResp.Data["keys"], 2)
 }
 
+// TestListStellarAccountPages tests paginating, sorting and filtering the account listing.
+func TestListStellarAccountPages(t *testing.T) {
+	b, storage := getTestBackendAndStorage(t)
+
+	// Accounts are created in the order of their names, except for an unnamed one
+	created := []string{}
+	for _, name := range []string{"deposit-a", "deposit-b", "deposit-c", "", "treasury"} {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "accounts",
+			Data:      map[string]interface{}{"name": name},
+			Storage:   storage,
+		})
+		require.NoError(t, err)
+		created = append(created, resp.Data["public_key"].(string))
+	}
+	list := func(data map[string]interface{}) []string {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.ListOperation,
+			Path:      "accounts",
+			Data:      data,
+			Storage:   storage,
+		})
+		require.NoError(t, err)
+		keys, _ := resp.Data["keys"].([]string)
+		return keys
+	}
+
+	sorted := append([]string{}, created...)
+	sort.Strings(sorted)
+	assert.Equal(t, sorted, list(nil))
+	assert.Equal(t, created, list(map[string]interface{}{"sort": "created_at"}))
+	assert.Equal(t, []string{created[3], created[0], created[1], created[2], created[4]}, list(map[string]interface{}{"sort": "name"}))
+
+	// Pages follow the last account of the previous page
+	assert.Equal(t, created[:2], list(map[string]interface{}{"sort": "created_at", "limit": 2}))
+	assert.Equal(t, created[2:4], list(map[string]interface{}{"sort": "created_at", "limit": 2, "after": created[1]}))
+	assert.Equal(t, created[4:], list(map[string]interface{}{"sort": "created_at", "limit": 2, "after": created[3]}))
+	assert.Equal(t, sorted[3:], list(map[string]interface{}{"after": sorted[2]}))
+
+	// Prefixes match names and addresses
+	assert.Equal(t, created[:3], list(map[string]interface{}{"sort": "name", "prefix": "deposit-"}))
+	assert.Equal(t, []string{created[3]}, list(map[string]interface{}{"prefix": created[3][:20]}))
+
+	// Renamed and deleted accounts leave the index
+	_, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "accounts/" + created[4],
+		Data:      map[string]interface{}{"name": "deposit-d"},
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	_, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.DeleteOperation,
+		Path:      "accounts/" + created[0],
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{created[1], created[2], created[4]}, list(map[string]interface{}{"sort": "name", "prefix": "deposit-"}))
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ListOperation,
+		Path:      "accounts",
+		Data:      map[string]interface{}{"prefix": "deposit-d", "detailed": true},
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "deposit-d", resp.Data["key_info"].(map[string]interface{})[created[4]].(map[string]interface{})["name"])
+
+	// Listings continue after an account deleted since the previous page
+	assert.Equal(t, []string{created[1], created[2], created[4]}, list(map[string]interface{}{"sort": "name", "after": created[0]}))
+	assert.Equal(t, created[1:3], list(map[string]interface{}{"sort": "created_at", "limit": 2, "after": created[0]}))
+
+	unknown, _ := keypair.Random()
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ListOperation,
+		Path:      "accounts",
+		Data:      map[string]interface{}{"sort": "name", "after": unknown.Address()},
+		Storage:   storage,
+	})
+	assert.ErrorIs(t, err, logical.ErrInvalidRequest)
+	assert.Equal(t, "invalid_request", resp.Data["data"].(map[string]interface{})["error_code"])
+
+	// The index is listed once per page, whatever the limit and the cursor
+	counting := &listCountingStorage{Storage: storage}
+	for _, data := range []map[string]interface{}{
+		nil,
+		{"limit": 1},
+		{"sort": "name", "limit": 1, "after": created[1]},
+		{"sort": "created_at", "prefix": "deposit-", "after": created[0]},
+	} {
+		counting.lists = 0
+		_, err = b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.ListOperation,
+			Path:      "accounts",
+			Data:      data,
+			Storage:   counting,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 1, counting.lists, "%v", data)
+	}
+
+	// Accounts stored before the index are indexed by the storage migration
+	legacy, _ := keypair.Random()
+	entry, err := logical.StorageEntryJSON("stellar/accounts/"+legacy.Address(), map[string]interface{}{
+		"public_key": legacy.Address(),
+		"secret_key": legacy.Seed(),
+	})
+	require.NoError(t, err)
+	require.NoError(t, storage.Put(context.Background(), entry))
+	assert.NotContains(t, list(nil), legacy.Address())
+	require.NoError(t, b.Initialize(context.Background(), &logical.InitializationRequest{Storage: storage}))
+	assert.Contains(t, list(nil), legacy.Address())
+}
+
+// listCountingStorage counts the List calls made to the storage it wraps
+type listCountingStorage struct {
+	logical.Storage
+	lists int
+}
+
+func (s *listCountingStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	s.lists++
+	return s.Storage.List(ctx, prefix)
+}
+
+// TestBulkCreateAccounts tests creating accounts in bulk with their funding transactions.
+func TestBulkCreateAccounts(t *testing.T) {
+	b, storage := getTestBackendAndStorage(t)
+	funder, _ := keypair.Random()
+	bulk := func(data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "accounts/bulk",
+			Data:      data,
+			Storage:   storage,
+		})
+	}
+
+	resp, err := bulk(map[string]interface{}{"count": 3, "name": "deposit", "max_signatures": 5})
+	require.NoError(t, err)
+	publicKeys := resp.Data["public_keys"].([]string)
+	require.Len(t, publicKeys, 3)
+	assert.NotContains(t, resp.Data, "transactions")
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ListOperation,
+		Path:      "accounts",
+		Data:      map[string]interface{}{"prefix": "deposit", "detailed": true},
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, publicKeys, resp.Data["keys"])
+	info := resp.Data["key_info"].(map[string]interface{})[publicKeys[0]].(map[string]interface{})
+	assert.Equal(t, "deposit", info["name"])
+	assert.EqualValues(t, 5, info["max_signatures"])
+	assert.Equal(t, []string{publicKeys[0]}, info["allowed_sources"])
+
+	// Sponsored accounts are created in as many transactions as the operation limit requires
+	resp, err = bulk(map[string]interface{}{
+		"count":           45,
+		"funding_account": funder.Address(),
+		"network":         "Testnet",
+		"sponsored":       true,
+		"sequence":        "100",
+	})
+	require.NoError(t, err)
+	transactions := resp.Data["transactions"].([]map[string]interface{})
+	require.Len(t, transactions, 3)
+	for i, expected := range []struct {
+		sequence int64
+		accounts int
+	}{{100, 19}, {101, 19}, {102, 7}} {
+		assert.Len(t, transactions[i]["public_keys"], expected.accounts)
+		envelope, err := txnbuild.TransactionFromXDR(transactions[i]["transaction"].(string))
+		require.NoError(t, err)
+		tx, _ := envelope.Transaction()
+		assert.Equal(t, expected.sequence, tx.SequenceNumber())
+		assert.Equal(t, funder.Address(), tx.SourceAccount().AccountID)
+		assert.Len(t, tx.Operations(), 3*expected.accounts)
+		// The accounts signed, the signature of the funding account is left to the caller
+		assert.Len(t, tx.Signatures(), expected.accounts)
+		hash, err := tx.HashHex(network.TestNetworkPassphrase)
+		require.NoError(t, err)
+		assert.Equal(t, hash, transactions[i]["transaction_hash"])
+	}
+
+	resp, err = bulk(map[string]interface{}{
+		"count":            2,
+		"funding_account":  funder.Address(),
+		"network":          "Testnet",
+		"starting_balance": "2",
+		"sequence":         "200",
+	})
+	require.NoError(t, err)
+	transactions = resp.Data["transactions"].([]map[string]interface{})
+	require.Len(t, transactions, 1)
+	envelope, err := txnbuild.TransactionFromXDR(transactions[0]["transaction"].(string))
+	require.NoError(t, err)
+	tx, _ := envelope.Transaction()
+	require.Len(t, tx.Operations(), 2)
+	assert.Equal(t, resp.Data["public_keys"].([]string)[1], tx.Operations()[1].(*txnbuild.CreateAccount).Destination)
+	assert.Empty(t, tx.Signatures())
+
+	// Invalid requests create no account
+	for _, data := range []map[string]interface{}{
+		{"count": 0},
+		{"count": 1001},
+		{"count": 2, "funding_account": funder.Address(), "network": "Testnet"},
+		{"count": 2, "funding_account": funder.Address(), "network": "Testnet", "sponsored": true, "starting_balance": "2"},
+		{"count": 2, "funding_account": funder.Address(), "network": "Testnet", "starting_balance": "lots"},
+		{"count": 2, "funding_account": funder.Address(), "network": "Mainnet", "starting_balance": "2"},
+		// The sequence numbers of funding accounts outside the mount are not tracked
+		{"count": 2, "funding_account": funder.Address(), "network": "Testnet", "starting_balance": "2"},
+	} {
+		_, err = bulk(data)
+		assert.ErrorIs(t, err, logical.ErrInvalidRequest, data)
+	}
+	_, err = bulk(map[string]interface{}{"count": 2, "role": "missing"})
+	assert.ErrorContains(t, err, `role "missing" does not exist`)
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ListOperation,
+		Path:      "accounts",
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	assert.Len(t, resp.Data["keys"], 50)
+
+	// The accounts are added to the role
+	_, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "roles/deposits",
+		Data:      map[string]interface{}{"accounts": publicKeys[0]},
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	resp, err = bulk(map[string]interface{}{"count": 2, "role": "deposits"})
+	require.NoError(t, err)
+	added := resp.Data["public_keys"].([]string)
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "roles/deposits",
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, append([]string{publicKeys[0]}, added...), resp.Data["accounts"])
+}
+
+// TestCreateFundedAccount tests creating accounts on-ledger from a stored funding account.
+func TestCreateFundedAccount(t *testing.T) {
+	b, storage := getTestBackendAndStorage(t)
+	funder := createTestAccount(t, b, storage, map[string]interface{}{})
+
+	// Without Horizon, the sequence number of the funding account is set explicitly
+	_, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "accounts/" + funder + "/sequence",
+		Data:      map[string]interface{}{"network": "Testnet", "next_sequence": "10"},
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	create := func(data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "accounts",
+			Data:      data,
+			Storage:   storage,
+		})
+	}
+	fundingTx := func(resp *logical.Response) *txnbuild.Transaction {
+		envelope, err := txnbuild.TransactionFromXDR(resp.Data["funding_transaction"].(string))
+		require.NoError(t, err)
+		tx, _ := envelope.Transaction()
+		return tx
+	}
+
+	// Sponsored reserves are signed by both accounts
+	resp, err := create(map[string]interface{}{"funding_account": funder, "network": "Testnet", "sponsored": true})
+	require.NoError(t, err)
+	sponsored := resp.Data["public_key"].(string)
+	assert.NotContains(t, resp.Data, "submission_status")
+	tx := fundingTx(resp)
+	assert.Equal(t, int64(10), tx.SequenceNumber())
+	assert.Equal(t, funder, tx.SourceAccount().AccountID)
+	require.Len(t, tx.Operations(), 3)
+	assert.Equal(t, sponsored, tx.Operations()[0].(*txnbuild.BeginSponsoringFutureReserves).SponsoredID)
+	assert.Equal(t, "0.0000000", tx.Operations()[1].(*txnbuild.CreateAccount).Amount)
+	assert.Equal(t, sponsored, tx.Operations()[2].GetSourceAccount())
+	hash, err := tx.Hash(network.TestNetworkPassphrase)
+	require.NoError(t, err)
+	require.Len(t, tx.Signatures(), 2)
+	for i, signer := range []string{funder, sponsored} {
+		kp, _ := keypair.ParseAddress(signer)
+		assert.NoError(t, kp.Verify(hash[:], tx.Signatures()[i].Signature))
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "accounts/" + sponsored,
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, funder, resp.Data["funded_by"])
+	assert.Equal(t, hex.EncodeToString(hash[:]), resp.Data["funding_transaction"])
+	// The co-signature does not count against the quota of the new account
+	assert.EqualValues(t, 0, resp.Data["signature_count"])
+
+	resp, err = create(map[string]interface{}{"funding_account": funder, "network": "Testnet", "starting_balance": "5"})
+	require.NoError(t, err)
+	tx = fundingTx(resp)
+	assert.Equal(t, int64(11), tx.SequenceNumber())
+	require.Len(t, tx.Operations(), 1)
+	assert.Equal(t, resp.Data["public_key"], tx.Operations()[0].(*txnbuild.CreateAccount).Destination)
+	assert.Equal(t, "5.0000000", tx.Operations()[0].(*txnbuild.CreateAccount).Amount)
+	assert.Len(t, tx.Signatures(), 1)
+
+	// With Horizon configured, the funding transaction is submitted
+	horizonDown := false
+	horizonServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if horizonDown {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		require.NoError(t, r.ParseForm())
+		envelope, err := txnbuild.TransactionFromXDR(r.PostForm.Get("tx"))
+		require.NoError(t, err)
+		inner, _ := envelope.Transaction()
+		hash, _ := inner.HashHex(network.TestNetworkPassphrase)
+		_, _ = fmt.Fprintf(w, `{"hash": %q, "ledger": 1234, "successful": true}`, hash)
+	}))
+	defer horizonServer.Close()
+	_, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Data:      map[string]interface{}{"horizon_urls": map[string]interface{}{"Testnet": horizonServer.URL}},
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	resp, err = create(map[string]interface{}{"funding_account": funder, "network": "Testnet", "sponsored": true})
+	require.NoError(t, err)
+	assert.Equal(t, "success", resp.Data["submission_status"])
+	assert.EqualValues(t, 1234, resp.Data["ledger"])
+
+	// A signed funding transaction may still land, the account is kept when its submission fails
+	horizonDown = true
+	resp, err = create(map[string]interface{}{"funding_account": funder, "network": "Testnet", "sponsored": true})
+	status, err := responseStatus(resp, err)
+	assert.Equal(t, http.StatusGatewayTimeout, status)
+	assert.Equal(t, "submission_pending", resp.Data["data"].(map[string]interface{})["error_code"])
+	assert.ErrorContains(t, err, "was created but submitting its funding transaction failed")
+
+	// Accounts whose funding fails before signing are not kept
+	expired := createTestAccount(t, b, storage, map[string]interface{}{"not_after": "2020-01-01T00:00:00Z"})
+	_, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "accounts/" + expired + "/sequence",
+		Data:      map[string]interface{}{"network": "Testnet", "next_sequence": "10"},
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	for _, tc := range []struct {
+		data   map[string]interface{}
+		status int
+	}{
+		{map[string]interface{}{"funding_account": funder, "network": "Testnet"}, http.StatusBadRequest},
+		{map[string]interface{}{"funding_account": strings.ToLower(sponsored), "network": "Testnet", "sponsored": true}, http.StatusBadRequest},
+		{map[string]interface{}{"funding_account": testTxSourceAccount, "network": "Testnet", "sponsored": true}, http.StatusNotFound},
+		{map[string]interface{}{"funding_account": expired, "network": "Testnet", "sponsored": true}, http.StatusForbidden},
+	} {
+		status, _ := responseStatus(create(tc.data))
+		assert.Equal(t, tc.status, status, tc.data)
+	}
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ListOperation,
+		Path:      "accounts",
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	assert.Len(t, resp.Data["keys"], 6)
+}
+
 func TestSignStellarTx(t *testing.T) {
 	b, storage := getTestBackendAndStorage(t)
 
-	// Create a couple of Stellar accounts
+	// Create a couple of Stellar accounts that may co-sign for the transaction source account
 	for i := 0; i < 2; i++ {
 		req := &logical.Request{
-			Operation: logical.UpdateOperation,
+			Operation: logical.CreateOperation,
 			Path:      "accounts",
-			Data:      map[string]interface{}{},
+			Data:      map[string]interface{}{"allowed_sources": testTxSourceAccount},
 			Storage:   storage,
 		}
 
@@ -134,7 +535,7 @@ func TestSignStellarTx(t *testing.T) {
 	// Create a request to sign a transaction
 	for i := 0; i < 2; i++ {
 		data := map[string]interface{}{
-			"transaction": "AAAAAgAAAAATozPrNDRTqLO2WUflkFsbKLSQN79/VlhRpv7MMzePdgAAAGQAAMGGAAAAAQAAAAEAAAAAAAAAAAAAAABlhHryAAAAAAAAAAEAAAABAAAAABOjM+s0NFOos7ZZR+WQWxsotJA3v39WWFGm/swzN492AAAAAQAAAAB69J8A290AJGAqNy4f0QIXBG4NoPQm7B+vDdeR0AvXRQAAAAAAAAACVAvkAAAAAAAAAAAA",
+			"transaction": testTransaction(t),
 			"network":     "Testnet",
 		}
 
@@ -149,17 +550,2213 @@ func TestSignStellarTx(t *testing.T) {
 		assert.NoError(t, innerErr)
 		assert.NotNil(t, resp)
 		assert.NotEmpty(t, resp.Data["signed_transaction"])
+		assert.Len(t, resp.Data["transaction_hash"], 64)
+		assert.Equal(t, testTxSourceAccount, resp.Data["source"])
+		assert.Equal(t, "212781269778433", resp.Data["sequence"])
+		assert.Equal(t, 1, resp.Data["operation_count"])
 	}
 
 }
 
-// getTestBackendAndStorage is a helper function to create a Backend and in-memory storage for testing.
-func getTestBackendAndStorage(t *testing.T) (logical.Backend, logical.Storage) {
-	config := logical.TestBackendConfig()
-	b, err := Factory(context.Background(), config)
-	if err != nil {
-		t.Fatal(err)
+// TestAuditNonHMACKeys tests that the fields kept readable in audit logs never carry a seed or a transaction envelope.
+func TestAuditNonHMACKeys(t *testing.T) {
+	b, storage := getTestBackendAndStorage(t)
+	pair, _ := keypair.Random()
+	transaction := testTransaction(t)
+
+	// The keys apply to every path of the mount, so the requests importing and using a seed are checked
+	requests := []*logical.Request{
+		{Operation: logical.CreateOperation, Path: "accounts", Data: map[string]interface{}{"secret_key": pair.Seed(), "allowed_sources": "*"}},
+		{Operation: logical.CreateOperation, Path: "accounts/" + pair.Address() + "/sign", Data: map[string]interface{}{"transaction": transaction, "network": "Testnet"}},
+		{Operation: logical.CreateOperation, Path: "accounts/" + pair.Address() + "/build", Data: map[string]interface{}{
+			"network":    "Testnet",
+			"sequence":   "7",
+			"operations": []interface{}{map[string]interface{}{"type": "manage_data", "name": "invoice", "value": "42"}},
+		}},
+		// Denied requests answer with their error code in the response data
+		{Operation: logical.CreateOperation, Path: "accounts/" + pair.Address() + "/sign", Data: map[string]interface{}{"transaction": transaction, "network": "Testnet", "idempotency_key": "retry"}},
 	}
-	storage := &logical.InmemStorage{}
-	return b, storage
+	secrets := []string{pair.Seed(), transaction}
+	readable := map[string]bool{}
+	for _, req := range requests {
+		req.Storage = storage
+		for _, key := range paths.AuditNonHMACRequestKeys {
+			if value, ok := req.Data[key]; ok {
+				for _, secret := range secrets {
+					assert.NotContains(t, fmt.Sprint(value), secret, key)
+				}
+			}
+		}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if req.Data["idempotency_key"] == nil {
+			require.NoError(t, err, req.Path)
+		}
+		require.NotNil(t, resp, req.Path)
+		if signed, ok := resp.Data["signed_transaction"].(string); ok {
+			secrets = append(secrets, signed)
+		}
+		for _, key := range paths.AuditNonHMACResponseKeys {
+			value, ok := resp.Data[key]
+			if details, isError := resp.Data["data"].(map[string]interface{}); !ok && isError {
+				// Error responses carry their error code in their data
+				value, ok = details[key]
+			}
+			if !ok {
+				continue
+			}
+			readable[key] = true
+			for _, secret := range secrets {
+				assert.NotContains(t, fmt.Sprint(value), secret, key)
+			}
+		}
+	}
+	// The keys are actually returned, so a renamed field does not silently become HMACed
+	for _, key := range []string{"transaction_hash", "source", "sequence", "operation_count", "error_code"} {
+		assert.True(t, readable[key], key)
+	}
+}
+
+// TestAccountUsage tests usage counters, signature quotas, validity periods and the unused keys report.
+func TestAccountUsage(t *testing.T) {
+	b, storage := getTestBackendAndStorage(t)
+
+	create := func(data map[string]interface{}) string {
+		data["allowed_sources"] = testTxSourceAccount
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "accounts",
+			Data:      data,
+			Storage:   storage,
+		})
+		require.NoError(t, err)
+		require.False(t, resp.IsError(), resp.Error())
+		return resp.Data["public_key"].(string)
+	}
+	sign := func(publicKey string) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "accounts/" + publicKey + "/sign",
+			Data: map[string]interface{}{
+				"transaction": testTransaction(t),
+				"network":     "Testnet",
+			},
+			Storage: storage,
+		})
+	}
+
+	// The quota caps the signatures of the account
+	quota := create(map[string]interface{}{"max_signatures": 2})
+	for i := 0; i < 2; i++ {
+		_, err := sign(quota)
+		require.NoError(t, err)
+	}
+	resp, err := sign(quota)
+	assert.ErrorContains(t, responseError(resp, err), "reached its quota of 2 signatures")
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "accounts/" + quota,
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, resp.Data["signature_count"])
+	assert.Equal(t, map[string]uint64{"Testnet": 2}, resp.Data["network_signatures"])
+	assert.EqualValues(t, 2, resp.Data["max_signatures"])
+	assert.NotEmpty(t, resp.Data["last_signed_at"])
+	assert.NotEmpty(t, resp.Data["created_at"])
+
+	// Raising the quota lets the account sign again
+	_, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "accounts/" + quota,
+		Data:      map[string]interface{}{"max_signatures": 3},
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	_, err = sign(quota)
+	assert.NoError(t, err)
+
+	// The validity period bounds when the account signs
+	future := create(map[string]interface{}{"not_before": time.Now().Add(time.Hour).Format(time.RFC3339)})
+	resp, err = sign(future)
+	assert.ErrorContains(t, responseError(resp, err), "is not valid before")
+	expired := create(map[string]interface{}{"not_after": time.Now().Add(-time.Hour).Format(time.RFC3339)})
+	resp, err = sign(expired)
+	assert.ErrorContains(t, responseError(resp, err), "expired at")
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "accounts",
+		Data:      map[string]interface{}{"not_before": "2030-01-01T00:00:00Z", "not_after": "2029-01-01T00:00:00Z"},
+		Storage:   storage,
+	})
+	assert.ErrorContains(t, responseError(resp, err), "not_after must be later than not_before")
+
+	// Detailed listings carry the usage of every account
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ListOperation,
+		Path:      "accounts",
+		Data:      map[string]interface{}{"detailed": true},
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	require.Contains(t, resp.Data["key_info"], quota)
+	assert.EqualValues(t, 3, resp.Data["key_info"].(map[string]interface{})[quota].(map[string]interface{})["signature_count"])
+
+	// An account stored before usage was tracked is reported until it signs
+	legacy, _ := keypair.Random()
+	entry, err := logical.StorageEntryJSON("stellar/accounts/"+legacy.Address(), map[string]interface{}{
+		"public_key": legacy.Address(),
+		"secret_key": legacy.Seed(),
+	})
+	require.NoError(t, err)
+	require.NoError(t, storage.Put(context.Background(), entry))
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "reports/unused-keys",
+		Data:      map[string]interface{}{"days": 1},
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{legacy.Address()}, resp.Data["keys"])
+	assert.EqualValues(t, 1, resp.Data["days"])
+
+	// The periodic function stores the report for the configured period
+	require.NoError(t, b.(*Backend).PeriodicFunc(context.Background(), &logical.Request{Storage: storage}))
+	entry, err = storage.Get(context.Background(), "stellar/reports/unused-keys")
+	require.NoError(t, err)
+	assert.NotNil(t, entry)
+}
+
+// TestSecretKeyStorage tests that seeds are stored encrypted under the seal-wrapped prefix and legacy accounts are migrated.
+func TestSecretKeyStorage(t *testing.T) {
+	b, storage := getTestBackendAndStorage(t)
+	assert.Contains(t, b.SpecialPaths().SealWrapStorage, "stellar/keys/")
+
+	// An account stored before the migration holds its seed in plaintext with its metadata
+	legacy, _ := keypair.Random()
+	entry, err := logical.StorageEntryJSON("stellar/accounts/"+legacy.Address(), map[string]interface{}{
+		"public_key":      legacy.Address(),
+		"secret_key":      legacy.Seed(),
+		"allowed_sources": []string{"*"},
+	})
+	require.NoError(t, err)
+	require.NoError(t, storage.Put(context.Background(), entry))
+
+	require.NoError(t, b.Initialize(context.Background(), &logical.InitializationRequest{Storage: storage}))
+	entry, err = storage.Get(context.Background(), "stellar/schema")
+	require.NoError(t, err)
+	require.NotNil(t, entry)
+	assert.JSONEq(t, `{"version": 2}`, string(entry.Value))
+
+	// Storage written by a newer version of the plugin is refused
+	newer, err := logical.StorageEntryJSON("stellar/schema", map[string]interface{}{"version": 1000})
+	require.NoError(t, err)
+	newerStorage := &logical.InmemStorage{}
+	require.NoError(t, newerStorage.Put(context.Background(), newer))
+	assert.ErrorContains(t, b.Initialize(context.Background(), &logical.InitializationRequest{Storage: newerStorage}), "newer")
+
+	// Imported accounts are stored the same way as migrated ones
+	imported, _ := keypair.Random()
+	_, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "accounts",
+		Data:      map[string]interface{}{"secret_key": imported.Seed(), "allowed_sources": "*"},
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+
+	for _, pair := range []*keypair.Full{legacy, imported} {
+		metadata, err := storage.Get(context.Background(), "stellar/accounts/"+pair.Address())
+		require.NoError(t, err)
+		require.NotNil(t, metadata)
+		assert.NotContains(t, string(metadata.Value), pair.Seed())
+
+		sealed, err := storage.Get(context.Background(), "stellar/keys/"+pair.Address())
+		require.NoError(t, err)
+		require.NotNil(t, sealed)
+		assert.NotContains(t, string(sealed.Value), pair.Seed())
+
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "accounts/" + pair.Address() + "/sign",
+			Data: map[string]interface{}{
+				"transaction": testTransaction(t),
+				"network":     "Testnet",
+			},
+			Storage: storage,
+		})
+		require.NoError(t, err)
+		signed, err := txnbuild.TransactionFromXDR(resp.Data["signed_transaction"].(string))
+		require.NoError(t, err)
+		tx, _ := signed.Transaction()
+		hash, err := tx.Hash(network.TestNetworkPassphrase)
+		require.NoError(t, err)
+		assert.NoError(t, pair.Verify(hash[:], tx.Signatures()[0].Signature))
+	}
+
+	// The data encryption key is only stored wrapped with the key encryption secret
+	entry, err = storage.Get(context.Background(), "stellar/keyring")
+	require.NoError(t, err)
+	var kr map[string][]byte
+	require.NoError(t, json.Unmarshal(entry.Value, &kr))
+	assert.NotEmpty(t, kr["wrapped_key"])
+
+	// Without the secret the mount was wrapped with, the seeds cannot be used
+	t.Setenv(stellar.KeyEncryptionSecretEnv, strings.Repeat("x", 44))
+	other, err := Factory(context.Background(), logical.TestBackendConfig())
+	require.NoError(t, err)
+	_, err = other.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "accounts/" + imported.Address() + "/sign",
+		Data: map[string]interface{}{
+			"transaction": testTransaction(t),
+			"network":     "Testnet",
+		},
+		Storage: storage,
+	})
+	assert.ErrorContains(t, err, stellar.KeyEncryptionSecretEnv)
+
+	// Deleting an account removes its seed
+	_, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.DeleteOperation,
+		Path:      "accounts/" + imported.Address(),
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	entry, err = storage.Get(context.Background(), "stellar/keys/"+imported.Address())
+	require.NoError(t, err)
+	assert.Nil(t, entry)
+}
+
+// TestAccountOperationMapping tests the existence checks deciding whether writes need the create or the update capability.
+func TestAccountOperationMapping(t *testing.T) {
+	b, storage := getTestBackendAndStorage(t)
+
+	pair, _ := keypair.Random()
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "accounts",
+		Data:      map[string]interface{}{"secret_key": pair.Seed()},
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	require.Equal(t, pair.Address(), resp.Data["public_key"])
+	muxed, err := xdr.MuxedAccountFromAccountId(pair.Address(), 7)
+	require.NoError(t, err)
+	missing, _ := keypair.Random()
+
+	for _, tc := range []struct {
+		path   string
+		exists bool
+	}{
+		// Generating and importing keys is always a create
+		{"accounts", false},
+		// Metadata changes and signing with an existing account are updates
+		{"accounts/" + pair.Address(), true},
+		{"accounts/" + pair.Address() + "/sign", true},
+		{"accounts/" + muxed.Address() + "/sign", true},
+		{"accounts/" + pair.Address() + "/build", true},
+		{"accounts/" + missing.Address(), false},
+		{"accounts/" + missing.Address() + "/sign", false},
+		{"accounts/invalid/sign", false},
+	} {
+		checkFound, exists, err := b.HandleExistenceCheck(context.Background(), &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      tc.path,
+			Storage:   storage,
+		})
+		require.NoError(t, err, tc.path)
+		assert.True(t, checkFound, tc.path)
+		assert.Equal(t, tc.exists, exists, tc.path)
+	}
+
+	// Importing the seed of an existing account is a conflict, not an overwrite
+	_, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "accounts/" + pair.Address(),
+		Data:      map[string]interface{}{"max_signatures": 5},
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	_, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "accounts",
+		Data:      map[string]interface{}{"secret_key": pair.Seed()},
+		Storage:   storage,
+	})
+	assert.ErrorContains(t, err, "already exists")
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "accounts/" + pair.Address(),
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, resp.Data["max_signatures"])
+
+	// The collection only accepts creates, and updating a missing account fails
+	_, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "accounts",
+		Storage:   storage,
+	})
+	assert.ErrorIs(t, err, logical.ErrUnsupportedOperation)
+	_, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "accounts/" + missing.Address(),
+		Data:      map[string]interface{}{"policy": ""},
+		Storage:   storage,
+	})
+	assert.ErrorContains(t, err, "does not exist")
+}
+
+// TestErrorResponses tests that errors caused by the request are answered with their HTTP status and error code.
+func TestErrorResponses(t *testing.T) {
+	b, storage := getTestBackendAndStorage(t)
+
+	_, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "policies/bounded",
+		Data:      map[string]interface{}{"require_time_bounds": true},
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	pair, _ := keypair.Random()
+	publicKey := createTestAccount(t, b, storage, map[string]interface{}{"secret_key": pair.Seed()})
+	bound := createTestAccount(t, b, storage, map[string]interface{}{"allowed_sources": testTxSourceAccount, "policy": "bounded"})
+	unbound := createTestAccount(t, b, storage, map[string]interface{}{"allowed_sources": testTxSourceAccount})
+	missing, _ := keypair.Random()
+	transaction := "AAAAAgAAAAATozPrNDRTqLO2WUflkFsbKLSQN79/VlhRpv7MMzePdgAAAGQAAMGGAAAAAQAAAAEAAAAAAAAAAAAAAABlhHryAAAAAAAAAAEAAAABAAAAABOjM+s0NFOos7ZZR+WQWxsotJA3v39WWFGm/swzN492AAAAAQAAAAB69J8A290AJGAqNy4f0QIXBG4NoPQm7B+vDdeR0AvXRQAAAAAAAAACVAvkAAAAAAAAAAAA"
+
+	for _, tc := range []struct {
+		name      string
+		operation logical.Operation
+		path      string
+		data      map[string]interface{}
+		status    int
+		code      string
+	}{
+		{"missing account", logical.CreateOperation, "accounts/" + missing.Address() + "/sign",
+			map[string]interface{}{"transaction": transaction, "network": "Testnet"}, http.StatusNotFound, "account_not_found"},
+		{"invalid network", logical.UpdateOperation, "accounts/" + publicKey + "/sign",
+			map[string]interface{}{"transaction": transaction, "network": "Futurenet"}, http.StatusBadRequest, "invalid_network"},
+		{"invalid envelope", logical.UpdateOperation, "accounts/" + publicKey + "/sign",
+			map[string]interface{}{"transaction": "AAAA", "network": "Testnet"}, http.StatusBadRequest, "invalid_envelope"},
+		{"invalid address", logical.ReadOperation, "accounts/GINVALID", nil, http.StatusBadRequest, "invalid_address"},
+		{"source not allowed", logical.UpdateOperation, "accounts/" + publicKey + "/sign",
+			map[string]interface{}{"transaction": transaction, "network": "Testnet"}, http.StatusForbidden, "source_not_allowed"},
+		{"account exists", logical.CreateOperation, "accounts",
+			map[string]interface{}{"secret_key": pair.Seed()}, http.StatusConflict, "account_exists"},
+		{"policy not found", logical.ReadOperation, "policies/missing", nil, http.StatusNotFound, "policy_not_found"},
+		{"expired without policy", logical.UpdateOperation, "accounts/" + unbound + "/sign",
+			map[string]interface{}{"transaction": transaction, "network": "Testnet"}, http.StatusBadRequest, "transaction_expired"},
+		{"invalid field", logical.UpdateOperation, "accounts/" + publicKey,
+			map[string]interface{}{"max_signatures": -1}, http.StatusBadRequest, "invalid_request"},
+	} {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: tc.operation,
+			Path:      tc.path,
+			Data:      tc.data,
+			Storage:   storage,
+		})
+		status, _ := responseStatus(resp, err)
+		assert.Equal(t, tc.status, status, tc.name)
+		require.NotNil(t, resp, tc.name)
+		assert.Equal(t, tc.code, resp.Data["data"].(map[string]interface{})["error_code"], tc.name)
+	}
+
+	// Policy denials name the policy and the rule that failed
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "accounts/" + bound + "/sign",
+		Data:      map[string]interface{}{"transaction": transaction, "network": "Testnet"},
+		Storage:   storage,
+	})
+	status, err := responseStatus(resp, err)
+	assert.Equal(t, http.StatusForbidden, status)
+	assert.ErrorContains(t, err, `denied by policy "bounded"`)
+	details := resp.Data["data"].(map[string]interface{})
+	assert.Equal(t, "policy_denied", details["error_code"])
+	assert.Equal(t, "bounded", details["policy"])
+	// The transaction of the test expired long ago
+	assert.Equal(t, "max_time", details["rule"])
+}
+
+// TestSorobanContractAllowlistPolicy tests that a Soroban policy only lets allowlisted contract calls through.
+func TestSorobanContractAllowlistPolicy(t *testing.T) {
+	b, storage := getTestBackendAndStorage(t)
+
+	allowedContract := randomContractID(t)
+	otherContract := randomContractID(t)
+	allowedAddress, _ := strkey.Encode(strkey.VersionByteContract, allowedContract[:])
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "policies/soroban",
+		Data: map[string]interface{}{
+			"soroban_contracts": []interface{}{
+				map[string]interface{}{
+					"contract":  allowedAddress,
+					"functions": []interface{}{"transfer"},
+					"args":      []interface{}{map[string]interface{}{"index": 0, "values": []interface{}{"alice"}}},
+				},
+				map[string]interface{}{
+					"contract":  allowedAddress,
+					"functions": []interface{}{"transfer"},
+					"args":      []interface{}{map[string]interface{}{"index": 0, "values": []interface{}{"carol"}}},
+				},
+			},
+			"soroban_max_resource_fee": 5000,
+		},
+		Storage: storage,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "soroban", resp.Data["name"])
+
+	publicKey := createTestAccount(t, b, storage, map[string]interface{}{"policy": "soroban"})
+
+	signSoroban := func(invocation xdr.InvokeContractArgs, auth []xdr.SorobanAuthorizationEntry, resourceFee int64) error {
+		tx := buildTestTx(t, publicKey, &txnbuild.InvokeHostFunction{
+			HostFunction: xdr.HostFunction{
+				Type:           xdr.HostFunctionTypeHostFunctionTypeInvokeContract,
+				InvokeContract: &invocation,
+			},
+			Auth: auth,
+			Ext: xdr.TransactionExt{
+				V:           1,
+				SorobanData: &xdr.SorobanTransactionData{ResourceFee: xdr.Int64(resourceFee)},
+			},
+		})
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "accounts/" + publicKey + "/sign",
+			Data:      map[string]interface{}{"transaction": tx, "network": "Testnet"},
+			Storage:   storage,
+		})
+		return responseError(resp, err)
+	}
+
+	assert.NoError(t, signSoroban(testInvocation(allowedContract, "transfer", "alice"), nil, 100))
+	// A call rejected by the arguments of one rule is allowed by a later rule for the same function
+	assert.NoError(t, signSoroban(testInvocation(allowedContract, "transfer", "carol"), nil, 100))
+	assert.ErrorContains(t, signSoroban(testInvocation(allowedContract, "transfer", "bob"), nil, 100), "argument 0")
+	assert.ErrorContains(t, signSoroban(testInvocation(allowedContract, "mint", "alice"), nil, 100), "soroban_contracts")
+	assert.ErrorContains(t, signSoroban(testInvocation(otherContract, "transfer", "alice"), nil, 100), "soroban_contracts")
+	assert.ErrorContains(t, signSoroban(testInvocation(allowedContract, "transfer", "alice"), nil, 6000), "soroban_max_resource_fee")
+
+	// Nested authorized invocations are checked as well
+	nestedCall := testInvocation(otherContract, "transfer", "alice")
+	auth := []xdr.SorobanAuthorizationEntry{{
+		Credentials: xdr.SorobanCredentials{Type: xdr.SorobanCredentialsTypeSorobanCredentialsSourceAccount},
+		RootInvocation: xdr.SorobanAuthorizedInvocation{
+			Function: xdr.SorobanAuthorizedFunction{
+				Type:       xdr.SorobanAuthorizedFunctionTypeSorobanAuthorizedFunctionTypeContractFn,
+				ContractFn: &nestedCall,
+			},
+		},
+	}}
+	assert.ErrorContains(t, signSoroban(testInvocation(allowedContract, "transfer", "alice"), auth, 100), "authorization")
+}
+
+// TestValidityGuardrails tests the mount-wide and per-account transaction validity rules.
+func TestValidityGuardrails(t *testing.T) {
+	b, storage := getTestBackendAndStorage(t)
+
+	writePolicy := func(name string, data map[string]interface{}) {
+		_, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "policies/" + name,
+			Data:      data,
+			Storage:   storage,
+		})
+		require.NoError(t, err)
+	}
+	extraSigner, _ := keypair.Random()
+	writePolicy("mount", map[string]interface{}{"require_time_bounds": true, "max_validity_window": "1h"})
+	writePolicy("cosigned", map[string]interface{}{"required_extra_signers": extraSigner.Address()})
+
+	_, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Data:      map[string]interface{}{"policy": "mount"},
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+
+	publicKey := createTestAccount(t, b, storage, map[string]interface{}{})
+	cosignedKey := createTestAccount(t, b, storage, map[string]interface{}{"policy": "cosigned"})
+
+	sign := func(account string, preconditions txnbuild.Preconditions) error {
+		tx := buildTestTxWithPreconditions(t, account, preconditions, &txnbuild.BumpSequence{BumpTo: 2})
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "accounts/" + account + "/sign",
+			Data:      map[string]interface{}{"transaction": tx, "network": "Testnet"},
+			Storage:   storage,
+		})
+		return responseError(resp, err)
+	}
+
+	now := time.Now().Unix()
+	assert.NoError(t, sign(publicKey, txnbuild.Preconditions{TimeBounds: txnbuild.NewTimeout(600)}))
+	assert.ErrorContains(t, sign(publicKey, txnbuild.Preconditions{TimeBounds: txnbuild.NewInfiniteTimeout()}), "require_time_bounds")
+	assert.ErrorContains(t, sign(publicKey, txnbuild.Preconditions{TimeBounds: txnbuild.NewTimeout(7200)}), "max_validity_window")
+	assert.ErrorContains(t, sign(publicKey, txnbuild.Preconditions{TimeBounds: txnbuild.NewTimebounds(0, now-60)}), "max_time")
+
+	// The account policy is enforced on top of the mount-wide policy
+	assert.ErrorContains(t, sign(cosignedKey, txnbuild.Preconditions{TimeBounds: txnbuild.NewTimeout(600)}), "required_extra_signers")
+	assert.NoError(t, sign(cosignedKey, txnbuild.Preconditions{
+		TimeBounds:   txnbuild.NewTimeout(600),
+		ExtraSigners: []string{extraSigner.Address()},
+	}))
+}
+
+// TestReplayProtection tests duplicate detection, idempotency keys and sequence number reuse.
+func TestReplayProtection(t *testing.T) {
+	b, storage := getTestBackendAndStorage(t)
+
+	_, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Data:      map[string]interface{}{"replay_protection": true, "reject_sequence_reuse": true, "replay_window": "1s"},
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+
+	publicKey := createTestAccount(t, b, storage, map[string]interface{}{})
+	sign := func(tx string, idempotencyKey string) (*logical.Response, error) {
+		data := map[string]interface{}{"transaction": tx, "network": "Testnet"}
+		if idempotencyKey != "" {
+			data["idempotency_key"] = idempotencyKey
+		}
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "accounts/" + publicKey + "/sign",
+			Data:      data,
+			Storage:   storage,
+		})
+	}
+
+	tx := buildTestTx(t, publicKey, &txnbuild.BumpSequence{BumpTo: 2})
+	otherTx := buildTestTx(t, publicKey, &txnbuild.BumpSequence{BumpTo: 3})
+
+	first, err := sign(tx, "request-1")
+	require.NoError(t, err)
+	retried, err := sign(tx, "request-1")
+	require.NoError(t, err)
+	assert.Equal(t, first.Data["signed_transaction"], retried.Data["signed_transaction"])
+
+	_, err = sign(tx, "")
+	assert.ErrorContains(t, err, "already signed")
+	_, err = sign(otherTx, "request-1")
+	assert.ErrorContains(t, err, "idempotency_key")
+	_, err = sign(otherTx, "request-2")
+	assert.ErrorContains(t, err, "sequence number")
+	// The sequence number of the ledger account is not reused through a mux ID
+	muxed, err := xdr.MuxedAccountFromAccountId(publicKey, 9)
+	require.NoError(t, err)
+	_, err = sign(buildTestTx(t, muxed.Address(), &txnbuild.BumpSequence{BumpTo: 3}), "")
+	assert.ErrorContains(t, err, "sequence number")
+
+	// Records are pruned by the periodic function once the window has passed
+	time.Sleep(1100 * time.Millisecond)
+	require.NoError(t, b.(*Backend).PeriodicFunc(context.Background(), &logical.Request{Storage: storage}))
+	records, err := storage.List(context.Background(), "stellar/replay/"+publicKey+"/tx/")
+	require.NoError(t, err)
+	assert.Empty(t, records)
+	_, err = sign(tx, "")
+	assert.NoError(t, err)
+}
+
+// TestSourceAccountBinding tests that accounts only sign for the ledger accounts they are bound to.
+func TestSourceAccountBinding(t *testing.T) {
+	b, storage := getTestBackendAndStorage(t)
+
+	publicKey := createTestAccount(t, b, storage, map[string]interface{}{})
+	other, _ := keypair.Random()
+
+	readResp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "accounts/" + publicKey,
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{publicKey}, readResp.Data["allowed_sources"])
+
+	sign := func(tx string) error {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "accounts/" + publicKey + "/sign",
+			Data:      map[string]interface{}{"transaction": tx, "network": "Testnet"},
+			Storage:   storage,
+		})
+		return responseError(resp, err)
+	}
+
+	muxed, err := xdr.MuxedAccountFromAccountId(publicKey, 42)
+	require.NoError(t, err)
+	assert.NoError(t, sign(buildTestTx(t, publicKey, &txnbuild.BumpSequence{BumpTo: 2})))
+	assert.NoError(t, sign(buildTestTx(t, muxed.Address(), &txnbuild.BumpSequence{BumpTo: 2})))
+	assert.ErrorContains(t, sign(buildTestTx(t, other.Address(), &txnbuild.BumpSequence{BumpTo: 2})), "not allowed")
+	assert.ErrorContains(t, sign(buildTestTx(t, publicKey, &txnbuild.BumpSequence{BumpTo: 2, SourceAccount: other.Address()})), "operation 0")
+
+	_, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "accounts/" + publicKey,
+		Data:      map[string]interface{}{"allowed_sources": []string{publicKey, other.Address()}},
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	assert.NoError(t, sign(buildTestTx(t, other.Address(), &txnbuild.BumpSequence{BumpTo: 2})))
+}
+
+// TestMuxedAccounts tests M-address resolution, per-mux-ID policy overrides and mux IDs in history.
+func TestMuxedAccounts(t *testing.T) {
+	b, storage := getTestBackendAndStorage(t)
+
+	_, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "policies/strict",
+		Data:      map[string]interface{}{"max_validity_window": "10m"},
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+
+	publicKey := createTestAccount(t, b, storage, map[string]interface{}{})
+	_, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "accounts/" + publicKey,
+		Data:      map[string]interface{}{"mux_policies": map[string]interface{}{"7": "strict"}},
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+
+	muxedResp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "accounts/" + publicKey + "/muxed/7",
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	muxedAddress := muxedResp.Data["muxed_address"].(string)
+	assert.Equal(t, "strict", muxedResp.Data["policy"])
+
+	readResp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "accounts/" + muxedAddress,
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, publicKey, readResp.Data["public_key"])
+	assert.Equal(t, uint64(7), readResp.Data["mux_id"])
+
+	sign := func(address string) error {
+		tx := buildTestTx(t, publicKey, &txnbuild.BumpSequence{BumpTo: 2})
+		return responseError(b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "accounts/" + address + "/sign",
+			Data:      map[string]interface{}{"transaction": tx, "network": "Testnet"},
+			Storage:   storage,
+		}))
+	}
+	// The override only applies when the account signs as mux ID 7
+	assert.ErrorContains(t, sign(muxedAddress), "max_validity_window")
+	require.NoError(t, sign(publicKey))
+
+	other, err := xdr.MuxedAccountFromAccountId(publicKey, 8)
+	require.NoError(t, err)
+	require.NoError(t, sign(other.Address()))
+
+	// Signing as mux ID 7 through the plain address, in the transaction source or in an
+	// operation source, still enforces the override
+	for _, tx := range []string{
+		buildTestTx(t, muxedAddress, &txnbuild.BumpSequence{BumpTo: 2}),
+		buildTestTx(t, other.Address(), &txnbuild.BumpSequence{BumpTo: 2, SourceAccount: muxedAddress}),
+	} {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "accounts/" + publicKey + "/sign",
+			Data:      map[string]interface{}{"transaction": tx, "network": "Testnet"},
+			Storage:   storage,
+		})
+		assert.ErrorContains(t, responseError(resp, err), "max_validity_window")
+	}
+
+	historyResp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ListOperation,
+		Path:      "accounts/" + publicKey + "/history",
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	assert.Len(t, historyResp.Data["keys"], 1)
+	for _, info := range historyResp.Data["key_info"].(map[string]interface{}) {
+		assert.Equal(t, uint64(8), info.(map[string]interface{})["mux_id"])
+	}
+}
+
+// TestPruneSigningHistory tests that the periodic function prunes the history records
+// older than the retention.
+func TestPruneSigningHistory(t *testing.T) {
+	b, storage := getTestBackendAndStorage(t)
+
+	publicKey := createTestAccount(t, b, storage, map[string]interface{}{})
+	var hashes []string
+	for _, bumpTo := range []int64{2, 3} {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "accounts/" + publicKey + "/sign",
+			Data:      map[string]interface{}{"transaction": buildTestTx(t, publicKey, &txnbuild.BumpSequence{BumpTo: bumpTo}), "network": "Testnet"},
+			Storage:   storage,
+		})
+		require.NoError(t, err)
+		hashes = append(hashes, resp.Data["transaction_hash"].(string))
+	}
+
+	// The first transaction was signed 400 days ago
+	today := time.Now().UTC().Format("20060102")
+	expired := time.Now().UTC().AddDate(0, 0, -400).Format("20060102")
+	require.NoError(t, storage.Delete(context.Background(), "stellar/history-days/"+today+"/"+publicKey+"."+hashes[0]))
+	require.NoError(t, storage.Put(context.Background(), &logical.StorageEntry{
+		Key: "stellar/history-days/" + expired + "/" + publicKey + "." + hashes[0], Value: []byte{},
+	}))
+
+	require.NoError(t, b.(*Backend).PeriodicFunc(context.Background(), &logical.Request{Storage: storage}))
+	historyResp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ListOperation,
+		Path:      "accounts/" + publicKey + "/history",
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{hashes[1]}, historyResp.Data["keys"])
+	days, err := storage.List(context.Background(), "stellar/history-days/")
+	require.NoError(t, err)
+	assert.Equal(t, []string{today + "/"}, days)
+}
+
+// TestReadAccountLedgerState tests enriching account reads with state fetched from Horizon.
+func TestReadAccountLedgerState(t *testing.T) {
+	b, storage := getTestBackendAndStorage(t)
+
+	fundedKey := createTestAccount(t, b, storage, map[string]interface{}{})
+	unfundedKey := createTestAccount(t, b, storage, map[string]interface{}{})
+
+	requests := 0
+	horizonServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch {
+		case r.URL.Path == "/accounts/"+fundedKey:
+			_, _ = fmt.Fprintf(w, `{"id": %q, "sequence": "4294967296", "balances": [{"balance": "100.0000000", "asset_type": "native"}],
+				"thresholds": {"low_threshold": 1, "med_threshold": 2, "high_threshold": 3}}`, fundedKey)
+		case r.URL.Path == "/accounts" && r.URL.Query().Get("signer") == fundedKey:
+			_, _ = fmt.Fprintf(w, `{"_embedded": {"records": [{"id": %q}, {"id": %q}]}}`, fundedKey, testTxSourceAccount)
+		case r.URL.Path == "/accounts" && r.URL.Query().Get("signer") == unfundedKey:
+			// The key signs for 201 accounts, listed over two pages
+			records := []map[string]string{}
+			first, count := 0, 200
+			if r.URL.Query().Get("cursor") == "199" {
+				first, count = 200, 1
+			}
+			for i := first; i < first+count; i++ {
+				records = append(records, map[string]string{"id": fmt.Sprintf("account-%d", i), "paging_token": strconv.Itoa(i)})
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"_embedded": map[string]interface{}{"records": records}})
+		case r.URL.Path == "/accounts":
+			_, _ = fmt.Fprint(w, `{"_embedded": {"records": []}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = fmt.Fprint(w, `{"status": 404, "title": "Resource Missing"}`)
+		}
+	}))
+	defer horizonServer.Close()
+
+	_, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Data:      map[string]interface{}{"horizon_urls": map[string]interface{}{"Testnet": horizonServer.URL}},
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+
+	read := func(publicKey string) *logical.Response {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "accounts/" + publicKey,
+			Data:      map[string]interface{}{"network": "Testnet"},
+			Storage:   storage,
+		})
+		require.NoError(t, err)
+		return resp
+	}
+
+	resp := read(fundedKey)
+	assert.Equal(t, true, resp.Data["exists"])
+	assert.Equal(t, "4294967296", resp.Data["sequence"])
+	assert.Equal(t, []string{fundedKey, testTxSourceAccount}, resp.Data["signer_for"])
+	assert.Equal(t, 2, requests)
+
+	// The second read is served from the cache
+	read(fundedKey)
+	assert.Equal(t, 2, requests)
+
+	resp = read(unfundedKey)
+	assert.Equal(t, false, resp.Data["exists"])
+	require.Len(t, resp.Data["signer_for"], 201)
+	assert.Equal(t, "account-200", resp.Data["signer_for"].([]string)[200])
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "accounts/" + fundedKey,
+		Data:      map[string]interface{}{"network": "Public"},
+		Storage:   storage,
+	})
+	assert.ErrorContains(t, responseError(resp, err), "no Horizon URL")
+}
+
+// TestSignAndSubmit tests submitting signed transactions to Horizon, synchronously and asynchronously.
+func TestSignAndSubmit(t *testing.T) {
+	b, storage := getTestBackendAndStorage(t)
+
+	badSeqResult, err := xdr.MarshalBase64(xdr.TransactionResult{
+		FeeCharged: 100,
+		Result:     xdr.TransactionResultResult{Code: xdr.TransactionResultCodeTxBadSeq},
+	})
+	require.NoError(t, err)
+
+	horizonServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		tx, err := txnbuild.TransactionFromXDR(r.PostForm.Get("tx"))
+		require.NoError(t, err)
+		inner, _ := tx.Transaction()
+		switch inner.SequenceNumber() {
+		case 1:
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = fmt.Fprintf(w, `{"status": 400, "title": "Transaction Failed", "extras": {"result_xdr": %q, "result_codes": {"transaction": "tx_bad_seq"}}}`, badSeqResult)
+			return
+		case 4:
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = fmt.Fprint(w, `{"type": "https://stellar.org/horizon-errors/transaction_malformed", "status": 400, "title": "Transaction Malformed"}`)
+			return
+		}
+		hash, _ := inner.HashHex(network.TestNetworkPassphrase)
+		_, _ = fmt.Fprintf(w, `{"hash": %q, "ledger": 1234, "successful": true}`, hash)
+	}))
+	defer horizonServer.Close()
+
+	_, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Data:      map[string]interface{}{"horizon_urls": map[string]interface{}{"Testnet": horizonServer.URL}},
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+
+	publicKey := createTestAccount(t, b, storage, map[string]interface{}{})
+	signAndSubmit := func(sequence int64, async bool) (*logical.Response, error) {
+		tx, err := txnbuild.NewTransaction(txnbuild.TransactionParams{
+			SourceAccount: &txnbuild.SimpleAccount{AccountID: publicKey, Sequence: sequence},
+			Operations:    []txnbuild.Operation{&txnbuild.BumpSequence{BumpTo: 2}},
+			BaseFee:       txnbuild.MinBaseFee,
+			Preconditions: txnbuild.Preconditions{TimeBounds: txnbuild.NewInfiniteTimeout()},
+		})
+		require.NoError(t, err)
+		txBase64, err := tx.Base64()
+		require.NoError(t, err)
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "accounts/" + publicKey + "/sign",
+			Data:      map[string]interface{}{"transaction": txBase64, "network": "Testnet", "submit": true, "async": async},
+			Storage:   storage,
+		})
+	}
+
+	resp, err := signAndSubmit(2, false)
+	require.NoError(t, err)
+	assert.Equal(t, "success", resp.Data["submission_status"])
+	assert.Equal(t, int32(1234), resp.Data["ledger"])
+
+	resp, err = signAndSubmit(1, false)
+	assert.ErrorContains(t, responseError(resp, err), "tx_bad_seq: the sequence number does not match the source account")
+
+	resp, err = signAndSubmit(3, true)
+	require.NoError(t, err)
+	assert.Equal(t, "pending", resp.Data["submission_status"])
+	txHash := resp.Data["transaction_hash"].(string)
+
+	require.Eventually(t, func() bool {
+		statusResp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "accounts/" + publicKey + "/submissions/" + txHash,
+			Storage:   storage,
+		})
+		return err == nil && statusResp.Data["status"] == "success"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	historyResp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ListOperation,
+		Path:      "accounts/" + publicKey + "/history",
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	info := historyResp.Data["key_info"].(map[string]interface{})[txHash].(map[string]interface{})
+	assert.Equal(t, "success", info["submission_status"])
+	assert.Equal(t, int32(1234), info["ledger"])
+
+	// Transactions Horizon rejects without a result fail instead of staying pending
+	resp, err = signAndSubmit(4, false)
+	assert.ErrorContains(t, responseError(resp, err), "Transaction Malformed")
+	resp, err = signAndSubmit(4, true)
+	require.NoError(t, err)
+	malformedHash := resp.Data["transaction_hash"].(string)
+	require.Eventually(t, func() bool {
+		statusResp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "accounts/" + publicKey + "/submissions/" + malformedHash,
+			Storage:   storage,
+		})
+		return err == nil && statusResp.Data["status"] == "failed" && statusResp.Data["result_code"] == "transaction_malformed"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Only pending submissions are indexed for the periodic function, and settled ones expire
+	pending, err := storage.List(context.Background(), "stellar/submissions-pending/")
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+	today := time.Now().UTC().Format("20060102")
+	require.NoError(t, storage.Delete(context.Background(), "stellar/submissions-completed/"+today+"/"+publicKey+"."+txHash))
+	expired := time.Now().UTC().AddDate(0, 0, -31).Format("20060102")
+	require.NoError(t, storage.Put(context.Background(), &logical.StorageEntry{
+		Key: "stellar/submissions-completed/" + expired + "/" + publicKey + "." + txHash, Value: []byte{},
+	}))
+	require.NoError(t, b.(*Backend).PeriodicFunc(context.Background(), &logical.Request{Storage: storage}))
+	_, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "accounts/" + publicKey + "/submissions/" + txHash,
+		Storage:   storage,
+	})
+	assert.ErrorContains(t, err, "submission does not exist")
+	_, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "accounts/" + publicKey + "/submissions/" + malformedHash,
+		Storage:   storage,
+	})
+	assert.NoError(t, err)
+
+	// Accounts co-signing the same transaction each keep their submission
+	cosigner := createTestAccount(t, b, storage, map[string]interface{}{})
+	_, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "accounts/" + cosigner,
+		Data:      map[string]interface{}{"allowed_sources": []string{publicKey}},
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	tx, err := txnbuild.NewTransaction(txnbuild.TransactionParams{
+		SourceAccount: &txnbuild.SimpleAccount{AccountID: publicKey, Sequence: 5},
+		Operations:    []txnbuild.Operation{&txnbuild.BumpSequence{BumpTo: 5}},
+		BaseFee:       txnbuild.MinBaseFee,
+		Preconditions: txnbuild.Preconditions{TimeBounds: txnbuild.NewInfiniteTimeout()},
+	})
+	require.NoError(t, err)
+	cosignedTx, err := tx.Base64()
+	require.NoError(t, err)
+	var cosignedHash string
+	for _, signer := range []string{publicKey, cosigner} {
+		resp, err = b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "accounts/" + signer + "/sign",
+			Data:      map[string]interface{}{"transaction": cosignedTx, "network": "Testnet", "submit": true},
+			Storage:   storage,
+		})
+		require.NoError(t, err)
+		cosignedHash = resp.Data["transaction_hash"].(string)
+	}
+	for _, signer := range []string{publicKey, cosigner} {
+		statusResp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "accounts/" + signer + "/submissions/" + cosignedHash,
+			Storage:   storage,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, signer, statusResp.Data["public_key"])
+	}
+
+	// With ownership enforced, submissions are hidden from callers that cannot use the account
+	_, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Data:      map[string]interface{}{"ownership": true},
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	_, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "accounts/" + publicKey + "/submissions/" + cosignedHash,
+		Storage:   storage,
+		EntityID:  "entity-other",
+	})
+	assert.ErrorContains(t, err, "submission does not exist")
+}
+
+func TestBuildTx(t *testing.T) {
+	b, storage := getTestBackendAndStorage(t)
+
+	publicKey := createTestAccount(t, b, storage, map[string]interface{}{})
+	horizonServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/accounts/"+publicKey {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = fmt.Fprint(w, `{"status": 404, "title": "Resource Missing"}`)
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"id": %q, "sequence": "4294967296", "balances": [], "signers": []}`, publicKey)
+	}))
+	defer horizonServer.Close()
+
+	build := func(data map[string]interface{}) (*logical.Response, error) {
+		data["network"] = "Testnet"
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "accounts/" + publicKey + "/build",
+			Data:      data,
+			Storage:   storage,
+		})
+	}
+	payment := map[string]interface{}{
+		"type":        "payment",
+		"destination": testTxSourceAccount,
+		"asset":       "native",
+		"amount":      "10",
+	}
+
+	resp, err := build(map[string]interface{}{"operations": []interface{}{payment}})
+	assert.ErrorContains(t, responseError(resp, err), "sequence must be provided")
+
+	resp, err = build(map[string]interface{}{"operations": []interface{}{payment}, "sequence": "7", "memo": "invoice 42"})
+	require.NoError(t, err)
+	assert.Equal(t, "7", resp.Data["sequence"])
+	tx, err := txnbuild.TransactionFromXDR(resp.Data["signed_transaction"].(string))
+	require.NoError(t, err)
+	signed, ok := tx.Transaction()
+	require.True(t, ok)
+	assert.Equal(t, int64(7), signed.SequenceNumber())
+	assert.Equal(t, txnbuild.MemoText("invoice 42"), signed.Memo())
+	assert.Len(t, signed.Signatures(), 1)
+	require.Len(t, signed.Operations(), 1)
+	assert.Equal(t, testTxSourceAccount, signed.Operations()[0].(*txnbuild.Payment).Destination)
+
+	_, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Data:      map[string]interface{}{"horizon_urls": map[string]interface{}{"Testnet": horizonServer.URL}},
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+
+	trust := `{"type": "change_trust", "asset": "USDC:` + testTxSourceAccount + `"}`
+	resp, err = build(map[string]interface{}{"operations": []interface{}{trust, payment}})
+	require.NoError(t, err)
+	assert.Equal(t, "4294967297", resp.Data["sequence"])
+
+	resp, err = build(map[string]interface{}{"operations": []interface{}{map[string]interface{}{"type": "inflation"}}})
+	assert.ErrorContains(t, responseError(resp, err), `unsupported type "inflation", supported types are: account_merge, begin_sponsoring_future_reserves, change_trust`)
+
+	resp, err = build(map[string]interface{}{"operations": []interface{}{payment}, "source": testTxSourceAccount, "sequence": "8"})
+	assert.ErrorContains(t, responseError(resp, err), "source account")
+
+	// A retried build returns the transaction built under its idempotency key
+	_, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Data:      map[string]interface{}{"replay_protection": true},
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	first, err := build(map[string]interface{}{"operations": []interface{}{payment}, "idempotency_key": "payout-1"})
+	require.NoError(t, err)
+	assert.Equal(t, "4294967298", first.Data["sequence"])
+	retried, err := build(map[string]interface{}{"operations": []interface{}{payment}, "idempotency_key": "payout-1"})
+	require.NoError(t, err)
+	assert.Equal(t, first.Data, retried.Data)
+	resp, err = build(map[string]interface{}{"operations": []interface{}{payment}, "idempotency_key": "payout-2"})
+	require.NoError(t, err)
+	assert.Equal(t, "4294967299", resp.Data["sequence"])
+}
+
+func TestSequenceManager(t *testing.T) {
+	b, storage := getTestBackendAndStorage(t)
+
+	badSeqResult, err := xdr.MarshalBase64(xdr.TransactionResult{
+		FeeCharged: 100,
+		Result:     xdr.TransactionResultResult{Code: xdr.TransactionResultCodeTxBadSeq},
+	})
+	require.NoError(t, err)
+
+	publicKey := createTestAccount(t, b, storage, map[string]interface{}{})
+	var ledgerSequence atomic.Int64
+	ledgerSequence.Store(100)
+	horizonServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = fmt.Fprintf(w, `{"status": 400, "title": "Transaction Failed", "extras": {"result_xdr": %q}}`, badSeqResult)
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"id": %q, "sequence": "%d", "balances": [], "signers": []}`, publicKey, ledgerSequence.Load())
+	}))
+	defer horizonServer.Close()
+
+	_, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Data:      map[string]interface{}{"horizon_urls": map[string]interface{}{"Testnet": horizonServer.URL}},
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+
+	build := func(submit bool) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "accounts/" + publicKey + "/build",
+			Data: map[string]interface{}{
+				"network":    "Testnet",
+				"submit":     submit,
+				"operations": []interface{}{map[string]interface{}{"type": "manage_data", "name": "key", "value": "value"}},
+			},
+			Storage: storage,
+		})
+	}
+	readSequence := func() map[string]interface{} {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "accounts/" + publicKey + "/sequence",
+			Data:      map[string]interface{}{"network": "Testnet"},
+			Storage:   storage,
+		})
+		require.NoError(t, err)
+		return resp.Data
+	}
+
+	assert.Equal(t, false, readSequence()["tracked"])
+
+	const parallel = 10
+	sequences := make(chan string, parallel)
+	var wg sync.WaitGroup
+	for i := 0; i < parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := build(false)
+			if assert.NoError(t, err) {
+				sequences <- resp.Data["sequence"].(string)
+			}
+		}()
+	}
+	wg.Wait()
+	close(sequences)
+	seen := map[string]bool{}
+	for sequence := range sequences {
+		seen[sequence] = true
+	}
+	assert.Len(t, seen, parallel)
+	assert.True(t, seen["101"])
+	assert.True(t, seen["110"])
+	assert.Equal(t, "111", readSequence()["next_sequence"])
+
+	// tx_bad_seq drops the tracked state, the next build resyncs from Horizon
+	resp, err := build(true)
+	assert.ErrorContains(t, responseError(resp, err), "tx_bad_seq")
+	assert.Equal(t, false, readSequence()["tracked"])
+	ledgerSequence.Store(200)
+	resp, err = build(false)
+	require.NoError(t, err)
+	assert.Equal(t, "201", resp.Data["sequence"])
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "accounts/" + publicKey + "/sequence",
+		Data:      map[string]interface{}{"network": "Testnet"},
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "201", resp.Data["next_sequence"])
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "accounts/" + publicKey + "/sequence",
+		Data:      map[string]interface{}{"network": "Testnet", "next_sequence": "500"},
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	resp, err = build(false)
+	require.NoError(t, err)
+	assert.Equal(t, "500", resp.Data["sequence"])
+}
+
+func TestChannelPool(t *testing.T) {
+	b, storage := getTestBackendAndStorage(t)
+
+	var submitted []*txnbuild.Transaction
+	var mu sync.Mutex
+	horizonServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			require.NoError(t, r.ParseForm())
+			tx, err := txnbuild.TransactionFromXDR(r.PostForm.Get("tx"))
+			require.NoError(t, err)
+			inner, _ := tx.Transaction()
+			mu.Lock()
+			submitted = append(submitted, inner)
+			mu.Unlock()
+			hash, _ := inner.HashHex(network.TestNetworkPassphrase)
+			_, _ = fmt.Fprintf(w, `{"hash": %q, "ledger": 1234, "successful": true}`, hash)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"sequence": "100", "balances": [], "signers": []}`)
+	}))
+	defer horizonServer.Close()
+
+	_, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Data:      map[string]interface{}{"horizon_urls": map[string]interface{}{"Testnet": horizonServer.URL}},
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+
+	mainAccount := createTestAccount(t, b, storage, map[string]interface{}{})
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "channels/payouts",
+		Data:      map[string]interface{}{"account": mainAccount, "network": "Testnet", "size": 2, "fund": true},
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	channels := resp.Data["channels"].([]string)
+	require.Len(t, channels, 2)
+	require.Len(t, submitted, 1)
+	assert.Equal(t, mainAccount, submitted[0].SourceAccount().AccountID)
+	assert.Len(t, submitted[0].Operations(), 2)
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "channels/payouts",
+		Data:      map[string]interface{}{"size": 5},
+		Storage:   storage,
+	})
+	assert.ErrorContains(t, responseError(resp, err), "size cannot be changed")
+
+	// A pool whose funding failed is kept and the failure returned as a warning
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "channels/unfunded",
+		Data:      map[string]interface{}{"account": mainAccount, "network": "Public", "size": 1, "fund": true},
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Warnings, 1)
+	assert.Contains(t, resp.Warnings[0], "funding the channels failed")
+	assert.Len(t, resp.Data["channels"], 1)
+	assert.Nil(t, resp.Data["funding_transaction_hash"])
+
+	lease := func() (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "channels/payouts/lease",
+			Storage:   storage,
+		})
+	}
+	firstLease, err := lease()
+	require.NoError(t, err)
+	require.NotNil(t, firstLease.Secret)
+	assert.Equal(t, 60*time.Second, firstLease.Secret.TTL)
+	secondLease, err := lease()
+	require.NoError(t, err)
+	_, err = lease()
+	assert.ErrorContains(t, err, "no channel of pool payouts is available")
+
+	// Renewals extend the lease up to the maximum lease TTL of the mount
+	assert.Zero(t, firstLease.Secret.MaxTTL)
+	renewed, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.RenewOperation,
+		Secret:    firstLease.Secret,
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 60*time.Second, renewed.Secret.TTL)
+	assert.Zero(t, renewed.Secret.MaxTTL)
+
+	channel := firstLease.Data["channel"].(string)
+	build := func(leaseID string) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "channels/payouts/build",
+			Data: map[string]interface{}{
+				"channel":  channel,
+				"lease_id": leaseID,
+				"submit":   true,
+				"operations": []interface{}{map[string]interface{}{
+					"type":        "payment",
+					"destination": testTxSourceAccount,
+					"asset":       "native",
+					"amount":      "10",
+				}},
+			},
+			Storage: storage,
+		})
+	}
+	// Only the holder of the channel's lease builds on it
+	resp, err = build("")
+	assert.ErrorContains(t, responseError(resp, err), "missing lease_id")
+	_, err = build(secondLease.Data["lease_id"].(string))
+	assert.ErrorContains(t, err, "leased under another lease")
+	resp, err = build(firstLease.Data["lease_id"].(string))
+	require.NoError(t, err)
+	assert.Equal(t, "success", resp.Data["submission_status"])
+	assert.Equal(t, "101", resp.Data["sequence"])
+
+	signed, err := txnbuild.TransactionFromXDR(resp.Data["signed_transaction"].(string))
+	require.NoError(t, err)
+	tx, _ := signed.Transaction()
+	assert.Equal(t, channel, tx.SourceAccount().AccountID)
+	assert.Equal(t, mainAccount, tx.Operations()[0].GetSourceAccount())
+	assert.Len(t, tx.Signatures(), 2)
+
+	// The submitted channel is released, the revoked one is returned to the pool
+	resp, err = lease()
+	require.NoError(t, err)
+	assert.Equal(t, channel, resp.Data["channel"])
+	_, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.RevokeOperation,
+		Secret:    secondLease.Secret,
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	resp, err = lease()
+	require.NoError(t, err)
+	assert.Equal(t, secondLease.Data["channel"], resp.Data["channel"])
+
+	// Revoking a lease of a channel that was leased again leaves the new lease in place
+	_, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.RevokeOperation,
+		Secret:    firstLease.Secret,
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "channels/payouts",
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	assert.Len(t, resp.Data["leased"], 2)
+}
+
+func TestEphemeralCreds(t *testing.T) {
+	b, storage := getTestBackendAndStorage(t)
+
+	var submitted []*txnbuild.Transaction
+	var mu sync.Mutex
+	horizonServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			require.NoError(t, r.ParseForm())
+			tx, err := txnbuild.TransactionFromXDR(r.PostForm.Get("tx"))
+			require.NoError(t, err)
+			inner, _ := tx.Transaction()
+			mu.Lock()
+			submitted = append(submitted, inner)
+			mu.Unlock()
+			hash, _ := inner.HashHex(network.TestNetworkPassphrase)
+			_, _ = fmt.Fprintf(w, `{"hash": %q, "ledger": 1234, "successful": true}`, hash)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"sequence": "100", "balances": [], "signers": []}`)
+	}))
+	defer horizonServer.Close()
+
+	_, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Data:      map[string]interface{}{"horizon_urls": map[string]interface{}{"Testnet": horizonServer.URL}},
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+
+	fundingAccount := createTestAccount(t, b, storage, map[string]interface{}{})
+	parentAccount := createTestAccount(t, b, storage, map[string]interface{}{})
+	writeRole := func(name string, data map[string]interface{}) error {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "roles/" + name,
+			Data:      data,
+			Storage:   storage,
+		})
+		return responseError(resp, err)
+	}
+	issue := func(name string) *logical.Response {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "creds/" + name,
+			Storage:   storage,
+		})
+		require.NoError(t, err)
+		require.NotNil(t, resp.Secret)
+		return resp
+	}
+	revoke := func(resp *logical.Response) {
+		_, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.RevokeOperation,
+			Secret:    resp.Secret,
+			Storage:   storage,
+		})
+		require.NoError(t, err)
+	}
+
+	assert.ErrorContains(t, writeRole("bad", map[string]interface{}{"funding_account": fundingAccount}), "network must be provided")
+	require.NoError(t, writeRole("ci", map[string]interface{}{
+		"network":         "Testnet",
+		"funding_account": fundingAccount,
+		"ttl":             "15m",
+		"max_ttl":         "1h",
+	}))
+
+	resp := issue("ci")
+	assert.Equal(t, 15*time.Minute, resp.Secret.TTL)
+	assert.Equal(t, time.Hour, resp.Secret.MaxTTL)
+	ephemeral := resp.Data["public_key"].(string)
+	pair, err := keypair.ParseFull(resp.Data["secret_key"].(string))
+	require.NoError(t, err)
+	assert.Equal(t, ephemeral, pair.Address())
+	require.Len(t, submitted, 1)
+	assert.Equal(t, fundingAccount, submitted[0].SourceAccount().AccountID)
+	assert.Equal(t, ephemeral, submitted[0].Operations()[0].(*txnbuild.CreateAccount).Destination)
+
+	revoke(resp)
+	require.Len(t, submitted, 2)
+	assert.Equal(t, ephemeral, submitted[1].SourceAccount().AccountID)
+	assert.Equal(t, fundingAccount, submitted[1].Operations()[0].(*txnbuild.AccountMerge).Destination)
+	_, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "accounts/" + ephemeral,
+		Storage:   storage,
+	})
+	assert.ErrorContains(t, err, "does not exist")
+
+	require.NoError(t, writeRole("worker", map[string]interface{}{
+		"network":        "Testnet",
+		"parent_account": parentAccount,
+		"signer_weight":  5,
+	}))
+	resp = issue("worker")
+	assert.Equal(t, time.Hour, resp.Secret.TTL)
+	ephemeral = resp.Data["public_key"].(string)
+	require.Len(t, submitted, 3)
+	added := submitted[2].Operations()[0].(*txnbuild.SetOptions)
+	assert.Equal(t, ephemeral, added.Signer.Address)
+	assert.Equal(t, txnbuild.Threshold(5), added.Signer.Weight)
+
+	// The ephemeral account signs for the parent account
+	signResp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "accounts/" + ephemeral + "/sign",
+		Data:      map[string]interface{}{"transaction": buildTestTx(t, parentAccount, &txnbuild.BumpSequence{BumpTo: 1}), "network": "Testnet"},
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, signResp.Data["signed_transaction"])
+
+	revoke(resp)
+	require.Len(t, submitted, 4)
+	removed := submitted[3].Operations()[0].(*txnbuild.SetOptions)
+	assert.Equal(t, ephemeral, removed.Signer.Address)
+	assert.Equal(t, txnbuild.Threshold(0), removed.Signer.Weight)
+
+	// With ownership enforced, the account is owned by the entity it was issued to
+	_, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Data:      map[string]interface{}{"ownership": true},
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	require.NoError(t, writeRole("local", map[string]interface{}{"network": "Testnet"}))
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "creds/local",
+		Storage:   storage,
+		EntityID:  "entity-ci",
+	})
+	require.NoError(t, err)
+	ephemeral = resp.Data["public_key"].(string)
+	for entityID, visible := range map[string]bool{"entity-ci": true, "entity-other": false} {
+		_, err = b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "accounts/" + ephemeral,
+			Storage:   storage,
+			EntityID:  entityID,
+		})
+		assert.Equal(t, visible, err == nil, entityID)
+	}
+}
+
+func TestRoleSigning(t *testing.T) {
+	b, storage := getTestBackendAndStorage(t)
+
+	_, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "policies/payroll-limits",
+		Data:      map[string]interface{}{"max_validity_window": 3600},
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+
+	publicKey := createTestAccount(t, b, storage, map[string]interface{}{})
+	otherKey := createTestAccount(t, b, storage, map[string]interface{}{})
+	for name, data := range map[string]map[string]interface{}{
+		"payroll":        {"accounts": publicKey, "networks": "Testnet", "policy": "payroll-limits"},
+		"treasury-admin": {"accounts": []string{publicKey, otherKey}},
+	} {
+		_, err = b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "roles/" + name,
+			Data:      data,
+			Storage:   storage,
+		})
+		require.NoError(t, err)
+	}
+
+	signWithRole := func(role string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "roles/" + role + "/sign",
+			Data:      data,
+			Storage:   storage,
+		})
+	}
+	unbounded := buildTestTx(t, publicKey, &txnbuild.BumpSequence{BumpTo: 1})
+	bounded := buildTestTxWithPreconditions(t, publicKey, txnbuild.Preconditions{TimeBounds: txnbuild.NewTimeout(300)},
+		&txnbuild.BumpSequence{BumpTo: 1})
+
+	// The narrow role applies its policy and network set, the single account is implied
+	resp, err := signWithRole("payroll", map[string]interface{}{"transaction": unbounded, "network": "Testnet"})
+	assert.ErrorContains(t, responseError(resp, err), `policy "payroll-limits", rule max_validity_window`)
+	resp, err = signWithRole("payroll", map[string]interface{}{"transaction": bounded, "network": "Public"})
+	assert.ErrorContains(t, responseError(resp, err), "does not grant signing for network Public")
+	resp, err = signWithRole("payroll", map[string]interface{}{"publicKey": otherKey, "transaction": bounded, "network": "Testnet"})
+	assert.ErrorContains(t, responseError(resp, err), "does not grant signing with account")
+	resp, err = signWithRole("payroll", map[string]interface{}{"transaction": bounded, "network": "Testnet"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, resp.Data["signed_transaction"])
+
+	// The broad role signs the same key without those restrictions
+	resp, err = signWithRole("treasury-admin", map[string]interface{}{"transaction": unbounded, "network": "Public"})
+	assert.ErrorContains(t, responseError(resp, err), "publicKey must be provided")
+	resp, err = signWithRole("treasury-admin", map[string]interface{}{"publicKey": publicKey, "transaction": unbounded, "network": "Public"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, resp.Data["signed_transaction"])
+
+	historyResp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ListOperation,
+		Path:      "accounts/" + publicKey + "/history",
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	roles := []interface{}{}
+	for _, info := range historyResp.Data["key_info"].(map[string]interface{}) {
+		roles = append(roles, info.(map[string]interface{})["role"])
+	}
+	assert.ElementsMatch(t, []interface{}{"payroll", "treasury-admin"}, roles)
+}
+
+// TestAccountOwnership tests that accounts are scoped to their owning entity or group when ownership is enforced.
+func TestAccountOwnership(t *testing.T) {
+	request := getTestBackendWithGroups(t, map[string][]*logical.Group{
+		"entity-alice": {{ID: "group-treasury", Name: "treasury"}},
+		"entity-bob":   {{ID: "group-treasury", Name: "treasury"}},
+		"entity-admin": {{ID: "group-ops", Name: "ops"}},
+	})
+	_, err := request("", logical.UpdateOperation, "config", map[string]interface{}{"ownership": true, "admin_group": "ops"})
+	require.NoError(t, err)
+
+	createAccount := func(entityID string, data map[string]interface{}) string {
+		resp, err := request(entityID, logical.CreateOperation, "accounts", data)
+		require.NoError(t, err)
+		return resp.Data["public_key"].(string)
+	}
+	carolKey := createAccount("entity-carol", map[string]interface{}{})
+	treasuryKey := createAccount("entity-alice", map[string]interface{}{"owner_group": "treasury"})
+	resp, err := request("entity-carol", logical.CreateOperation, "accounts", map[string]interface{}{"owner_group": "treasury"})
+	assert.ErrorContains(t, responseError(resp, err), "owner_group must be a group the caller is a member of")
+
+	listKeys := func(entityID string) []string {
+		resp, err := request(entityID, logical.ListOperation, "accounts", nil)
+		require.NoError(t, err)
+		keys, _ := resp.Data["keys"].([]string)
+		return keys
+	}
+	assert.Equal(t, []string{carolKey}, listKeys("entity-carol"))
+	assert.Equal(t, []string{treasuryKey}, listKeys("entity-bob"))
+	assert.Empty(t, listKeys("entity-dave"))
+	assert.ElementsMatch(t, []string{carolKey, treasuryKey}, listKeys("entity-admin"))
+	// Tokens without an entity, root tokens included, are not admins
+	assert.Empty(t, listKeys(""))
+
+	sign := func(entityID string, publicKey string) error {
+		resp, err := request(entityID, logical.CreateOperation, "accounts/"+publicKey+"/sign", map[string]interface{}{
+			"transaction": buildTestTx(t, publicKey, &txnbuild.BumpSequence{BumpTo: 2}),
+			"network":     "Testnet",
+		})
+		return responseError(resp, err)
+	}
+	_, err = request("entity-dave", logical.ReadOperation, "accounts/"+carolKey, nil)
+	assert.ErrorContains(t, err, "stellar account does not exist")
+	assert.ErrorContains(t, sign("entity-dave", carolKey), "account not found")
+	assert.NoError(t, sign("entity-bob", treasuryKey))
+
+	// Sequence numbers, roles and channel pools are scoped the same way
+	_, err = request("entity-dave", logical.ReadOperation, "accounts/"+carolKey+"/sequence", map[string]interface{}{"network": "Testnet"})
+	assert.ErrorContains(t, err, "account not found")
+	_, err = request("entity-dave", logical.UpdateOperation, "accounts/"+carolKey+"/sequence",
+		map[string]interface{}{"network": "Testnet", "next_sequence": "5"})
+	assert.ErrorContains(t, err, "account not found")
+	bulkFunding := map[string]interface{}{
+		"count": 1, "funding_account": carolKey, "network": "Testnet", "starting_balance": "2", "sequence": "5",
+	}
+	_, err = request("entity-dave", logical.CreateOperation, "accounts/bulk", bulkFunding)
+	assert.ErrorContains(t, err, "account not found")
+	_, err = request("entity-carol", logical.CreateOperation, "accounts/bulk", bulkFunding)
+	require.NoError(t, err)
+	_, err = request("entity-dave", logical.UpdateOperation, "roles/dave", map[string]interface{}{"accounts": carolKey})
+	assert.ErrorContains(t, err, "not found")
+	_, err = request("entity-carol", logical.UpdateOperation, "roles/carol", map[string]interface{}{"accounts": carolKey})
+	require.NoError(t, err)
+	resp, err = request("entity-dave", logical.ListOperation, "roles", nil)
+	require.NoError(t, err)
+	assert.Empty(t, resp.Data["keys"])
+	_, err = request("entity-dave", logical.ReadOperation, "roles/carol", nil)
+	assert.ErrorContains(t, err, "role does not exist")
+	resp, err = request("entity-dave", logical.UpdateOperation, "roles/carol", map[string]interface{}{"networks": "Testnet"})
+	assert.ErrorContains(t, responseError(resp, err), "has accounts the caller cannot use")
+	_, err = request("entity-dave", logical.DeleteOperation, "roles/carol", nil)
+	require.NoError(t, err)
+	resp, err = request("entity-carol", logical.ReadOperation, "roles/carol", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{carolKey}, resp.Data["accounts"])
+	// A role delegates its writer's access, the caller needs no access to the account
+	_, err = request("entity-dave", logical.UpdateOperation, "roles/carol/sign", map[string]interface{}{
+		"transaction": buildTestTx(t, carolKey, &txnbuild.BumpSequence{BumpTo: 2}), "network": "Testnet",
+	})
+	assert.NoError(t, err)
+	_, err = request("entity-dave", logical.CreateOperation, "channels/dave", map[string]interface{}{"account": carolKey, "network": "Testnet", "size": 1})
+	assert.ErrorContains(t, err, "account not found")
+	_, err = request("entity-carol", logical.CreateOperation, "channels/carol", map[string]interface{}{"account": carolKey, "network": "Testnet", "size": 1})
+	require.NoError(t, err)
+	_, err = request("entity-dave", logical.UpdateOperation, "channels/carol/lease", nil)
+	assert.ErrorContains(t, err, "channel pool does not exist")
+	resp, err = request("entity-dave", logical.ListOperation, "channels", nil)
+	require.NoError(t, err)
+	assert.Empty(t, resp.Data["keys"])
+
+	// Sharing lets another entity read and sign with the account, but not change it
+	_, err = request("entity-dave", logical.UpdateOperation, "accounts/"+carolKey, map[string]interface{}{"shared_with": "entity-dave"})
+	assert.ErrorContains(t, err, "stellar account does not exist")
+	_, err = request("entity-carol", logical.UpdateOperation, "accounts/"+carolKey, map[string]interface{}{"shared_with": "entity-dave"})
+	require.NoError(t, err)
+	readResp, err := request("entity-dave", logical.ReadOperation, "accounts/"+carolKey, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "entity-carol", readResp.Data["owner_entity"])
+	assert.NoError(t, sign("entity-dave", carolKey))
+	resp, err = request("entity-dave", logical.CreateOperation, "channels/dave", map[string]interface{}{"account": carolKey, "network": "Testnet", "size": 1})
+	assert.ErrorContains(t, responseError(resp, err), "only the owner of the account can create a channel pool")
+	resp, err = request("entity-dave", logical.UpdateOperation, "accounts/"+carolKey, map[string]interface{}{"shared_with": ""})
+	assert.ErrorContains(t, responseError(resp, err), "only the owner of the account can update it")
+	resp, err = request("entity-dave", logical.DeleteOperation, "accounts/"+carolKey, nil)
+	assert.ErrorContains(t, responseError(resp, err), "only the owner of the account can delete it")
+	_, err = request("entity-admin", logical.DeleteOperation, "accounts/"+carolKey, nil)
+	require.NoError(t, err)
+	assert.Empty(t, listKeys("entity-dave"))
+}
+
+// TestApprovalWorkflow tests that transactions of accounts with an approval rule are only signed once a quorum approved them.
+func TestApprovalWorkflow(t *testing.T) {
+	request := getTestBackendWithGroups(t, map[string][]*logical.Group{
+		"entity-alice": {{ID: "group-treasury", Name: "treasury"}},
+		"entity-bob":   {{ID: "group-treasury", Name: "treasury"}},
+		"entity-carol": {{ID: "group-risk", Name: "risk"}},
+	})
+
+	resp, err := request("entity-requester", logical.CreateOperation, "accounts", map[string]interface{}{})
+	require.NoError(t, err)
+	publicKey := resp.Data["public_key"].(string)
+	resp, err = request("entity-requester", logical.UpdateOperation, "accounts/"+publicKey, map[string]interface{}{
+		"approver_groups": "treasury,risk", "approval_quorum": 3, "approval_distinct_groups": true,
+	})
+	assert.ErrorContains(t, responseError(resp, err), "approval_quorum cannot exceed the number of approver_groups")
+	// Repeated approvers count once
+	resp, err = request("entity-requester", logical.UpdateOperation, "accounts/"+publicKey, map[string]interface{}{
+		"approvers": "entity-alice,entity-alice", "approval_quorum": 2,
+	})
+	assert.ErrorContains(t, responseError(resp, err), "approval_quorum cannot exceed the number of approvers")
+	_, err = request("entity-requester", logical.UpdateOperation, "accounts/"+publicKey, map[string]interface{}{
+		"approver_groups": "treasury,risk", "approval_quorum": 2, "approval_distinct_groups": true,
+	})
+	require.NoError(t, err)
+
+	destination, _ := keypair.Random()
+	tx := buildTestTx(t, publicKey, &txnbuild.Payment{Destination: destination.Address(), Amount: "75000", Asset: txnbuild.NativeAsset{}})
+	resp, err = request("entity-requester", logical.CreateOperation, "accounts/"+publicKey+"/sign", map[string]interface{}{
+		"transaction": tx, "network": "Testnet",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "pending", resp.Data["status"])
+	assert.NotContains(t, resp.Data, "signed_transaction")
+
+	submit := func() string {
+		resp, err := request("entity-requester", logical.UpdateOperation, "requests", map[string]interface{}{
+			"publicKey": publicKey, "transaction": tx, "network": "Testnet", "comment": "supplier payment",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "pending", resp.Data["status"])
+		operations := resp.Data["summary"].(map[string]interface{})["operations"].([]map[string]interface{})
+		assert.Equal(t, "payment", operations[0]["type"])
+		assert.Equal(t, "75000.0000000", operations[0]["amount"])
+		return resp.Data["id"].(string)
+	}
+	decide := func(entityID string, id string, decision string) (*logical.Response, error) {
+		return request(entityID, logical.UpdateOperation, "requests/"+id+"/"+decision, map[string]interface{}{"comment": decision})
+	}
+
+	// A rejection by any approver rejects the request
+	rejected := submit()
+	resp, err = decide("entity-dave", rejected, "approve")
+	assert.ErrorContains(t, responseError(resp, err), "is not an approver")
+	resp, err = decide("entity-carol", rejected, "reject")
+	require.NoError(t, err)
+	assert.Equal(t, "rejected", resp.Data["status"])
+	_, err = decide("entity-alice", rejected, "approve")
+	assert.ErrorContains(t, err, "approval request is rejected")
+
+	// Approvals must come from distinct groups and the requester cannot approve
+	id := submit()
+	resp, err = decide("entity-requester", id, "approve")
+	assert.ErrorContains(t, responseError(resp, err), "cannot decide on their own request")
+	resp, err = decide("entity-alice", id, "approve")
+	require.NoError(t, err)
+	assert.Equal(t, "pending", resp.Data["status"])
+	_, err = decide("entity-alice", id, "approve")
+	assert.ErrorContains(t, err, "already decided")
+	_, err = decide("entity-bob", id, "approve")
+	assert.ErrorContains(t, err, "distinct approver groups")
+	resp, err = decide("entity-carol", id, "approve")
+	require.NoError(t, err)
+	assert.Equal(t, "approved", resp.Data["status"])
+	assert.NotContains(t, resp.Data, "signed_transaction")
+
+	// The signed transaction is released to the requester only
+	resp, err = request("entity-carol", logical.ReadOperation, "requests/"+id, nil)
+	require.NoError(t, err)
+	assert.NotContains(t, resp.Data, "signed_transaction")
+	resp, err = request("entity-requester", logical.ReadOperation, "requests/"+id, nil)
+	require.NoError(t, err)
+	assert.NotEmpty(t, resp.Data["signed_transaction"])
+	assert.Len(t, resp.Data["decisions"], 2)
+
+	resp, err = request("", logical.ListOperation, "accounts/"+publicKey+"/history", nil)
+	require.NoError(t, err)
+	assert.Equal(t, id, resp.Data["key_info"].(map[string]interface{})[resp.Data["keys"].([]string)[0]].(map[string]interface{})["approval_request"])
+
+	// Pending requests expire after the approval TTL
+	_, err = request("entity-requester", logical.UpdateOperation, "accounts/"+publicKey, map[string]interface{}{"approval_ttl": 1})
+	require.NoError(t, err)
+	expiring := submit()
+	time.Sleep(1100 * time.Millisecond)
+	_, err = decide("entity-alice", expiring, "approve")
+	assert.ErrorContains(t, err, "approval request is expired")
+	resp, err = request("", logical.ListOperation, "requests", nil)
+	require.NoError(t, err)
+	assert.Len(t, resp.Data["keys"], 4)
+	assert.Equal(t, "expired", resp.Data["key_info"].(map[string]interface{})[expiring].(map[string]interface{})["status"])
+
+	// With ownership enforced, requests are visible to the account's users and the approvers only
+	_, err = request("", logical.UpdateOperation, "config", map[string]interface{}{"ownership": true})
+	require.NoError(t, err)
+	for entityID, count := range map[string]int{"entity-requester": 4, "entity-carol": 4, "entity-dave": 0} {
+		resp, err = request(entityID, logical.ListOperation, "requests", nil)
+		require.NoError(t, err)
+		keys, _ := resp.Data["keys"].([]string)
+		assert.Len(t, keys, count, entityID)
+	}
+	_, err = request("entity-dave", logical.ReadOperation, "requests/"+id, nil)
+	assert.ErrorContains(t, err, "approval request not found")
+}
+
+// TestApprovalTiers tests that policy tiers route transactions by value to signing or to an approval request.
+func TestApprovalTiers(t *testing.T) {
+	request := getTestBackendWithGroups(t, map[string][]*logical.Group{
+		"entity-alice": {{ID: "group-treasury", Name: "treasury"}},
+		"entity-bob":   {{ID: "group-treasury", Name: "treasury"}},
+	})
+
+	usdc := "USDC:GA5ZSEJYB37JRC5AVCIA5MOP4RHTM335X2KGX3IHOJAPP5RE34K4KZVN"
+	_, err := request("", logical.UpdateOperation, "config", map[string]interface{}{"prices": map[string]interface{}{usdc: "10"}})
+	require.NoError(t, err)
+	_, err = request("", logical.UpdateOperation, "policies/payment-tiers", map[string]interface{}{
+		"approval_tiers": []interface{}{
+			map[string]interface{}{"approvals": 2, "approver_groups": []string{"treasury"}},
+			map[string]interface{}{"max_value": "50000", "approvals": 1},
+			map[string]interface{}{"max_value": "1000", "approvals": 0},
+		},
+	})
+	require.NoError(t, err)
+	resp, err := request("", logical.UpdateOperation, "policies/repeated-approvers", map[string]interface{}{
+		"approval_tiers": []interface{}{map[string]interface{}{"approvals": 2, "approvers": []string{"entity-alice", "entity-alice"}}},
+	})
+	assert.ErrorContains(t, responseError(resp, err), "approvals cannot exceed the number of approvers in approval tier 0")
+	policyResp, err := request("", logical.ReadOperation, "policies/payment-tiers", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "1000", policyResp.Data["approval_tiers"].([]map[string]interface{})[0]["max_value"])
+
+	resp, err = request("entity-requester", logical.CreateOperation, "accounts", map[string]interface{}{})
+	require.NoError(t, err)
+	publicKey := resp.Data["public_key"].(string)
+	_, err = request("entity-requester", logical.UpdateOperation, "accounts/"+publicKey, map[string]interface{}{
+		"policy": "payment-tiers", "approvers": "entity-carol",
+	})
+	require.NoError(t, err)
+
+	destination, _ := keypair.Random()
+	issuer := strings.Split(usdc, ":")[1]
+	sign := func(ops ...txnbuild.Operation) map[string]interface{} {
+		resp, err := request("entity-requester", logical.CreateOperation, "accounts/"+publicKey+"/sign", map[string]interface{}{
+			"transaction": buildTestTx(t, publicKey, ops...), "network": "Testnet",
+		})
+		require.NoError(t, err)
+		return resp.Data
+	}
+	payment := func(amount string, asset txnbuild.Asset) txnbuild.Operation {
+		return &txnbuild.Payment{Destination: destination.Address(), Amount: amount, Asset: asset}
+	}
+
+	// Under 1,000 XLM-equivalent is signed right away
+	assert.NotEmpty(t, sign(payment("999", txnbuild.NativeAsset{}))["signed_transaction"])
+
+	// Up to 50k needs one approval from the account's approvers
+	pending := sign(payment("400", txnbuild.CreditAsset{Code: "USDC", Issuer: issuer}), payment("900", txnbuild.NativeAsset{}))
+	assert.Equal(t, "pending", pending["status"])
+	assert.Equal(t, 1, pending["quorum"])
+	resp, err = request("entity-carol", logical.UpdateOperation, "requests/"+pending["id"].(string)+"/approve", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "approved", resp.Data["status"])
+
+	// Above that, or when the value is unknown, two approvals from treasury are needed
+	for _, op := range []txnbuild.Operation{
+		payment("60000", txnbuild.NativeAsset{}),
+		payment("1", txnbuild.CreditAsset{Code: "EURC", Issuer: issuer}),
+		&txnbuild.AccountMerge{Destination: destination.Address()},
+		&txnbuild.ManageSellOffer{Selling: txnbuild.NativeAsset{}, Buying: txnbuild.CreditAsset{Code: "USDC", Issuer: issuer}, Amount: "1", Price: xdr.Price{N: 1, D: 1}},
+		&txnbuild.SetOptions{Signer: &txnbuild.Signer{Address: destination.Address(), Weight: 255}},
+	} {
+		pending = sign(op)
+		assert.Equal(t, "pending", pending["status"])
+		assert.Equal(t, 2, pending["quorum"])
+	}
+	resp, err = request("entity-carol", logical.UpdateOperation, "requests/"+pending["id"].(string)+"/approve", nil)
+	assert.ErrorContains(t, responseError(resp, err), "is not an approver")
+	for _, entityID := range []string{"entity-alice", "entity-bob"} {
+		resp, err = request(entityID, logical.UpdateOperation, "requests/"+pending["id"].(string)+"/approve", nil)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, "approved", resp.Data["status"])
+
+	// When the account requires approval itself, the approvers of the tier are needed as well
+	_, err = request("entity-requester", logical.UpdateOperation, "accounts/"+publicKey, map[string]interface{}{"approval_quorum": 1})
+	require.NoError(t, err)
+	pending = sign(payment("60000", txnbuild.NativeAsset{}))
+	assert.Equal(t, 1, pending["quorum"])
+	also := pending["rule"].(map[string]interface{})["also"].([]map[string]interface{})
+	require.Len(t, also, 1)
+	assert.Equal(t, []string{"treasury"}, also[0]["approver_groups"])
+	for _, decision := range []struct{ entityID, status string }{
+		{"entity-carol", "pending"}, {"entity-alice", "pending"}, {"entity-bob", "approved"},
+	} {
+		resp, err = request(decision.entityID, logical.UpdateOperation, "requests/"+pending["id"].(string)+"/approve", nil)
+		require.NoError(t, err)
+		assert.Equal(t, decision.status, resp.Data["status"], decision.entityID)
+	}
+}
+
+// TestEvents tests that account and signing lifecycle events are sent without secrets.
+func TestEvents(t *testing.T) {
+	events := &testEventRecorder{}
+	config := logical.TestBackendConfig()
+	config.EventsSender = events
+	storage := &logical.InmemStorage{}
+	config.StorageView = storage
+	b := newTestBackend(t, config)
+
+	_, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "policies/bounded",
+		Data:      map[string]interface{}{"require_time_bounds": true},
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	publicKey := createTestAccount(t, b, storage, map[string]interface{}{})
+	imported, _ := keypair.Random()
+	createTestAccount(t, b, storage, map[string]interface{}{"secret_key": imported.Seed(), "policy": "bounded"})
+
+	sign := func(publicKey string, tx string) error {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "accounts/" + publicKey + "/sign",
+			Data:      map[string]interface{}{"transaction": tx, "network": "Testnet"},
+			Storage:   storage,
+		})
+		return responseError(resp, err)
+	}
+	destination, _ := keypair.Random()
+	require.NoError(t, sign(publicKey, buildTestTx(t, publicKey, &txnbuild.Payment{
+		Destination: destination.Address(), Amount: "10", Asset: txnbuild.NativeAsset{},
+	}, &txnbuild.BumpSequence{BumpTo: 2})))
+	assert.Error(t, sign(imported.Address(), buildTestTxWithPreconditions(t, imported.Address(),
+		txnbuild.Preconditions{TimeBounds: txnbuild.NewInfiniteTimeout()}, &txnbuild.BumpSequence{BumpTo: 2})))
+
+	_, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.DeleteOperation,
+		Path:      "accounts/" + publicKey,
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+
+	recorded := events.recorded()
+	require.Len(t, recorded, 5)
+	assert.Equal(t, []logical.EventType{
+		"stellar/account-create", "stellar/account-import", "stellar/sign", "stellar/policy-denial", "stellar/account-delete",
+	}, []logical.EventType{recorded[0].eventType, recorded[1].eventType, recorded[2].eventType, recorded[3].eventType, recorded[4].eventType})
+
+	signed := recorded[2].metadata
+	assert.Equal(t, publicKey, signed["public_key"])
+	assert.Equal(t, "Testnet", signed["network"])
+	assert.Equal(t, "payment,bump_sequence", signed["operation_types"])
+	assert.Len(t, signed["tx_hash"], 64)
+	denied := recorded[3].metadata
+	assert.Equal(t, "bounded", denied["policy"])
+	assert.Equal(t, "require_time_bounds", denied["rule"])
+	for _, event := range recorded {
+		for _, value := range event.metadata {
+			assert.NotContains(t, value, imported.Seed())
+		}
+	}
+}
+
+// TestTelemetry tests the handler, signature and denial metrics and the configurable signer label.
+func TestTelemetry(t *testing.T) {
+	sink := metrics.NewInmemSink(time.Hour, time.Hour)
+	metricsConfig := metrics.DefaultConfig("vault")
+	metricsConfig.EnableHostname = false
+	_, err := metrics.NewGlobal(metricsConfig, sink)
+	require.NoError(t, err)
+	defer metrics.NewGlobal(metrics.DefaultConfig("vault"), &metrics.BlackholeSink{})
+
+	b, storage := getTestBackendAndStorage(t)
+	_, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "policies/bounded",
+		Data:      map[string]interface{}{"require_time_bounds": true},
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	publicKey := createTestAccount(t, b, storage, map[string]interface{}{})
+	boundedKey := createTestAccount(t, b, storage, map[string]interface{}{"policy": "bounded"})
+
+	sign := func(publicKey string) {
+		_, _ = b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "accounts/" + publicKey + "/sign",
+			Data:      map[string]interface{}{"transaction": buildTestTx(t, publicKey, &txnbuild.BumpSequence{BumpTo: 2}), "network": "Testnet"},
+			Storage:   storage,
+		})
+	}
+	sign(publicKey)
+	sign(boundedKey)
+	_, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Data:      map[string]interface{}{"metrics_account_label": "none"},
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	sign(publicKey)
+
+	counters := sink.Data()[0].Counters
+	counter := func(key string) int {
+		if c, ok := counters[key]; ok {
+			return c.Count
+		}
+		return 0
+	}
+	assert.Equal(t, 3, counter("vault.stellar.handler.requests;operation=sign_tx"))
+	assert.Equal(t, 1, counter("vault.stellar.handler.errors;operation=sign_tx"))
+	assert.Equal(t, 1, counter("vault.stellar.signatures;network=Testnet;public_key="+publicKey))
+	assert.Equal(t, 1, counter("vault.stellar.signatures;network=Testnet"))
+	assert.Equal(t, 1, counter("vault.stellar.policy.denials;policy=bounded;rule=require_time_bounds;public_key="+boundedKey))
+	assert.Contains(t, sink.Data()[0].Samples, "vault.stellar.handler.latency;operation=sign_tx")
+	assert.Contains(t, sink.Data()[0].Samples, "vault.stellar.storage.latency;operation=get")
+}
+
+func randomContractID(t *testing.T) xdr.Hash {
+	var id xdr.Hash
+	_, err := rand.Read(id[:])
+	require.NoError(t, err)
+	return id
+}
+
+func testInvocation(contract xdr.Hash, function string, arg string) xdr.InvokeContractArgs {
+	sym := xdr.ScSymbol(arg)
+	return xdr.InvokeContractArgs{
+		ContractAddress: xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeContract, ContractId: &contract},
+		FunctionName:    xdr.ScSymbol(function),
+		Args:            []xdr.ScVal{{Type: xdr.ScValTypeScvSymbol, Sym: &sym}},
+	}
+}
+
+// createTestAccount creates a Stellar account through the backend and returns its public key.
+func createTestAccount(t *testing.T, b logical.Backend, storage logical.Storage, data map[string]interface{}) string {
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "accounts",
+		Data:      data,
+		Storage:   storage,
+	})
+	require.NoError(t, err)
+	return resp.Data["public_key"].(string)
+}
+
+// buildTestTx builds a base64 encoded transaction envelope for the given source account.
+func buildTestTx(t *testing.T, source string, ops ...txnbuild.Operation) string {
+	return buildTestTxWithPreconditions(t, source, txnbuild.Preconditions{TimeBounds: txnbuild.NewInfiniteTimeout()}, ops...)
+}
+
+// buildTestTxWithPreconditions builds a base64 encoded transaction envelope with the given preconditions.
+func buildTestTxWithPreconditions(t *testing.T, source string, preconditions txnbuild.Preconditions, ops ...txnbuild.Operation) string {
+	tx, err := txnbuild.NewTransaction(txnbuild.TransactionParams{
+		SourceAccount: &txnbuild.SimpleAccount{AccountID: source, Sequence: 1},
+		Operations:    ops,
+		BaseFee:       txnbuild.MinBaseFee,
+		Preconditions: preconditions,
+	})
+	require.NoError(t, err)
+	txBase64, err := tx.Base64()
+	require.NoError(t, err)
+	return txBase64
+}
+
+// testTxSourceAccount is the source account of the transaction signed in TestSignStellarTx
+const testTxSourceAccount = "GAJ2GM7LGQ2FHKFTWZMUPZMQLMNSRNEQG67X6VSYKGTP5TBTG6HXNLM2"
+
+// testTransaction returns a payment of testTxSourceAccount valid for an hour, as
+// transactions whose max time is past are never signed.
+func testTransaction(t *testing.T) string {
+	expired, err := txnbuild.TransactionFromXDR("AAAAAgAAAAATozPrNDRTqLO2WUflkFsbKLSQN79/VlhRpv7MMzePdgAAAGQAAMGGAAAAAQAAAAEAAAAAAAAAAAAAAABlhHryAAAAAAAAAAEAAAABAAAAABOjM+s0NFOos7ZZR+WQWxsotJA3v39WWFGm/swzN492AAAAAQAAAAB69J8A290AJGAqNy4f0QIXBG4NoPQm7B+vDdeR0AvXRQAAAAAAAAACVAvkAAAAAAAAAAAA")
+	require.NoError(t, err)
+	payment, _ := expired.Transaction()
+	tx, err := txnbuild.NewTransaction(txnbuild.TransactionParams{
+		SourceAccount: &txnbuild.SimpleAccount{AccountID: testTxSourceAccount, Sequence: payment.SequenceNumber()},
+		Operations:    payment.Operations(),
+		BaseFee:       payment.BaseFee(),
+		Preconditions: txnbuild.Preconditions{TimeBounds: txnbuild.NewTimeout(3600)},
+	})
+	require.NoError(t, err)
+	txBase64, err := tx.Base64()
+	require.NoError(t, err)
+	return txBase64
+}
+
+// testGroupsSystemView reports the identity group memberships of test entities
+type testGroupsSystemView struct {
+	logical.SystemView
+	groups map[string][]*logical.Group
+}
+
+func (v testGroupsSystemView) GroupsForEntity(entityID string) ([]*logical.Group, error) {
+	return v.groups[entityID], nil
+}
+
+// testEventRecorder records the events sent by the backend
+type testEventRecorder struct {
+	lock   sync.Mutex
+	events []testEvent
+}
+
+type testEvent struct {
+	eventType logical.EventType
+	metadata  map[string]string
+}
+
+func (r *testEventRecorder) SendEvent(ctx context.Context, eventType logical.EventType, event *logical.EventData) error {
+	metadata := map[string]string{}
+	for key, value := range event.Metadata.AsMap() {
+		metadata[key] = value.(string)
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.events = append(r.events, testEvent{eventType: eventType, metadata: metadata})
+	return nil
+}
+
+func (r *testEventRecorder) recorded() []testEvent {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]testEvent(nil), r.events...)
+}
+
+// testEntityRequest makes a request with the token of an identity entity, or with a token without entity when entityID is empty.
+// As in Vault, the plugin is not given the token entry of the request.
+type testEntityRequest func(entityID string, operation logical.Operation, path string, data map[string]interface{}) (*logical.Response, error)
+
+// getTestBackendWithGroups creates a backend whose entities are members of the given identity groups.
+func getTestBackendWithGroups(t *testing.T, groups map[string][]*logical.Group) testEntityRequest {
+	config := logical.TestBackendConfig()
+	config.System = testGroupsSystemView{SystemView: config.System, groups: groups}
+	storage := &logical.InmemStorage{}
+	config.StorageView = storage
+	b := newTestBackend(t, config)
+
+	return func(entityID string, operation logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+		req := &logical.Request{
+			Operation: operation,
+			Path:      path,
+			Data:      data,
+			Storage:   storage,
+			EntityID:  entityID,
+		}
+		return b.HandleRequest(context.Background(), req)
+	}
+}
+
+// getTestBackendAndStorage is a helper function to create a Backend and in-memory storage for testing.
+func getTestBackendAndStorage(t *testing.T) (logical.Backend, logical.Storage) {
+	// The backend works in the background with the storage it was set up with, which is
+	// the storage of every request in Vault
+	config := logical.TestBackendConfig()
+	storage := &logical.InmemStorage{}
+	config.StorageView = storage
+	return newTestBackend(t, config), storage
+}
+
+// responseStatus returns the HTTP status and the error Vault answers a response of the plugin with.
+func responseStatus(resp *logical.Response, err error) (int, error) {
+	status, err := logical.RespondErrorCommon(&logical.Request{}, resp, err)
+	logical.AdjustErrorStatusCode(&status, err)
+	return status, err
+}
+
+// responseError returns the error Vault answers a response of the plugin with.
+func responseError(resp *logical.Response, err error) error {
+	_, err = responseStatus(resp, err)
+	return err
+}
+
+// testKeyEncryptionSecret is the key encryption secret of the test backends.
+const testKeyEncryptionSecret = "c2VjcmV0LWtleS1lbmNyeXB0aW9uLXRlc3Qtc2VjcmV0LQ=="
+
+func newTestBackend(t *testing.T, config *logical.BackendConfig) logical.Backend {
+	t.Setenv(stellar.KeyEncryptionSecretEnv, testKeyEncryptionSecret)
+	b, err := Factory(context.Background(), config)
+	require.NoError(t, err)
+	return b
 }
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"vault-plugin-stellar-sign/internal/backend/stellar"
+)
+
+type ApproveRequestHandler struct {
+	manager *stellar.Manager
+}
+
+func NewApproveRequestHandler(m *stellar.Manager) *ApproveRequestHandler {
+	return &ApproveRequestHandler{manager: m}
+}
+
+func (h *ApproveRequestHandler) Handler() framework.OperationFunc {
+	return instrument("approve_request", h.manager.ApproveRequest)
+}
+
+func (h *ApproveRequestHandler) Properties() framework.OperationProperties {
+	return framework.OperationProperties{
+		Summary: "Approves a pending request",
+		Description: "This operation records the approval of the calling entity, which must be an approver of the request " +
+			"other than the requester. Reaching the quorum signs the transaction.",
+		Examples: []framework.RequestExample{
+			{
+				Description: "Approve a request",
+				Data: map[string]interface{}{
+					"id":      "0b2f6f3e-5a4c-4d0b-9a8e-3c1f2d7e6b5a",
+					"comment": "Matches invoice 2024-117",
+				},
+				Response: &framework.Response{
+					Description: "The approval request with the decision recorded",
+					MediaType:   "application/json",
+					Example: &logical.Response{
+						Data: map[string]interface{}{
+							"id":               "0b2f6f3e-5a4c-4d0b-9a8e-3c1f2d7e6b5a",
+							"public_key":       "GATBMIXGZKJGSEVJQH7D2ZP3A4UQ4WKB3X5H3C6KHPGJRH4B3U5UJ6CH",
+							"network":          "Public",
+							"transaction_hash": "3389e9f0f1a65f19736cacf544c2e825313e8447f569233bb8db39aa607c8889",
+							"status":           "approved",
+							"approvals":        2,
+							"quorum":           2,
+						},
+					},
+				},
+			},
+		},
+		ForwardPerformanceStandby:   true,
+		ForwardPerformanceSecondary: true,
+	}
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"vault-plugin-stellar-sign/internal/backend/stellar"
+)
+
+type CreateApprovalRequestHandler struct {
+	manager *stellar.Manager
+}
+
+func NewCreateApprovalRequestHandler(m *stellar.Manager) *CreateApprovalRequestHandler {
+	return &CreateApprovalRequestHandler{manager: m}
+}
+
+func (h *CreateApprovalRequestHandler) Handler() framework.OperationFunc {
+	return instrument("create_approval_request", h.manager.CreateApprovalRequest)
+}
+
+func (h *CreateApprovalRequestHandler) Properties() framework.OperationProperties {
+	return framework.OperationProperties{
+		Summary: "Submits a transaction for approval",
+		Description: "This operation stores a transaction of an account with an approval rule as pending, with a decoded " +
+			"summary for the approvers. It is signed once the quorum of approvers is reached.",
+		Examples: []framework.RequestExample{
+			{
+				Description: "Submit a payment for approval",
+				Data: map[string]interface{}{
+					"publicKey":   "GATBMIXGZKJGSEVJQH7D2ZP3A4UQ4WKB3X5H3C6KHPGJRH4B3U5UJ6CH",
+					"transaction": "base64EncodedTransactionEnvelope",
+					"network":     "Public",
+					"comment":     "Quarterly supplier payment",
+				},
+				Response: &framework.Response{
+					Description: "The pending approval request",
+					MediaType:   "application/json",
+					Example: &logical.Response{
+						Data: map[string]interface{}{
+							"id":               "0b2f6f3e-5a4c-4d0b-9a8e-3c1f2d7e6b5a",
+							"public_key":       "GATBMIXGZKJGSEVJQH7D2ZP3A4UQ4WKB3X5H3C6KHPGJRH4B3U5UJ6CH",
+							"network":          "Public",
+							"transaction_hash": "3389e9f0f1a65f19736cacf544c2e825313e8447f569233bb8db39aa607c8889",
+							"status":           "pending",
+							"approvals":        0,
+							"quorum":           2,
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"vault-plugin-stellar-sign/internal/backend/stellar"
+)
+
+type ListApprovalRequestsHandler struct {
+	manager *stellar.Manager
+}
+
+func NewListApprovalRequestsHandler(m *stellar.Manager) *ListApprovalRequestsHandler {
+	return &ListApprovalRequestsHandler{manager: m}
+}
+
+func (h *ListApprovalRequestsHandler) Handler() framework.OperationFunc {
+	return instrument("list_approval_requests", h.manager.ListApprovalRequests)
+}
+
+func (h *ListApprovalRequestsHandler) Properties() framework.OperationProperties {
+	return framework.OperationProperties{
+		Summary:     "Lists approval requests",
+		Description: "Retrieves the IDs of the approval requests with their account, status, requester and expiry.",
+		Examples: []framework.RequestExample{
+			{
+				Description: "List approval requests",
+				Response: &framework.Response{
+					Description: "Successful retrieval of the approval requests",
+					MediaType:   "application/json",
+					Example: &logical.Response{
+						Data: map[string]interface{}{
+							"keys": []string{"0b2f6f3e-5a4c-4d0b-9a8e-3c1f2d7e6b5a"},
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"vault-plugin-stellar-sign/internal/backend/stellar"
+)
+
+type ReadApprovalRequestHandler struct {
+	manager *stellar.Manager
+}
+
+func NewReadApprovalRequestHandler(m *stellar.Manager) *ReadApprovalRequestHandler {
+	return &ReadApprovalRequestHandler{manager: m}
+}
+
+func (h *ReadApprovalRequestHandler) Handler() framework.OperationFunc {
+	return instrument("read_approval_request", h.manager.ReadApprovalRequest)
+}
+
+func (h *ReadApprovalRequestHandler) Properties() framework.OperationProperties {
+	return framework.OperationProperties{
+		Summary: "Reads an approval request",
+		Description: "Retrieves an approval request with its transaction summary and decisions. Once approved, the signed " +
+			"transaction is returned to the requester.",
+		Examples: []framework.RequestExample{
+			{
+				Description: "Read an approved request as the requester",
+				Data: map[string]interface{}{
+					"id": "0b2f6f3e-5a4c-4d0b-9a8e-3c1f2d7e6b5a",
+				},
+				Response: &framework.Response{
+					Description: "Successful retrieval of the approval request",
+					MediaType:   "application/json",
+					Example: &logical.Response{
+						Data: map[string]interface{}{
+							"id":                 "0b2f6f3e-5a4c-4d0b-9a8e-3c1f2d7e6b5a",
+							"public_key":         "GATBMIXGZKJGSEVJQH7D2ZP3A4UQ4WKB3X5H3C6KHPGJRH4B3U5UJ6CH",
+							"network":            "Public",
+							"transaction_hash":   "3389e9f0f1a65f19736cacf544c2e825313e8447f569233bb8db39aa607c8889",
+							"status":             "approved",
+							"approvals":          2,
+							"quorum":             2,
+							"signed_transaction": "base64EncodedSignedTransactionEnvelope",
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"vault-plugin-stellar-sign/internal/backend/stellar"
+)
+
+type RejectRequestHandler struct {
+	manager *stellar.Manager
+}
+
+func NewRejectRequestHandler(m *stellar.Manager) *RejectRequestHandler {
+	return &RejectRequestHandler{manager: m}
+}
+
+func (h *RejectRequestHandler) Handler() framework.OperationFunc {
+	return instrument("reject_request", h.manager.RejectRequest)
+}
+
+func (h *RejectRequestHandler) Properties() framework.OperationProperties {
+	return framework.OperationProperties{
+		Summary: "Rejects a pending request",
+		Description: "This operation records the rejection of the calling entity, which must be an approver of the request " +
+			"other than the requester. A single rejection rejects the request.",
+		Examples: []framework.RequestExample{
+			{
+				Description: "Reject a request",
+				Data: map[string]interface{}{
+					"id":      "0b2f6f3e-5a4c-4d0b-9a8e-3c1f2d7e6b5a",
+					"comment": "Unknown destination",
+				},
+				Response: &framework.Response{
+					Description: "The approval request with the decision recorded",
+					MediaType:   "application/json",
+					Example: &logical.Response{
+						Data: map[string]interface{}{
+							"id":               "0b2f6f3e-5a4c-4d0b-9a8e-3c1f2d7e6b5a",
+							"public_key":       "GATBMIXGZKJGSEVJQH7D2ZP3A4UQ4WKB3X5H3C6KHPGJRH4B3U5UJ6CH",
+							"network":          "Public",
+							"transaction_hash": "3389e9f0f1a65f19736cacf544c2e825313e8447f569233bb8db39aa607c8889",
+							"status":           "rejected",
+							"approvals":        0,
+							"quorum":           2,
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"vault-plugin-stellar-sign/internal/backend/stellar"
+)
+
+type BuildTxHandler struct {
+	manager *stellar.Manager
+}
+
+func NewBuildTxHandler(m *stellar.Manager) *BuildTxHandler {
+	return &BuildTxHandler{manager: m}
+}
+
+func (h *BuildTxHandler) Handler() framework.OperationFunc {
+	return instrument("build_tx", h.manager.BuildTx)
+}
+
+func (h *BuildTxHandler) Properties() framework.OperationProperties {
+	return framework.OperationProperties{
+		Summary: "Builds and signs a Stellar transaction",
+		Description: "This operation builds a Stellar transaction from a JSON description of its operations, " +
+			"checks it against the signing policies and signs it using the secret key of the specified account. " +
+			"Supported operation types are payment, path_payment_strict_receive, path_payment_strict_send, " +
+			"change_trust, create_account, begin_sponsoring_future_reserves, end_sponsoring_future_reserves, " +
+			"manage_data, set_options, account_merge, create_claimable_balance and claim_claimable_balance.",
+		// Sequence numbers are allocated under a lock held by the active node only
+		ForwardPerformanceStandby:   true,
+		ForwardPerformanceSecondary: true,
+		Examples: []framework.RequestExample{
+			{
+				Description: "Build and sign a payment",
+				Data: map[string]interface{}{
+					"publicKey": "GATBMIXGZKJGSEVJQH7D2ZP3A4UQ4WKB3X5H3C6KHPGJRH4B3U5UJ6CH",
+					"network":   "Testnet",
+					"operations": []map[string]interface{}{
+						{
+							"type":        "payment",
+							"destination": "GASYNOBIVOZZJGBH6C5K2FPJQ2RPN2QBJ7OO6OXENIG5S2IKRALFTKIA",
+							"asset":       "native",
+							"amount":      "10",
+						},
+					},
+					"memo": "invoice 42",
+				},
+				Response: &framework.Response{
+					Description: "Successful building and signing of the Stellar transaction",
+					MediaType:   "application/json",
+					Example: &logical.Response{
+						Data: map[string]interface{}{
+							"signed_transaction": "base64EncodedSignedTransactionEnvelope",
+							"transaction_hash":   "3389e9f0f1a65f19736cacf544c2e825313e8447f569233bb8db39aa607c8889",
+							"sequence":           "4294967297",
+						},
+					},
+				},
+			},
+		},
+	}
+}
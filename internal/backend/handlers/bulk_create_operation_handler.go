@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"vault-plugin-stellar-sign/internal/backend/stellar"
+)
+
+type BulkCreateAccountsHandler struct {
+	manager *stellar.Manager
+}
+
+func NewBulkCreateAccountsHandler(m *stellar.Manager) *BulkCreateAccountsHandler {
+	return &BulkCreateAccountsHandler{manager: m}
+}
+
+func (h *BulkCreateAccountsHandler) Handler() framework.OperationFunc {
+	return instrument("bulk_create_accounts", h.manager.BulkCreateAccounts)
+}
+
+func (h *BulkCreateAccountsHandler) Properties() framework.OperationProperties {
+	return framework.OperationProperties{
+		Summary: "Creates several Stellar accounts",
+		Description: "Generates a number of Stellar accounts sharing their name, policy, allowed sources, owner group " +
+			"and limits, optionally adds them to a role, and returns their public keys. With a funding account, it also returns the transactions " +
+			"creating the accounts on-ledger with a starting balance or with sponsored reserves, ready to be signed " +
+			"by the funding account.",
+		// Funding transactions allocate sequence numbers under a lock held by the active node only
+		ForwardPerformanceStandby:   true,
+		ForwardPerformanceSecondary: true,
+		Examples: []framework.RequestExample{
+			{
+				Description: "Create 500 deposit accounts with sponsored reserves",
+				Data: map[string]interface{}{
+					"count":           500,
+					"name":            "deposit",
+					"policy":          "deposits",
+					"role":            "deposits",
+					"funding_account": "GATBMIXGZKJGSEVJQH7D2ZP3A4UQ4WKB3X5H3C6KHPGJRH4B3U5UJ6CH",
+					"network":         "Testnet",
+					"sponsored":       true,
+				},
+				Response: &framework.Response{
+					Description: "Successful creation of the Stellar accounts",
+					MediaType:   "application/json",
+					Example: &logical.Response{
+						Data: map[string]interface{}{
+							"public_keys": []string{"GASYNOBIVOZZJGBH6C5K2FPJQ2RPN2QBJ7OO6OXENIG5S2IKRALFTKIA", "..."},
+							"role":        "deposits",
+							"transactions": []map[string]interface{}{
+								{
+									"transaction":      "base64EncodedTransactionEnvelope",
+									"transaction_hash": "3389e9f0f1a65f19736cacf544c2e825313e8447f569233bb8db39aa607c8889",
+									"sequence":         "4294967297",
+									"public_keys":      []string{"GASYNOBIVOZZJGBH6C5K2FPJQ2RPN2QBJ7OO6OXENIG5S2IKRALFTKIA", "..."},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"vault-plugin-stellar-sign/internal/backend/stellar"
+)
+
+type BuildChannelTxHandler struct {
+	manager *stellar.Manager
+}
+
+func NewBuildChannelTxHandler(m *stellar.Manager) *BuildChannelTxHandler {
+	return &BuildChannelTxHandler{manager: m}
+}
+
+func (h *BuildChannelTxHandler) Handler() framework.OperationFunc {
+	return instrument("build_channel_tx", h.manager.BuildChannelTx)
+}
+
+func (h *BuildChannelTxHandler) Properties() framework.OperationProperties {
+	return framework.OperationProperties{
+		Summary: "Builds and signs a Stellar transaction on a channel account",
+		Description: "This operation builds a transaction with a leased channel as source and the main account of the " +
+			"pool as source of every operation, and signs it with both. The channel is released once the " +
+			"submission outcome is known.",
+		// Sequence numbers are allocated under a lock held by the active node only
+		ForwardPerformanceStandby:   true,
+		ForwardPerformanceSecondary: true,
+		Examples: []framework.RequestExample{
+			{
+				Description: "Build, sign and submit a payment on a leased channel",
+				Data: map[string]interface{}{
+					"name":     "payouts",
+					"channel":  "GATBMIXGZKJGSEVJQH7D2ZP3A4UQ4WKB3X5H3C6KHPGJRH4B3U5UJ6CH",
+					"lease_id": "8c7d3e1a-5b0f-4c2e-9a61-2f4b7d9e0c13",
+					"operations": []map[string]interface{}{
+						{
+							"type":        "payment",
+							"destination": "GAJ2GM7LGQ2FHKFTWZMUPZMQLMNSRNEQG67X6VSYKGTP5TBTG6HXNLM2",
+							"asset":       "native",
+							"amount":      "10",
+						},
+					},
+					"submit": true,
+				},
+				Response: &framework.Response{
+					Description: "Successful building, signing and submission of the transaction",
+					MediaType:   "application/json",
+					Example: &logical.Response{
+						Data: map[string]interface{}{
+							"signed_transaction": "base64EncodedSignedTransactionEnvelope",
+							"transaction_hash":   "3389e9f0f1a65f19736cacf544c2e825313e8447f569233bb8db39aa607c8889",
+							"sequence":           "4294967297",
+							"channel":            "GATBMIXGZKJGSEVJQH7D2ZP3A4UQ4WKB3X5H3C6KHPGJRH4B3U5UJ6CH",
+							"submission_status":  "success",
+							"ledger":             1234567,
+						},
+					},
+				},
+			},
+		},
+	}
+}
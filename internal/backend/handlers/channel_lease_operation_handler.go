@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"vault-plugin-stellar-sign/internal/backend/stellar"
+)
+
+type LeaseChannelHandler struct {
+	manager *stellar.Manager
+}
+
+func NewLeaseChannelHandler(m *stellar.Manager) *LeaseChannelHandler {
+	return &LeaseChannelHandler{manager: m}
+}
+
+func (h *LeaseChannelHandler) Handler() framework.OperationFunc {
+	return instrument("lease_channel", h.manager.LeaseChannel)
+}
+
+func (h *LeaseChannelHandler) Properties() framework.OperationProperties {
+	return framework.OperationProperties{
+		Summary:     "Leases a channel account",
+		Description: "Reserves a free channel of the pool for the duration of a Vault lease. Revoking the lease releases the channel.",
+		// Leases are handed out under a lock held by the active node only
+		ForwardPerformanceStandby:   true,
+		ForwardPerformanceSecondary: true,
+		Examples: []framework.RequestExample{
+			{
+				Description: "Lease a channel of a pool",
+				Data: map[string]interface{}{
+					"name": "payouts",
+				},
+				Response: &framework.Response{
+					Description: "Successful lease of a channel",
+					MediaType:   "application/json",
+					Example: &logical.Response{
+						Data: map[string]interface{}{
+							"pool":     "payouts",
+							"channel":  "GATBMIXGZKJGSEVJQH7D2ZP3A4UQ4WKB3X5H3C6KHPGJRH4B3U5UJ6CH",
+							"network":  "Testnet",
+							"lease_id": "8c7d3e1a-5b0f-4c2e-9a61-2f4b7d9e0c13",
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"github.com/hashicorp/vault/sdk/framework"
+	"vault-plugin-stellar-sign/internal/backend/stellar"
+)
+
+type DeleteChannelPoolHandler struct {
+	manager *stellar.Manager
+}
+
+func NewDeleteChannelPoolHandler(m *stellar.Manager) *DeleteChannelPoolHandler {
+	return &DeleteChannelPoolHandler{manager: m}
+}
+
+func (h *DeleteChannelPoolHandler) Handler() framework.OperationFunc {
+	return instrument("delete_channel_pool", h.manager.DeleteChannelPool)
+}
+
+func (h *DeleteChannelPoolHandler) Properties() framework.OperationProperties {
+	return framework.OperationProperties{
+		Summary:     "Deletes a channel pool",
+		Description: "Removes a channel pool without active leases. The channel accounts stay stored.",
+		Examples: []framework.RequestExample{
+			{
+				Description: "Delete a channel pool",
+				Data: map[string]interface{}{
+					"name": "payouts",
+				},
+				Response: &framework.Response{
+					Description: "Successful deletion of the channel pool",
+					MediaType:   "application/json",
+				},
+			},
+		},
+	}
+}
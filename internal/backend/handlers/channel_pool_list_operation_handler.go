@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"vault-plugin-stellar-sign/internal/backend/stellar"
+)
+
+type ListChannelPoolsHandler struct {
+	manager *stellar.Manager
+}
+
+func NewListChannelPoolsHandler(m *stellar.Manager) *ListChannelPoolsHandler {
+	return &ListChannelPoolsHandler{manager: m}
+}
+
+func (h *ListChannelPoolsHandler) Handler() framework.OperationFunc {
+	return instrument("list_channel_pools", h.manager.ListChannelPools)
+}
+
+func (h *ListChannelPoolsHandler) Properties() framework.OperationProperties {
+	return framework.OperationProperties{
+		Summary:     "Lists the channel pools",
+		Description: "Retrieves the names of all channel pools.",
+		Examples: []framework.RequestExample{
+			{
+				Description: "List all channel pools",
+				Response: &framework.Response{
+					Description: "Successful retrieval of the channel pools",
+					MediaType:   "application/json",
+					Example: &logical.Response{
+						Data: map[string]interface{}{
+							"keys": []string{"payouts"},
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"vault-plugin-stellar-sign/internal/backend/stellar"
+)
+
+type ReadChannelPoolHandler struct {
+	manager *stellar.Manager
+}
+
+func NewReadChannelPoolHandler(m *stellar.Manager) *ReadChannelPoolHandler {
+	return &ReadChannelPoolHandler{manager: m}
+}
+
+func (h *ReadChannelPoolHandler) Handler() framework.OperationFunc {
+	return instrument("read_channel_pool", h.manager.ReadChannelPool)
+}
+
+func (h *ReadChannelPoolHandler) Properties() framework.OperationProperties {
+	return framework.OperationProperties{
+		Summary:     "Reads a channel pool",
+		Description: "Retrieves the main account, network and channels of a pool, and which channels are leased.",
+		Examples: []framework.RequestExample{
+			{
+				Description: "Read a channel pool",
+				Data: map[string]interface{}{
+					"name": "payouts",
+				},
+				Response: &framework.Response{
+					Description: "Successful retrieval of the channel pool",
+					MediaType:   "application/json",
+					Example: &logical.Response{
+						Data: map[string]interface{}{
+							"name":      "payouts",
+							"account":   "GASYNOBIVOZZJGBH6C5K2FPJQ2RPN2QBJ7OO6OXENIG5S2IKRALFTKIA",
+							"network":   "Testnet",
+							"channels":  []string{"GATBMIXGZKJGSEVJQH7D2ZP3A4UQ4WKB3X5H3C6KHPGJRH4B3U5UJ6CH"},
+							"lease_ttl": 60,
+							"leased":    []string{},
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"github.com/hashicorp/vault/sdk/framework"
+	"vault-plugin-stellar-sign/internal/backend/stellar"
+)
+
+type WriteChannelPoolHandler struct {
+	manager *stellar.Manager
+}
+
+func NewWriteChannelPoolHandler(m *stellar.Manager) *WriteChannelPoolHandler {
+	return &WriteChannelPoolHandler{manager: m}
+}
+
+func (h *WriteChannelPoolHandler) Handler() framework.OperationFunc {
+	return instrument("write_channel_pool", h.manager.WriteChannelPool)
+}
+
+func (h *WriteChannelPoolHandler) Properties() framework.OperationProperties {
+	return framework.OperationProperties{
+		Summary: "Creates or updates a channel pool",
+		Description: "Creates a pool of new channel accounts for a main account, optionally creating them on-ledger " +
+			"funded by the main account. Only the lease TTL of an existing pool can be changed.",
+		Examples: []framework.RequestExample{
+			{
+				Description: "Create a funded pool of five channels",
+				Data: map[string]interface{}{
+					"name":    "payouts",
+					"account": "GASYNOBIVOZZJGBH6C5K2FPJQ2RPN2QBJ7OO6OXENIG5S2IKRALFTKIA",
+					"network": "Testnet",
+					"size":    5,
+					"fund":    true,
+				},
+				Response: &framework.Response{
+					Description: "Successful creation of the channel pool",
+					MediaType:   "application/json",
+				},
+			},
+		},
+	}
+}
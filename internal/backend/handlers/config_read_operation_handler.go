@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"vault-plugin-stellar-sign/internal/backend/stellar"
+)
+
+type ReadConfigHandler struct {
+	manager *stellar.Manager
+}
+
+func NewReadConfigHandler(m *stellar.Manager) *ReadConfigHandler {
+	return &ReadConfigHandler{manager: m}
+}
+
+func (h *ReadConfigHandler) Handler() framework.OperationFunc {
+	return instrument("read_config", h.manager.ReadConfig)
+}
+
+func (h *ReadConfigHandler) Properties() framework.OperationProperties {
+	return framework.OperationProperties{
+		Summary:     "Reads the plugin configuration",
+		Description: "Retrieves the mount-wide settings of the plugin.",
+		Examples: []framework.RequestExample{
+			{
+				Description: "Read the plugin configuration",
+				Response: &framework.Response{
+					Description: "Successful retrieval of the plugin configuration",
+					MediaType:   "application/json",
+					Example: &logical.Response{
+						Data: map[string]interface{}{
+							"policy": "mount-guardrails",
+						},
+					},
+				},
+			},
+		},
+	}
+}
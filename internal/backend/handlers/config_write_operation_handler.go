@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"github.com/hashicorp/vault/sdk/framework"
+	"vault-plugin-stellar-sign/internal/backend/stellar"
+)
+
+type WriteConfigHandler struct {
+	manager *stellar.Manager
+}
+
+func NewWriteConfigHandler(m *stellar.Manager) *WriteConfigHandler {
+	return &WriteConfigHandler{manager: m}
+}
+
+func (h *WriteConfigHandler) Handler() framework.OperationFunc {
+	return instrument("write_config", h.manager.WriteConfig)
+}
+
+func (h *WriteConfigHandler) Properties() framework.OperationProperties {
+	return framework.OperationProperties{
+		Summary:     "Updates the plugin configuration",
+		Description: "Updates the mount-wide settings of the plugin. Fields that are not provided keep their current value.",
+		Examples: []framework.RequestExample{
+			{
+				Description: "Enforce a signing policy for every account of the mount",
+				Data: map[string]interface{}{
+					"policy": "mount-guardrails",
+				},
+				Response: &framework.Response{
+					Description: "Successful update of the plugin configuration",
+					MediaType:   "application/json",
+				},
+			},
+		},
+	}
+}
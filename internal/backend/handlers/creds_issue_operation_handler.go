@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"vault-plugin-stellar-sign/internal/backend/stellar"
+)
+
+type IssueCredsHandler struct {
+	manager *stellar.Manager
+}
+
+func NewIssueCredsHandler(m *stellar.Manager) *IssueCredsHandler {
+	return &IssueCredsHandler{manager: m}
+}
+
+func (h *IssueCredsHandler) Handler() framework.OperationFunc {
+	return instrument("issue_creds", h.manager.IssueCreds)
+}
+
+func (h *IssueCredsHandler) Properties() framework.OperationProperties {
+	return framework.OperationProperties{
+		Summary: "Issues an ephemeral Stellar account",
+		Description: "Generates a keypair leased for the TTL of the role, created on-ledger by the funding account " +
+			"or added as a signer of the parent account when the role says so.",
+		// Issuing writes to storage and submits transactions, which only the active node does
+		ForwardPerformanceStandby:   true,
+		ForwardPerformanceSecondary: true,
+		Examples: []framework.RequestExample{
+			{
+				Description: "Issue an ephemeral account",
+				Data: map[string]interface{}{
+					"name": "ci",
+				},
+				Response: &framework.Response{
+					Description: "Successful issuance of the ephemeral account",
+					MediaType:   "application/json",
+					Example: &logical.Response{
+						Data: map[string]interface{}{
+							"public_key": "GATBMIXGZKJGSEVJQH7D2ZP3A4UQ4WKB3X5H3C6KHPGJRH4B3U5UJ6CH",
+							"secret_key": "SBK2VIYYSVG76E7VC3QHYARNFLY2EAQXDHRC7BMXBBGIFG74ARPRMNQM",
+							"role":       "ci",
+							"network":    "Testnet",
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -14,7 +14,7 @@ func NewDeleteAccountHandler(m *stellar.Manager) *DeleteAccountHandler {
 }
 
 func (h *DeleteAccountHandler) Handler() framework.OperationFunc {
-	return h.manager.DeleteAccount
+	return instrument("delete_account", h.manager.DeleteAccount)
 }
 
 func (h *DeleteAccountHandler) Properties() framework.OperationProperties {
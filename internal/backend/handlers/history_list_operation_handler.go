@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"vault-plugin-stellar-sign/internal/backend/stellar"
+)
+
+type ListHistoryHandler struct {
+	manager *stellar.Manager
+}
+
+func NewListHistoryHandler(m *stellar.Manager) *ListHistoryHandler {
+	return &ListHistoryHandler{manager: m}
+}
+
+func (h *ListHistoryHandler) Handler() framework.OperationFunc {
+	return instrument("list_history", h.manager.ListHistory)
+}
+
+func (h *ListHistoryHandler) Properties() framework.OperationProperties {
+	return framework.OperationProperties{
+		Summary:     "Lists the signing history of a Stellar account",
+		Description: "Retrieves the hashes of the transactions signed by an account, with details about each of them.",
+		Examples: []framework.RequestExample{
+			{
+				Description: "List the transactions signed by an account",
+				Data: map[string]interface{}{
+					"publicKey": "GASYNOBIVOZZJGBH6C5K2FPJQ2RPN2QBJ7OO6OXENIG5S2IKRALFTKIA",
+				},
+				Response: &framework.Response{
+					Description: "Successful retrieval of the signing history",
+					MediaType:   "application/json",
+					Example: &logical.Response{
+						Data: map[string]interface{}{
+							"keys": []string{"3389e9f0f1a65f19736cacf544c2e825313e8447f569233bb8db39aa607c8889"},
+							"key_info": map[string]interface{}{
+								"3389e9f0f1a65f19736cacf544c2e825313e8447f569233bb8db39aa607c8889": map[string]interface{}{
+									"network": "Testnet",
+									"mux_id":  42,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
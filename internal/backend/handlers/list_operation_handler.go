@@ -15,13 +15,13 @@ func NewListAccountsHandler(m *stellar.Manager) *ListAccountsHandler {
 }
 
 func (h *ListAccountsHandler) Handler() framework.OperationFunc {
-	return h.manager.ListAccounts
+	return instrument("list_accounts", h.manager.ListAccounts)
 }
 
 func (h *ListAccountsHandler) Properties() framework.OperationProperties {
 	return framework.OperationProperties{
 		Summary:     "Lists Stellar accounts",
-		Description: "Retrieves a list of all Stellar accounts stored in the backend.",
+		Description: "Retrieves the Stellar accounts stored in the backend, optionally a page of them filtered by prefix and sorted by public key, name or creation time.",
 		Examples: []framework.RequestExample{
 			{
 				Description: "List all Stellar accounts",
@@ -41,6 +41,46 @@ func (h *ListAccountsHandler) Properties() framework.OperationProperties {
 					},
 				},
 			},
+			{
+				Description: "List the Stellar accounts with their metadata and usage",
+				Data: map[string]interface{}{
+					"detailed": true,
+				},
+				Response: &framework.Response{
+					Description: "Successful retrieval of the detailed Stellar account list",
+					MediaType:   "application/json",
+					Example: &logical.Response{
+						Data: map[string]interface{}{
+							"keys": []string{"GASYNOBIVOZZJGBH6C5K2FPJQ2RPN2QBJ7OO6OXENIG5S2IKRALFTKIA"},
+							"key_info": map[string]interface{}{
+								"GASYNOBIVOZZJGBH6C5K2FPJQ2RPN2QBJ7OO6OXENIG5S2IKRALFTKIA": map[string]interface{}{
+									"signature_count": 12,
+									"last_signed_at":  "2024-03-02T17:45:00Z",
+									"max_signatures":  1000,
+								},
+							},
+						},
+					},
+				},
+			},
+			{
+				Description: "List the 100 oldest Stellar accounts named after a prefix, following the last account of the previous page",
+				Data: map[string]interface{}{
+					"prefix": "deposit-",
+					"sort":   "created_at",
+					"limit":  100,
+					"after":  "GASYNOBIVOZZJGBH6C5K2FPJQ2RPN2QBJ7OO6OXENIG5S2IKRALFTKIA",
+				},
+				Response: &framework.Response{
+					Description: "Successful retrieval of a page of the Stellar account list",
+					MediaType:   "application/json",
+					Example: &logical.Response{
+						Data: map[string]interface{}{
+							"keys": []string{"GBRPYHIL2CI3FNQ4BXLFMNDLFJUNPU2HY3ZMFSHONUCEOASW7QC7OX2H"},
+						},
+					},
+				},
+			},
 		},
 	}
 }
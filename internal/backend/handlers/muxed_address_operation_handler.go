@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"vault-plugin-stellar-sign/internal/backend/stellar"
+)
+
+type MuxedAddressHandler struct {
+	manager *stellar.Manager
+}
+
+func NewMuxedAddressHandler(m *stellar.Manager) *MuxedAddressHandler {
+	return &MuxedAddressHandler{manager: m}
+}
+
+func (h *MuxedAddressHandler) Handler() framework.OperationFunc {
+	return instrument("muxed_address", h.manager.MuxedAddress)
+}
+
+func (h *MuxedAddressHandler) Properties() framework.OperationProperties {
+	return framework.OperationProperties{
+		Summary:     "Generates a muxed account address",
+		Description: "Returns the M-address of a stored Stellar account for the given mux ID, along with the policy enforced for it.",
+		Examples: []framework.RequestExample{
+			{
+				Description: "Generate the muxed address of a customer sub-account",
+				Data: map[string]interface{}{
+					"publicKey": "GASYNOBIVOZZJGBH6C5K2FPJQ2RPN2QBJ7OO6OXENIG5S2IKRALFTKIA",
+					"id":        "42",
+				},
+				Response: &framework.Response{
+					Description: "Successful generation of the muxed address",
+					MediaType:   "application/json",
+					Fields: map[string]*framework.FieldSchema{
+						"muxed_address": {
+							Type:        framework.TypeString,
+							Description: "The M-address of the account for the mux ID",
+						},
+					},
+					Example: &logical.Response{
+						Data: map[string]interface{}{
+							"public_key":    "GASYNOBIVOZZJGBH6C5K2FPJQ2RPN2QBJ7OO6OXENIG5S2IKRALFTKIA",
+							"mux_id":        42,
+							"muxed_address": "MASYNOBIVOZZJGBH6C5K2FPJQ2RPN2QBJ7OO6OXENIG5S2IKRALFTKIAAAAAAAAAAAAFKBD6Q",
+						},
+					},
+				},
+			},
+		},
+	}
+}
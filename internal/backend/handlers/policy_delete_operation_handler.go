@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"github.com/hashicorp/vault/sdk/framework"
+	"vault-plugin-stellar-sign/internal/backend/stellar"
+)
+
+type DeletePolicyHandler struct {
+	manager *stellar.Manager
+}
+
+func NewDeletePolicyHandler(m *stellar.Manager) *DeletePolicyHandler {
+	return &DeletePolicyHandler{manager: m}
+}
+
+func (h *DeletePolicyHandler) Handler() framework.OperationFunc {
+	return instrument("delete_policy", h.manager.DeletePolicy)
+}
+
+func (h *DeletePolicyHandler) Properties() framework.OperationProperties {
+	return framework.OperationProperties{
+		Summary:     "Deletes a signing policy",
+		Description: "Removes a signing policy from storage. Accounts that still reference it can no longer sign.",
+		Examples: []framework.RequestExample{
+			{
+				Description: "Delete a signing policy",
+				Data: map[string]interface{}{
+					"name": "soroban-dex",
+				},
+				Response: &framework.Response{
+					Description: "Successful deletion of the signing policy",
+					MediaType:   "application/json",
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"vault-plugin-stellar-sign/internal/backend/stellar"
+)
+
+type ListPoliciesHandler struct {
+	manager *stellar.Manager
+}
+
+func NewListPoliciesHandler(m *stellar.Manager) *ListPoliciesHandler {
+	return &ListPoliciesHandler{manager: m}
+}
+
+func (h *ListPoliciesHandler) Handler() framework.OperationFunc {
+	return instrument("list_policies", h.manager.ListPolicies)
+}
+
+func (h *ListPoliciesHandler) Properties() framework.OperationProperties {
+	return framework.OperationProperties{
+		Summary:     "Lists signing policies",
+		Description: "Retrieves the names of all signing policies stored in the backend.",
+		Examples: []framework.RequestExample{
+			{
+				Description: "List all signing policies",
+				Response: &framework.Response{
+					Description: "Successful retrieval of the signing policy list",
+					MediaType:   "application/json",
+					Example: &logical.Response{
+						Data: map[string]interface{}{
+							"keys": []string{"soroban-dex", "payments"},
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"vault-plugin-stellar-sign/internal/backend/stellar"
+)
+
+type ReadPolicyHandler struct {
+	manager *stellar.Manager
+}
+
+func NewReadPolicyHandler(m *stellar.Manager) *ReadPolicyHandler {
+	return &ReadPolicyHandler{manager: m}
+}
+
+func (h *ReadPolicyHandler) Handler() framework.OperationFunc {
+	return instrument("read_policy", h.manager.ReadPolicy)
+}
+
+func (h *ReadPolicyHandler) Properties() framework.OperationProperties {
+	return framework.OperationProperties{
+		Summary:     "Reads a signing policy",
+		Description: "Retrieves the rules of a signing policy by name.",
+		Examples: []framework.RequestExample{
+			{
+				Description: "Read a signing policy",
+				Data: map[string]interface{}{
+					"name": "soroban-dex",
+				},
+				Response: &framework.Response{
+					Description: "Successful retrieval of the signing policy",
+					MediaType:   "application/json",
+					Example: &logical.Response{
+						Data: map[string]interface{}{
+							"name":                            "soroban-dex",
+							"soroban_max_resource_fee":        1000000,
+							"soroban_allow_wasm_upload":       false,
+							"soroban_allow_contract_creation": false,
+						},
+					},
+				},
+			},
+		},
+	}
+}
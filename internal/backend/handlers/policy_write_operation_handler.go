@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"github.com/hashicorp/vault/sdk/framework"
+	"vault-plugin-stellar-sign/internal/backend/stellar"
+)
+
+type WritePolicyHandler struct {
+	manager *stellar.Manager
+}
+
+func NewWritePolicyHandler(m *stellar.Manager) *WritePolicyHandler {
+	return &WritePolicyHandler{manager: m}
+}
+
+func (h *WritePolicyHandler) Handler() framework.OperationFunc {
+	return instrument("write_policy", h.manager.WritePolicy)
+}
+
+func (h *WritePolicyHandler) Properties() framework.OperationProperties {
+	return framework.OperationProperties{
+		Summary:     "Creates or replaces a signing policy",
+		Description: "Stores a named signing policy. Writing an existing policy replaces all of its rules.",
+		Examples: []framework.RequestExample{
+			{
+				Description: "Allow only transfers on a single Soroban contract",
+				Data: map[string]interface{}{
+					"name": "soroban-dex",
+					"soroban_contracts": []map[string]interface{}{
+						{
+							"contract":  "CDLZFC3SYJYDZT7K67VZ75HPJVIEUVNIXF47ZG2FB2RMQQVU2HHGCYSC",
+							"functions": []string{"transfer"},
+						},
+					},
+					"soroban_max_resource_fee": 1000000,
+				},
+				Response: &framework.Response{
+					Description: "Successful creation of the signing policy",
+					MediaType:   "application/json",
+				},
+			},
+		},
+	}
+}
@@ -15,7 +15,7 @@ func NewReadAccountHandler(m *stellar.Manager) *ReadAccountHandler {
 }
 
 func (h *ReadAccountHandler) Handler() framework.OperationFunc {
-	return h.manager.ReadAccount
+	return instrument("read_account", h.manager.ReadAccount)
 }
 
 func (h *ReadAccountHandler) Properties() framework.OperationProperties {
@@ -44,6 +44,27 @@ func (h *ReadAccountHandler) Properties() framework.OperationProperties {
 					},
 				},
 			},
+			{
+				Description: "Read a Stellar account with its on-ledger state",
+				Data: map[string]interface{}{
+					"publicKey": "publicKeyToRead",
+					"network":   "Testnet",
+				},
+				Response: &framework.Response{
+					Description: "Successful retrieval of the Stellar account and its state from Horizon",
+					MediaType:   "application/json",
+					Example: &logical.Response{
+						Data: map[string]interface{}{
+							"public_key": "ExamplePublicKey",
+							"network":    "Testnet",
+							"exists":     true,
+							"sequence":   "103720918407102567",
+							"balances":   []map[string]interface{}{{"balance": "100.0000000", "asset_type": "native"}},
+							"signer_for": []string{"ExamplePublicKey"},
+						},
+					},
+				},
+			},
 		},
 	}
 }
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"github.com/hashicorp/vault/sdk/framework"
+	"vault-plugin-stellar-sign/internal/backend/stellar"
+)
+
+type DeleteRoleHandler struct {
+	manager *stellar.Manager
+}
+
+func NewDeleteRoleHandler(m *stellar.Manager) *DeleteRoleHandler {
+	return &DeleteRoleHandler{manager: m}
+}
+
+func (h *DeleteRoleHandler) Handler() framework.OperationFunc {
+	return instrument("delete_role", h.manager.DeleteRole)
+}
+
+func (h *DeleteRoleHandler) Properties() framework.OperationProperties {
+	return framework.OperationProperties{
+		Summary:     "Deletes a role",
+		Description: "Removes a role from storage. Accounts already issued for it are still revoked when their lease ends.",
+		Examples: []framework.RequestExample{
+			{
+				Description: "Delete a role",
+				Data: map[string]interface{}{
+					"name": "ci",
+				},
+				Response: &framework.Response{
+					Description: "Successful deletion of the role",
+					MediaType:   "application/json",
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"vault-plugin-stellar-sign/internal/backend/stellar"
+)
+
+type ListRolesHandler struct {
+	manager *stellar.Manager
+}
+
+func NewListRolesHandler(m *stellar.Manager) *ListRolesHandler {
+	return &ListRolesHandler{manager: m}
+}
+
+func (h *ListRolesHandler) Handler() framework.OperationFunc {
+	return instrument("list_roles", h.manager.ListRoles)
+}
+
+func (h *ListRolesHandler) Properties() framework.OperationProperties {
+	return framework.OperationProperties{
+		Summary:     "Lists the roles",
+		Description: "Retrieves the names of all roles.",
+		Examples: []framework.RequestExample{
+			{
+				Description: "List all roles",
+				Response: &framework.Response{
+					Description: "Successful retrieval of the roles",
+					MediaType:   "application/json",
+					Example: &logical.Response{
+						Data: map[string]interface{}{
+							"keys": []string{"ci"},
+						},
+					},
+				},
+			},
+		},
+	}
+}
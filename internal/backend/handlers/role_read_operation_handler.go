@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"vault-plugin-stellar-sign/internal/backend/stellar"
+)
+
+type ReadRoleHandler struct {
+	manager *stellar.Manager
+}
+
+func NewReadRoleHandler(m *stellar.Manager) *ReadRoleHandler {
+	return &ReadRoleHandler{manager: m}
+}
+
+func (h *ReadRoleHandler) Handler() framework.OperationFunc {
+	return instrument("read_role", h.manager.ReadRole)
+}
+
+func (h *ReadRoleHandler) Properties() framework.OperationProperties {
+	return framework.OperationProperties{
+		Summary:     "Reads a role",
+		Description: "Retrieves the settings of a role.",
+		Examples: []framework.RequestExample{
+			{
+				Description: "Read a role",
+				Data: map[string]interface{}{
+					"name": "ci",
+				},
+				Response: &framework.Response{
+					Description: "Successful retrieval of the role",
+					MediaType:   "application/json",
+					Example: &logical.Response{
+						Data: map[string]interface{}{
+							"name":             "ci",
+							"network":          "Testnet",
+							"funding_account":  "GASYNOBIVOZZJGBH6C5K2FPJQ2RPN2QBJ7OO6OXENIG5S2IKRALFTKIA",
+							"starting_balance": "2",
+							"ttl":              900,
+							"max_ttl":          3600,
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"vault-plugin-stellar-sign/internal/backend/stellar"
+)
+
+type SignWithRoleHandler struct {
+	manager *stellar.Manager
+}
+
+func NewSignWithRoleHandler(m *stellar.Manager) *SignWithRoleHandler {
+	return &SignWithRoleHandler{manager: m}
+}
+
+func (h *SignWithRoleHandler) Handler() framework.OperationFunc {
+	return instrument("sign_with_role", h.manager.SignWithRole)
+}
+
+func (h *SignWithRoleHandler) Properties() framework.OperationProperties {
+	return framework.OperationProperties{
+		Summary: "Signs a Stellar transaction envelope through a role",
+		Description: "This operation signs a provided Stellar transaction envelope with one of the accounts of the role. " +
+			"The network must be allowed by the role and the role's signing policy applies in addition to the " +
+			"policies of the account, so Vault ACL can be granted per role.",
+		Examples: []framework.RequestExample{
+			{
+				Description: "Sign a transaction through a role",
+				Data: map[string]interface{}{
+					"name":        "payroll",
+					"publicKey":   "GATBMIXGZKJGSEVJQH7D2ZP3A4UQ4WKB3X5H3C6KHPGJRH4B3U5UJ6CH",
+					"transaction": "base64EncodedTransactionEnvelope",
+					"network":     "Public",
+				},
+				Response: &framework.Response{
+					Description: "Successful signing of the Stellar transaction",
+					MediaType:   "application/json",
+					Example: &logical.Response{
+						Data: map[string]interface{}{
+							"signed_transaction": "base64EncodedSignedTransactionEnvelope",
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"github.com/hashicorp/vault/sdk/framework"
+	"vault-plugin-stellar-sign/internal/backend/stellar"
+)
+
+type WriteRoleHandler struct {
+	manager *stellar.Manager
+}
+
+func NewWriteRoleHandler(m *stellar.Manager) *WriteRoleHandler {
+	return &WriteRoleHandler{manager: m}
+}
+
+func (h *WriteRoleHandler) Handler() framework.OperationFunc {
+	return instrument("write_role", h.manager.WriteRole)
+}
+
+func (h *WriteRoleHandler) Properties() framework.OperationProperties {
+	return framework.OperationProperties{
+		Summary:     "Creates or replaces a role",
+		Description: "Stores a role. Fields that are not provided are reset to their default.",
+		Examples: []framework.RequestExample{
+			{
+				Description: "Grant signing payroll transactions on the public network with a shared account",
+				Data: map[string]interface{}{
+					"name":     "payroll",
+					"accounts": "GASYNOBIVOZZJGBH6C5K2FPJQ2RPN2QBJ7OO6OXENIG5S2IKRALFTKIA",
+					"networks": "Public",
+					"policy":   "payroll-limits",
+				},
+				Response: &framework.Response{
+					Description: "Successful storage of the role",
+					MediaType:   "application/json",
+				},
+			},
+			{
+				Description: "Issue funded ephemeral accounts for CI jobs",
+				Data: map[string]interface{}{
+					"name":            "ci",
+					"network":         "Testnet",
+					"funding_account": "GASYNOBIVOZZJGBH6C5K2FPJQ2RPN2QBJ7OO6OXENIG5S2IKRALFTKIA",
+					"ttl":             "15m",
+					"max_ttl":         "1h",
+				},
+				Response: &framework.Response{
+					Description: "Successful storage of the role",
+					MediaType:   "application/json",
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"vault-plugin-stellar-sign/internal/backend/stellar"
+)
+
+type ReadSequenceHandler struct {
+	manager *stellar.Manager
+}
+
+func NewReadSequenceHandler(m *stellar.Manager) *ReadSequenceHandler {
+	return &ReadSequenceHandler{manager: m}
+}
+
+func (h *ReadSequenceHandler) Handler() framework.OperationFunc {
+	return instrument("read_sequence", h.manager.ReadSequence)
+}
+
+func (h *ReadSequenceHandler) Properties() framework.OperationProperties {
+	return framework.OperationProperties{
+		Summary:     "Reads the tracked sequence number of a Stellar account",
+		Description: "Retrieves the next sequence number the build endpoint allocates for a source account on a network.",
+		Examples: []framework.RequestExample{
+			{
+				Description: "Read the tracked sequence number of an account",
+				Data: map[string]interface{}{
+					"publicKey": "GASYNOBIVOZZJGBH6C5K2FPJQ2RPN2QBJ7OO6OXENIG5S2IKRALFTKIA",
+					"network":   "Testnet",
+				},
+				Response: &framework.Response{
+					Description: "Successful retrieval of the sequence number",
+					MediaType:   "application/json",
+					Example: &logical.Response{
+						Data: map[string]interface{}{
+							"public_key":    "GASYNOBIVOZZJGBH6C5K2FPJQ2RPN2QBJ7OO6OXENIG5S2IKRALFTKIA",
+							"network":       "Testnet",
+							"tracked":       true,
+							"next_sequence": "4294967301",
+							"allocated":     4,
+							"synced_at":     "2024-05-01T12:00:00Z",
+						},
+					},
+				},
+			},
+		},
+	}
+}
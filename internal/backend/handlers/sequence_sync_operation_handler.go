@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"github.com/hashicorp/vault/sdk/framework"
+	"vault-plugin-stellar-sign/internal/backend/stellar"
+)
+
+type SyncSequenceHandler struct {
+	manager *stellar.Manager
+}
+
+func NewSyncSequenceHandler(m *stellar.Manager) *SyncSequenceHandler {
+	return &SyncSequenceHandler{manager: m}
+}
+
+func (h *SyncSequenceHandler) Handler() framework.OperationFunc {
+	return instrument("sync_sequence", h.manager.SyncSequence)
+}
+
+func (h *SyncSequenceHandler) Properties() framework.OperationProperties {
+	return framework.OperationProperties{
+		Summary:     "Resynchronises the tracked sequence number of a Stellar account",
+		Description: "Fetches the current sequence number of the account from Horizon, or sets the next one explicitly.",
+		// Sequence numbers are allocated under a lock held by the active node only
+		ForwardPerformanceStandby:   true,
+		ForwardPerformanceSecondary: true,
+		Examples: []framework.RequestExample{
+			{
+				Description: "Resynchronise the sequence number from Horizon",
+				Data: map[string]interface{}{
+					"publicKey": "GASYNOBIVOZZJGBH6C5K2FPJQ2RPN2QBJ7OO6OXENIG5S2IKRALFTKIA",
+					"network":   "Testnet",
+				},
+				Response: &framework.Response{
+					Description: "Successful resynchronisation of the sequence number",
+					MediaType:   "application/json",
+				},
+			},
+		},
+	}
+}
@@ -15,7 +15,7 @@ func NewSignTxHandler(m *stellar.Manager) *SignTxHandler {
 }
 
 func (h *SignTxHandler) Handler() framework.OperationFunc {
-	return h.manager.SignTx
+	return instrument("sign_tx", h.manager.SignTx)
 }
 
 func (h *SignTxHandler) Properties() framework.OperationProperties {
@@ -23,7 +23,8 @@ func (h *SignTxHandler) Properties() framework.OperationProperties {
 		Summary: "Signs a Stellar transaction envelope",
 		Description: "This operation signs a provided Stellar transaction envelope using the secret key " +
 			"of the specified account. The transaction is specified in a base64-encoded format, " +
-			"and the account is identified by its public key.",
+			"and the account is identified by its public key. When the account or the approval tiers of its " +
+			"policies require approval, the transaction is stored as a pending approval request instead.",
 		Examples: []framework.RequestExample{
 			{
 				Description: "Sign a transaction with a specific account",
@@ -40,10 +41,75 @@ func (h *SignTxHandler) Properties() framework.OperationProperties {
 							Type:        framework.TypeString,
 							Description: "The base64 encoded signed Stellar transaction envelope",
 						},
+						"transaction_hash": {
+							Type:        framework.TypeString,
+							Description: "The hex encoded hash of the transaction",
+						},
+						"source": {
+							Type:        framework.TypeString,
+							Description: "The source account of the transaction",
+						},
+						"sequence": {
+							Type:        framework.TypeString,
+							Description: "The sequence number of the transaction",
+						},
+						"operation_count": {
+							Type:        framework.TypeInt,
+							Description: "The number of operations of the transaction",
+						},
 					},
 					Example: &logical.Response{
 						Data: map[string]interface{}{
 							"signed_transaction": "base64EncodedSignedTransactionEnvelope",
+							"transaction_hash":   "3389e9f0f1a65f19736cacf544c2e825313e8447f569233bb8db39aa607c8889",
+							"source":             "GAJ2GM7LGQ2FHKFTWZMUPZMQLMNSRNEQG67X6VSYKGTP5TBTG6HXNLM2",
+							"sequence":           "212781269778433",
+							"operation_count":    1,
+						},
+					},
+				},
+			},
+			{
+				Description: "Sign a transaction and submit it to Horizon",
+				Data: map[string]interface{}{
+					"publicKey":   "GATBMIXGZKJGSEVJQH7D2ZP3A4UQ4WKB3X5H3C6KHPGJRH4B3U5UJ6CH",
+					"transaction": "base64EncodedTransactionEnvelope",
+					"network":     "Testnet",
+					"submit":      true,
+				},
+				Response: &framework.Response{
+					Description: "Successful signing and submission of the Stellar transaction",
+					MediaType:   "application/json",
+					Example: &logical.Response{
+						Data: map[string]interface{}{
+							"signed_transaction": "base64EncodedSignedTransactionEnvelope",
+							"transaction_hash":   "3389e9f0f1a65f19736cacf544c2e825313e8447f569233bb8db39aa607c8889",
+							"source":             "GAJ2GM7LGQ2FHKFTWZMUPZMQLMNSRNEQG67X6VSYKGTP5TBTG6HXNLM2",
+							"sequence":           "212781269778433",
+							"operation_count":    1,
+							"submission_status":  "success",
+							"ledger":             1234567,
+						},
+					},
+				},
+			},
+			{
+				Description: "Sign a transaction whose value requires approval",
+				Data: map[string]interface{}{
+					"publicKey":   "GATBMIXGZKJGSEVJQH7D2ZP3A4UQ4WKB3X5H3C6KHPGJRH4B3U5UJ6CH",
+					"transaction": "base64EncodedTransactionEnvelope",
+					"network":     "Public",
+				},
+				Response: &framework.Response{
+					Description: "The transaction awaits approval at requests/<id>",
+					MediaType:   "application/json",
+					Example: &logical.Response{
+						Data: map[string]interface{}{
+							"id":               "0b2f6f3e-5a4c-4d0b-9a8e-3c1f2d7e6b5a",
+							"status":           "pending",
+							"approvals":        0,
+							"quorum":           2,
+							"transaction_hash": "3389e9f0f1a65f19736cacf544c2e825313e8447f569233bb8db39aa607c8889",
 						},
 					},
 				},
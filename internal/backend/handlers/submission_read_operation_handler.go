@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"vault-plugin-stellar-sign/internal/backend/stellar"
+)
+
+type ReadSubmissionHandler struct {
+	manager *stellar.Manager
+}
+
+func NewReadSubmissionHandler(m *stellar.Manager) *ReadSubmissionHandler {
+	return &ReadSubmissionHandler{manager: m}
+}
+
+func (h *ReadSubmissionHandler) Handler() framework.OperationFunc {
+	return instrument("read_submission", h.manager.ReadSubmission)
+}
+
+func (h *ReadSubmissionHandler) Properties() framework.OperationProperties {
+	return framework.OperationProperties{
+		Summary:     "Reads the status of a submission",
+		Description: "Retrieves the status, ledger and result of a transaction submitted to Horizon by the plugin.",
+		Examples: []framework.RequestExample{
+			{
+				Description: "Read the status of a submitted transaction",
+				Data: map[string]interface{}{
+					"publicKey": "GDSKR6UYBIYIU7GGVPIUZCX6C7EWG5VCRC2VCCH5NVFLBWMOSLBDBLHW",
+					"hash":      "3389e9f0f1a65f19736cacf544c2e825313e8447f569233bb8db39aa607c8889",
+				},
+				Response: &framework.Response{
+					Description: "Successful retrieval of the submission",
+					MediaType:   "application/json",
+					Example: &logical.Response{
+						Data: map[string]interface{}{
+							"transaction_hash": "3389e9f0f1a65f19736cacf544c2e825313e8447f569233bb8db39aa607c8889",
+							"public_key":       "GDSKR6UYBIYIU7GGVPIUZCX6C7EWG5VCRC2VCCH5NVFLBWMOSLBDBLHW",
+							"status":           "failed",
+							"result_code":      "tx_bad_seq",
+							"error":            "tx_bad_seq: the sequence number does not match the source account",
+						},
+					},
+				},
+			},
+		},
+	}
+}
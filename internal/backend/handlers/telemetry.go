@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"context"
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"time"
+	"vault-plugin-stellar-sign/internal/backend/stellar"
+)
+
+// instrument counts the requests and errors of an operation and measures its latency
+// and the latency of its storage operations. Errors caused by the request are answered
+// with their HTTP status and error code.
+func instrument(operation string, fn framework.OperationFunc) framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		labels := []metrics.Label{{Name: "operation", Value: operation}}
+		defer metrics.MeasureSinceWithLabels([]string{"stellar", "handler", "latency"}, time.Now(), labels)
+		metrics.IncrCounterWithLabels([]string{"stellar", "handler", "requests"}, 1, labels)
+
+		req.Storage = &meteredStorage{Storage: req.Storage}
+		resp, err := fn(ctx, req, data)
+		if err != nil || resp.IsError() {
+			metrics.IncrCounterWithLabels([]string{"stellar", "handler", "errors"}, 1, labels)
+		}
+		if err != nil {
+			return stellar.ErrorResponse(err)
+		}
+		return resp, nil
+	}
+}
+
+// meteredStorage measures the latency of the storage operations of a request
+type meteredStorage struct {
+	logical.Storage
+}
+
+func (s *meteredStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	defer measureStorage("list", time.Now())
+	return s.Storage.List(ctx, prefix)
+}
+
+func (s *meteredStorage) Get(ctx context.Context, key string) (*logical.StorageEntry, error) {
+	defer measureStorage("get", time.Now())
+	return s.Storage.Get(ctx, key)
+}
+
+func (s *meteredStorage) Put(ctx context.Context, entry *logical.StorageEntry) error {
+	defer measureStorage("put", time.Now())
+	return s.Storage.Put(ctx, entry)
+}
+
+func (s *meteredStorage) Delete(ctx context.Context, key string) error {
+	defer measureStorage("delete", time.Now())
+	return s.Storage.Delete(ctx, key)
+}
+
+func measureStorage(operation string, start time.Time) {
+	metrics.MeasureSinceWithLabels([]string{"stellar", "storage", "latency"}, start,
+		[]metrics.Label{{Name: "operation", Value: operation}})
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"vault-plugin-stellar-sign/internal/backend/stellar"
+)
+
+type ReadUnusedKeysReportHandler struct {
+	manager *stellar.Manager
+}
+
+func NewReadUnusedKeysReportHandler(m *stellar.Manager) *ReadUnusedKeysReportHandler {
+	return &ReadUnusedKeysReportHandler{manager: m}
+}
+
+func (h *ReadUnusedKeysReportHandler) Handler() framework.OperationFunc {
+	return instrument("read_unused_keys_report", h.manager.ReadUnusedKeysReport)
+}
+
+func (h *ReadUnusedKeysReportHandler) Properties() framework.OperationProperties {
+	return framework.OperationProperties{
+		Summary:     "Reports unused Stellar accounts",
+		Description: "Lists the accounts that have not signed a transaction for a number of days, to find dormant keys.",
+		Examples: []framework.RequestExample{
+			{
+				Description: "List the accounts unused for 30 days",
+				Data: map[string]interface{}{
+					"days": 30,
+				},
+				Response: &framework.Response{
+					Description: "Successful retrieval of the unused keys report",
+					MediaType:   "application/json",
+					Example: &logical.Response{
+						Data: map[string]interface{}{
+							"days":         30,
+							"generated_at": "2024-05-01T12:00:00Z",
+							"keys":         []string{"GASYNOBIVOZZJGBH6C5K2FPJQ2RPN2QBJ7OO6OXENIG5S2IKRALFTKIA"},
+							"key_info": map[string]interface{}{
+								"GASYNOBIVOZZJGBH6C5K2FPJQ2RPN2QBJ7OO6OXENIG5S2IKRALFTKIA": map[string]interface{}{
+									"created_at":      "2024-01-15T09:30:00Z",
+									"last_signed_at":  "2024-03-02T17:45:00Z",
+									"signature_count": 12,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
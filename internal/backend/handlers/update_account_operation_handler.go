@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"vault-plugin-stellar-sign/internal/backend/stellar"
+)
+
+type UpdateAccountHandler struct {
+	manager *stellar.Manager
+}
+
+func NewUpdateAccountHandler(m *stellar.Manager) *UpdateAccountHandler {
+	return &UpdateAccountHandler{manager: m}
+}
+
+func (h *UpdateAccountHandler) Handler() framework.OperationFunc {
+	return instrument("update_account", h.manager.UpdateAccount)
+}
+
+func (h *UpdateAccountHandler) Properties() framework.OperationProperties {
+	return framework.OperationProperties{
+		Summary:     "Updates a Stellar account",
+		Description: "Updates the metadata of a stored Stellar account, such as the signing policy attached to it.",
+		Examples: []framework.RequestExample{
+			{
+				Description: "Attach a signing policy to a Stellar account",
+				Data: map[string]interface{}{
+					"publicKey": "GASYNOBIVOZZJGBH6C5K2FPJQ2RPN2QBJ7OO6OXENIG5S2IKRALFTKIA",
+					"policy":    "soroban-dex",
+				},
+				Response: &framework.Response{
+					Description: "Successful update of the Stellar account",
+					MediaType:   "application/json",
+					Example: &logical.Response{
+						Data: map[string]interface{}{
+							"public_key": "GASYNOBIVOZZJGBH6C5K2FPJQ2RPN2QBJ7OO6OXENIG5S2IKRALFTKIA",
+							"policy":     "soroban-dex",
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -15,13 +15,17 @@ func NewCreateAccountHandler(m *stellar.Manager) *CreateAccountHandler {
 }
 
 func (h *CreateAccountHandler) Handler() framework.OperationFunc {
-	return h.manager.CreateAccount
+	return instrument("create_account", h.manager.CreateAccount)
 }
 
 func (h *CreateAccountHandler) Properties() framework.OperationProperties {
 	return framework.OperationProperties{
-		Summary:     "Creates a new Stellar account",
-		Description: "Generates a new Stellar account and stores it in the backend.",
+		Summary: "Creates a new Stellar account",
+		Description: "Generates a new Stellar account and stores it in the backend, optionally creating it on-ledger " +
+			"from a stored funding account with a starting balance or with sponsored reserves.",
+		// Funding transactions allocate sequence numbers under a lock held by the active node only
+		ForwardPerformanceStandby:   true,
+		ForwardPerformanceSecondary: true,
 		Examples: []framework.RequestExample{
 			{
 				Description: "Create a new Stellar account",
@@ -49,6 +53,27 @@ func (h *CreateAccountHandler) Properties() framework.OperationProperties {
 					},
 				},
 			},
+			{
+				Description: "Create a Stellar account on-ledger with reserves sponsored by a stored account",
+				Data: map[string]interface{}{
+					"funding_account": "GATBMIXGZKJGSEVJQH7D2ZP3A4UQ4WKB3X5H3C6KHPGJRH4B3U5UJ6CH",
+					"network":         "Testnet",
+					"sponsored":       true,
+				},
+				Response: &framework.Response{
+					Description: "Successful creation and funding of a new Stellar account",
+					MediaType:   "application/json",
+					Example: &logical.Response{
+						Data: map[string]interface{}{
+							"public_key":               "GASYNOBIVOZZJGBH6C5K2FPJQ2RPN2QBJ7OO6OXENIG5S2IKRALFTKIA",
+							"funding_transaction":      "base64EncodedSignedTransactionEnvelope",
+							"funding_transaction_hash": "3389e9f0f1a65f19736cacf544c2e825313e8447f569233bb8db39aa607c8889",
+							"submission_status":        "success",
+							"ledger":                   1234,
+						},
+					},
+				},
+			},
 		},
 	}
 }
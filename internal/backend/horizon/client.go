@@ -0,0 +1,215 @@
+package horizon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when Horizon does not know the requested resource
+var ErrNotFound = errors.New("resource not found on Horizon")
+
+// Client is a minimal Horizon API client covering the endpoints the plugin uses
+type Client struct {
+	url  string
+	http *http.Client
+}
+
+func NewClient(horizonURL string) *Client {
+	return &Client{
+		url:  strings.TrimRight(horizonURL, "/"),
+		http: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Problem is the error document returned by Horizon
+type Problem struct {
+	Type   string                 `json:"type"`
+	Title  string                 `json:"title"`
+	Status int                    `json:"status"`
+	Detail string                 `json:"detail"`
+	Extras map[string]interface{} `json:"extras,omitempty"`
+}
+
+func (p *Problem) Error() string {
+	return fmt.Sprintf("horizon error %d %s: %s", p.Status, p.Title, p.Detail)
+}
+
+// ResultXDR returns the base64 TransactionResult of a failed submission, if any
+func (p *Problem) ResultXDR() string {
+	resultXDR, _ := p.Extras["result_xdr"].(string)
+	return resultXDR
+}
+
+// Rejected tells whether Horizon refused the transaction itself, e.g. as
+// transaction_malformed, rather than failing to process it. Submitting it again gets the
+// same answer.
+func (p *Problem) Rejected() bool {
+	return p.Status >= 400 && p.Status < 500 && p.Status != http.StatusRequestTimeout && p.Status != http.StatusTooManyRequests
+}
+
+// Code returns the last segment of the problem type, e.g. transaction_malformed
+func (p *Problem) Code() string {
+	if p.Type == "" {
+		return strconv.Itoa(p.Status)
+	}
+	return p.Type[strings.LastIndex(p.Type, "/")+1:]
+}
+
+// OperationResultCodes returns the operation result codes of a failed submission, if any
+func (p *Problem) OperationResultCodes() []string {
+	resultCodes, _ := p.Extras["result_codes"].(map[string]interface{})
+	rawCodes, _ := resultCodes["operations"].([]interface{})
+	codes := make([]string, 0, len(rawCodes))
+	for _, code := range rawCodes {
+		if s, ok := code.(string); ok {
+			codes = append(codes, s)
+		}
+	}
+	return codes
+}
+
+// Transaction is a transaction included in the ledger
+type Transaction struct {
+	Hash       string `json:"hash"`
+	Ledger     int32  `json:"ledger"`
+	Successful bool   `json:"successful"`
+	ResultXDR  string `json:"result_xdr"`
+}
+
+// Balance is an account balance as reported by Horizon
+type Balance struct {
+	Balance     string `json:"balance"`
+	AssetType   string `json:"asset_type"`
+	AssetCode   string `json:"asset_code,omitempty"`
+	AssetIssuer string `json:"asset_issuer,omitempty"`
+}
+
+// Thresholds are the signature thresholds of an account
+type Thresholds struct {
+	Low  int `json:"low_threshold"`
+	Med  int `json:"med_threshold"`
+	High int `json:"high_threshold"`
+}
+
+// Signer is a signer of an account
+type Signer struct {
+	Key    string `json:"key"`
+	Weight int    `json:"weight"`
+	Type   string `json:"type"`
+}
+
+// Account is the ledger state of an account
+type Account struct {
+	ID         string     `json:"id"`
+	Sequence   string     `json:"sequence"`
+	Balances   []Balance  `json:"balances"`
+	Thresholds Thresholds `json:"thresholds"`
+	Signers    []Signer   `json:"signers"`
+}
+
+// Account returns the ledger state of an account, or ErrNotFound if it does not exist
+func (c *Client) Account(ctx context.Context, accountID string) (*Account, error) {
+	var account Account
+	if err := c.get(ctx, "/accounts/"+url.PathEscape(accountID), &account); err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// AccountsForSigner returns the IDs of the accounts on which the key is a signer. Pages
+// are requested from the paging token of the last record, as the next link of Horizon
+// does, until a page is not full.
+func (c *Client) AccountsForSigner(ctx context.Context, signer string) ([]string, error) {
+	const pageSize = 200
+	accountIDs := []string{}
+	cursor := ""
+	for {
+		var page struct {
+			Embedded struct {
+				Records []struct {
+					ID          string `json:"id"`
+					PagingToken string `json:"paging_token"`
+				} `json:"records"`
+			} `json:"_embedded"`
+		}
+		query := url.Values{"signer": {signer}, "limit": {strconv.Itoa(pageSize)}, "order": {"asc"}}
+		if cursor != "" {
+			query.Set("cursor", cursor)
+		}
+		if err := c.get(ctx, "/accounts?"+query.Encode(), &page); err != nil {
+			return nil, err
+		}
+		records := page.Embedded.Records
+		for _, record := range records {
+			accountIDs = append(accountIDs, record.ID)
+		}
+		if len(records) < pageSize || records[len(records)-1].PagingToken == "" || records[len(records)-1].PagingToken == cursor {
+			return accountIDs, nil
+		}
+		cursor = records[len(records)-1].PagingToken
+	}
+}
+
+// SubmitTransaction submits a signed transaction envelope and waits for it to be
+// included in a ledger. Rejected transactions are returned as a *Problem.
+func (c *Client) SubmitTransaction(ctx context.Context, txEnvelopeBase64 string) (*Transaction, error) {
+	form := url.Values{"tx": {txEnvelopeBase64}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url+"/transactions", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var tx Transaction
+	if err = c.do(req, &tx); err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
+
+// Transaction returns a transaction by hash, or ErrNotFound if it is not in the ledger
+func (c *Client) Transaction(ctx context.Context, hash string) (*Transaction, error) {
+	var tx Transaction
+	if err := c.get(ctx, "/transactions/"+url.PathEscape(hash), &tx); err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
+
+func (c *Client) get(ctx context.Context, path string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	return c.do(req, out)
+}
+
+func (c *Client) do(req *http.Request, out interface{}) error {
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("horizon request failed: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		problem := &Problem{Status: resp.StatusCode, Title: resp.Status}
+		_ = json.NewDecoder(resp.Body).Decode(problem)
+		return problem
+	}
+	if err = json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode horizon response: %s", err)
+	}
+	return nil
+}
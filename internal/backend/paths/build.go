@@ -0,0 +1,88 @@
+package paths
+
+import (
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"vault-plugin-stellar-sign/internal/backend/handlers"
+	"vault-plugin-stellar-sign/internal/backend/stellar"
+)
+
+func Build(m *stellar.Manager) *framework.Path {
+	return &framework.Path{
+		Pattern:      "accounts/" + framework.GenericNameRegex("publicKey") + "/build",
+		HelpSynopsis: "Build and sign a Stellar transaction from a description of its operations.",
+		HelpDescription: `
+
+    Build a Stellar transaction from a JSON description of its operations, check it
+    against the signing policies and sign it with the secret key of the specified account.
+    The sequence number is fetched from the Horizon configured for the network unless
+    it is provided.
+
+    `,
+		Fields: map[string]*framework.FieldSchema{
+			"publicKey": {
+				Type:        framework.TypeString,
+				Description: "The public key of the account to use for signing, or a muxed account address of it.",
+			},
+			"network": {
+				Type:        framework.TypeString,
+				Description: "The network for the transaction ('Public' or 'Testnet').",
+			},
+			"operations": {
+				Type:        framework.TypeSlice,
+				Description: "The operations of the transaction as JSON objects with a 'type' and the fields of that operation type.",
+			},
+			"source": {
+				Type:        framework.TypeString,
+				Description: "The source account of the transaction. Defaults to the signing account.",
+			},
+			"sequence": {
+				Type:        framework.TypeString,
+				Description: "The sequence number of the transaction. Fetched from Horizon when not provided.",
+			},
+			"fee": {
+				Type:        framework.TypeInt,
+				Description: "The base fee per operation in stroops.",
+				Default:     100,
+			},
+			"memo": {
+				Type:        framework.TypeString,
+				Description: "A text memo for the transaction.",
+			},
+			"memo_id": {
+				Type:        framework.TypeString,
+				Description: "An ID memo for the transaction.",
+			},
+			"min_time": {
+				Type:        framework.TypeInt,
+				Description: "The unix time before which the transaction is not valid.",
+			},
+			"max_time": {
+				Type:        framework.TypeInt,
+				Description: "The unix time after which the transaction is not valid. Overrides timeout.",
+			},
+			"timeout": {
+				Type:        framework.TypeDurationSecond,
+				Description: "How long the transaction stays valid from now. Defaults to 300 seconds.",
+			},
+			"idempotency_key": {
+				Type:        framework.TypeString,
+				Description: "Client chosen key identifying the request. Repeating it returns the previously signed transaction instead of signing again. Requires replay protection.",
+			},
+			"submit": {
+				Type:        framework.TypeBool,
+				Description: "Submit the signed transaction to the Horizon configured for the network.",
+			},
+			"async": {
+				Type:        framework.TypeBool,
+				Description: "With submit, return immediately and track the outcome at submissions/<hash>.",
+			},
+		},
+		ExistenceCheck: m.AccountExistenceCheck,
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: handlers.NewBuildTxHandler(m),
+			// Signing with an account that does not exist arrives as CreateOperation and fails
+			logical.CreateOperation: handlers.NewBuildTxHandler(m),
+		},
+	}
+}
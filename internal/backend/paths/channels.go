@@ -0,0 +1,169 @@
+package paths
+
+import (
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"vault-plugin-stellar-sign/internal/backend/handlers"
+	"vault-plugin-stellar-sign/internal/backend/stellar"
+)
+
+func ListChannelPools(m *stellar.Manager) *framework.Path {
+	return &framework.Path{
+		Pattern:      "channels/?",
+		HelpSynopsis: "List the channel pools maintained by the plugin backend.",
+		HelpDescription: `
+
+    LIST - list all channel pools
+
+    `,
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ListOperation: handlers.NewListChannelPoolsHandler(m),
+		},
+	}
+}
+
+func ChannelPool(m *stellar.Manager) *framework.Path {
+	return &framework.Path{
+		Pattern:      "channels/" + framework.GenericNameRegex("name"),
+		HelpSynopsis: "Create, get, update or delete a channel pool by name",
+		HelpDescription: `
+			GET - return the pool and its leased channels
+			POST - create the pool, or update the lease TTLs of an existing pool
+			DELETE - deletes the pool, the channel accounts stay stored`,
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "The name of the channel pool.",
+			},
+			"account": {
+				Type:        framework.TypeString,
+				Description: "The public key of the main account, the source of the operations built on the channels.",
+			},
+			"network": {
+				Type:        framework.TypeString,
+				Description: "The network of the channels ('Public' or 'Testnet').",
+			},
+			"size": {
+				Type:        framework.TypeInt,
+				Description: "The number of channel accounts to create, at most 100.",
+			},
+			"lease_ttl": {
+				Type:        framework.TypeDurationSecond,
+				Description: "How long a channel stays leased unless the lease is renewed. Defaults to 60 seconds.",
+			},
+			"max_lease_ttl": {
+				Type:        framework.TypeDurationSecond,
+				Description: "How long a lease can be renewed for in total. Defaults to the maximum lease TTL of the mount.",
+			},
+			"fund": {
+				Type:        framework.TypeBool,
+				Description: "Create the channel accounts on-ledger, funded by the main account.",
+			},
+			"starting_balance": {
+				Type:        framework.TypeString,
+				Description: "The XLM balance each channel account is created with. Defaults to 2.",
+			},
+		},
+		ExistenceCheck: m.ChannelPoolExistenceCheck,
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation:   handlers.NewReadChannelPoolHandler(m),
+			logical.CreateOperation: handlers.NewWriteChannelPoolHandler(m),
+			logical.UpdateOperation: handlers.NewWriteChannelPoolHandler(m),
+			logical.DeleteOperation: handlers.NewDeleteChannelPoolHandler(m),
+		},
+	}
+}
+
+func ChannelLease(m *stellar.Manager) *framework.Path {
+	return &framework.Path{
+		Pattern:      "channels/" + framework.GenericNameRegex("name") + "/lease",
+		HelpSynopsis: "Lease a channel account of a pool.",
+		HelpDescription: `
+
+    Reserve a free channel of the pool for the duration of a Vault lease. The channel
+    is released when the lease is revoked or expires, or once a transaction built on
+    it was submitted.
+
+    `,
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "The name of the channel pool.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: handlers.NewLeaseChannelHandler(m),
+		},
+	}
+}
+
+func ChannelBuild(m *stellar.Manager) *framework.Path {
+	return &framework.Path{
+		Pattern:      "channels/" + framework.GenericNameRegex("name") + "/build",
+		HelpSynopsis: "Build and sign a Stellar transaction on a leased channel account.",
+		HelpDescription: `
+
+    Build a transaction with the leased channel as source and the main account of the
+    pool as source of every operation, and sign it with both accounts.
+
+    `,
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "The name of the channel pool.",
+			},
+			"channel": {
+				Type:        framework.TypeString,
+				Description: "The public key of the leased channel account.",
+			},
+			"lease_id": {
+				Type:        framework.TypeString,
+				Description: "The lease_id returned with the channel by channels/<name>/lease.",
+			},
+			"operations": {
+				Type:        framework.TypeSlice,
+				Description: "The operations of the transaction as JSON objects with a 'type' and the fields of that operation type.",
+			},
+			"fee": {
+				Type:        framework.TypeInt,
+				Description: "The base fee per operation in stroops.",
+				Default:     100,
+			},
+			"memo": {
+				Type:        framework.TypeString,
+				Description: "A text memo for the transaction.",
+			},
+			"memo_id": {
+				Type:        framework.TypeString,
+				Description: "An ID memo for the transaction.",
+			},
+			"min_time": {
+				Type:        framework.TypeInt,
+				Description: "The unix time before which the transaction is not valid.",
+			},
+			"max_time": {
+				Type:        framework.TypeInt,
+				Description: "The unix time after which the transaction is not valid. Overrides timeout.",
+			},
+			"timeout": {
+				Type:        framework.TypeDurationSecond,
+				Description: "How long the transaction stays valid from now. Defaults to 300 seconds.",
+			},
+			"idempotency_key": {
+				Type:        framework.TypeString,
+				Description: "Client chosen key identifying the request. Repeating it returns the previously signed transaction instead of signing again. Requires replay protection.",
+			},
+			"submit": {
+				Type:        framework.TypeBool,
+				Description: "Submit the signed transaction to the Horizon configured for the network.",
+			},
+			"async": {
+				Type:        framework.TypeBool,
+				Description: "With submit, return immediately and track the outcome at submissions/<hash>.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: handlers.NewBuildChannelTxHandler(m),
+		},
+	}
+}
@@ -0,0 +1,76 @@
+package paths
+
+import (
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"vault-plugin-stellar-sign/internal/backend/handlers"
+	"vault-plugin-stellar-sign/internal/backend/stellar"
+)
+
+func Config(m *stellar.Manager) *framework.Path {
+	return &framework.Path{
+		Pattern:      "config",
+		HelpSynopsis: "Configure the mount-wide settings of the plugin backend.",
+		HelpDescription: `
+
+    GET - return the current configuration
+    POST - update the configuration
+
+    `,
+		Fields: map[string]*framework.FieldSchema{
+			"policy": {
+				Type:        framework.TypeString,
+				Description: "The name of a signing policy enforced for every account of the mount, in addition to the account's own policy.",
+			},
+			"replay_protection": {
+				Type:        framework.TypeBool,
+				Description: "Remember signed transaction hashes and refuse to sign the same transaction twice.",
+			},
+			"replay_window": {
+				Type:        framework.TypeDurationSecond,
+				Description: "How long signed transactions are remembered when replay protection is enabled. Defaults to 24h.",
+			},
+			"reject_sequence_reuse": {
+				Type:        framework.TypeBool,
+				Description: "Refuse to sign two different transactions for the same source account and sequence number.",
+			},
+			"horizon_urls": {
+				Type:        framework.TypeKVPairs,
+				Description: "Horizon URL to use for each network, as a map of network name ('Public' or 'Testnet') to URL.",
+			},
+			"horizon_cache_ttl": {
+				Type:        framework.TypeDurationSecond,
+				Description: "How long account state fetched from Horizon is cached. Defaults to 30s.",
+			},
+			"ownership": {
+				Type:        framework.TypeBool,
+				Description: "Scope accounts to the entity or group owning them. Other callers cannot list, read or sign with them unless they were shared.",
+			},
+			"admin_group": {
+				Type:        framework.TypeString,
+				Description: "The name or ID of an identity group whose members see every account when ownership is enforced.",
+			},
+			"metrics_account_label": {
+				Type:          framework.TypeString,
+				Description:   "How signature and policy denial metrics identify the signer: by 'public_key' (default), by 'role', or 'none' to only label by network and policy.",
+				AllowedValues: []interface{}{"public_key", "role", "none"},
+			},
+			"unused_key_days": {
+				Type:        framework.TypeInt,
+				Description: "After how many days without a signature a key is listed in the unused keys report. Defaults to 90.",
+			},
+			"history_retention_days": {
+				Type:        framework.TypeInt,
+				Description: "For how many days signing history records are kept before the periodic function prunes them. Defaults to 365.",
+			},
+			"prices": {
+				Type:        framework.TypeKVPairs,
+				Description: "The value in XLM of one unit of each asset, keyed by CODE:ISSUER, used to value transactions for the approval tiers of policies. An empty price removes the asset.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation:   handlers.NewReadConfigHandler(m),
+			logical.UpdateOperation: handlers.NewWriteConfigHandler(m),
+		},
+	}
+}
@@ -23,10 +23,160 @@ func CreateAndList(m *stellar.Manager) *framework.Path {
 				Description: "Base64 encoded string representing the Stellar secret key. If provided, the request will import this key instead of generating a new one. The secret key is used to sign transactions and should be kept private.",
 				Default:     "",
 			},
+			"name": {
+				Type:        framework.TypeString,
+				Description: "An optional label of the account. Listings can filter and sort on it.",
+			},
+			"policy": {
+				Type:        framework.TypeString,
+				Description: "The name of the signing policy to enforce when this account signs transactions.",
+			},
+			"allowed_sources": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "The ledger accounts (G- or M-addresses) this account may sign for, checked against the transaction source and every operation source. Defaults to the account itself. Use '*' to allow any source account.",
+			},
+			"owner_group": {
+				Type:        framework.TypeString,
+				Description: "The name or ID of an identity group of the caller that owns the account, in addition to the caller's entity.",
+			},
+			"max_signatures": {
+				Type:        framework.TypeInt,
+				Description: "The number of signatures the account may produce over its lifetime. 0 is unlimited.",
+			},
+			"not_before": {
+				Type:        framework.TypeString,
+				Description: "The RFC 3339 time before which the account refuses to sign. Empty clears it.",
+			},
+			"not_after": {
+				Type:        framework.TypeString,
+				Description: "The RFC 3339 time from which the account refuses to sign. Empty clears it.",
+			},
+			"detailed": {
+				Type:        framework.TypeBool,
+				Description: "When listing, return the metadata and usage of every account in key_info.",
+			},
+			"network": {
+				Type:        framework.TypeString,
+				Description: "The network ('Public' or 'Testnet') of the funding transaction when creating. When listing, also return the on-ledger state of every account in key_info, fetched from the Horizon configured for this network.",
+			},
+			"funding_account": {
+				Type:        framework.TypeString,
+				Description: "When creating, the public key of a stored account that creates the new account on-ledger. The transaction is submitted when Horizon is configured for the network.",
+			},
+			"starting_balance": {
+				Type:        framework.TypeString,
+				Description: "The XLM balance the funding account creates the new account with.",
+			},
+			"sponsored": {
+				Type:        framework.TypeBool,
+				Description: "Create the account with a zero balance and its reserves sponsored by the funding account. Both accounts sign the funding transaction.",
+			},
+			"after": {
+				Type:        framework.TypeString,
+				Description: "When listing, return the accounts following this public key, the last one of the previous page.",
+			},
+			"limit": {
+				Type:        framework.TypeInt,
+				Description: "When listing, the maximum number of accounts to return. 0 returns all of them.",
+			},
+			"sort": {
+				Type:          framework.TypeString,
+				Description:   "When listing, the order of the accounts: 'public_key', 'name' or 'created_at'.",
+				Default:       stellar.SortByPublicKey,
+				AllowedValues: []interface{}{stellar.SortByPublicKey, stellar.SortByName, stellar.SortByCreatedAt},
+			},
+			"prefix": {
+				Type:        framework.TypeString,
+				Description: "When listing, only return the accounts whose address or name starts with this prefix.",
+			},
 		},
+		ExistenceCheck: m.NewAccountExistenceCheck,
 		Operations: map[logical.Operation]framework.OperationHandler{
 			logical.ListOperation:   handlers.NewListAccountsHandler(m),
-			logical.UpdateOperation: handlers.NewCreateAccountHandler(m),
+			logical.CreateOperation: handlers.NewCreateAccountHandler(m),
+		},
+	}
+}
+
+func BulkCreate(m *stellar.Manager) *framework.Path {
+	return &framework.Path{
+		Pattern:      "accounts/bulk",
+		HelpSynopsis: "Create several Stellar accounts sharing their settings in one request.",
+		HelpDescription: `
+
+    POST - generate count accounts with the same name, policy, allowed sources, owner
+    group and limits, optionally add them to a role, and return their public keys.
+    If any account cannot be created, none are kept.
+
+    With a funding account, the response also holds the transactions of the funding
+    account creating the accounts on-ledger, ready to be signed by it.
+
+    `,
+		Fields: map[string]*framework.FieldSchema{
+			"count": {
+				Type:        framework.TypeInt,
+				Description: "The number of accounts to generate, at most 1000.",
+			},
+			"name": {
+				Type:        framework.TypeString,
+				Description: "An optional label shared by the accounts. Listings can filter and sort on it.",
+			},
+			"policy": {
+				Type:        framework.TypeString,
+				Description: "The name of the signing policy to enforce when the accounts sign transactions.",
+			},
+			"allowed_sources": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "The ledger accounts (G- or M-addresses) the accounts may sign for. Defaults to each account itself. Use '*' to allow any source account.",
+			},
+			"owner_group": {
+				Type:        framework.TypeString,
+				Description: "The name or ID of an identity group of the caller that owns the accounts, in addition to the caller's entity.",
+			},
+			"role": {
+				Type:        framework.TypeString,
+				Description: "The name of a role the accounts are added to, so that the role signs with them.",
+			},
+			"max_signatures": {
+				Type:        framework.TypeInt,
+				Description: "The number of signatures each account may produce over its lifetime. 0 is unlimited.",
+			},
+			"not_before": {
+				Type:        framework.TypeString,
+				Description: "The RFC 3339 time before which the accounts refuse to sign.",
+			},
+			"not_after": {
+				Type:        framework.TypeString,
+				Description: "The RFC 3339 time from which the accounts refuse to sign.",
+			},
+			"funding_account": {
+				Type:        framework.TypeString,
+				Description: "The address of the account creating the accounts on-ledger. When set, the unsigned funding transactions are returned.",
+			},
+			"network": {
+				Type:        framework.TypeString,
+				Description: "The network of the funding transactions ('Public' or 'Testnet').",
+			},
+			"starting_balance": {
+				Type:        framework.TypeString,
+				Description: "The XLM balance each account is created with by the funding account.",
+			},
+			"sponsored": {
+				Type:        framework.TypeBool,
+				Description: "Create the accounts with a zero balance and their reserves sponsored by the funding account. The accounts sign the funding transactions.",
+			},
+			"sequence": {
+				Type:        framework.TypeString,
+				Description: "The sequence number of the first funding transaction, the next ones follow it. Fetched from Horizon when not provided.",
+			},
+			"timeout": {
+				Type:        framework.TypeDurationSecond,
+				Description: "How long the funding transactions stay valid from now. Defaults to 300 seconds.",
+			},
+		},
+		ExistenceCheck: m.NewAccountExistenceCheck,
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.CreateOperation: handlers.NewBulkCreateAccountsHandler(m),
 		},
 	}
 }
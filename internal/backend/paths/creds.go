@@ -0,0 +1,31 @@
+package paths
+
+import (
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"vault-plugin-stellar-sign/internal/backend/handlers"
+	"vault-plugin-stellar-sign/internal/backend/stellar"
+)
+
+func Creds(m *stellar.Manager) *framework.Path {
+	return &framework.Path{
+		Pattern:      "creds/" + framework.GenericNameRegex("name"),
+		HelpSynopsis: "Issue an ephemeral Stellar account for a role.",
+		HelpDescription: `
+
+    Generate a keypair leased for the TTL of the role. Depending on the role, the
+    account is created on-ledger by the funding account or added as a signer of the
+    parent account, and merged back or removed when the lease is revoked.
+
+    `,
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "The name of the role.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: handlers.NewIssueCredsHandler(m),
+		},
+	}
+}
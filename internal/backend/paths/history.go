@@ -0,0 +1,29 @@
+package paths
+
+import (
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"vault-plugin-stellar-sign/internal/backend/handlers"
+	"vault-plugin-stellar-sign/internal/backend/stellar"
+)
+
+func History(m *stellar.Manager) *framework.Path {
+	return &framework.Path{
+		Pattern:      "accounts/" + framework.GenericNameRegex("publicKey") + "/history/?",
+		HelpSynopsis: "List the transactions signed by a Stellar account.",
+		HelpDescription: `
+
+    LIST - list the signed transaction hashes with their details
+
+    `,
+		Fields: map[string]*framework.FieldSchema{
+			"publicKey": {
+				Type:        framework.TypeString,
+				Description: "The public key or muxed address of the account.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ListOperation: handlers.NewListHistoryHandler(m),
+		},
+	}
+}
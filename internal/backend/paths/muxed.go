@@ -0,0 +1,33 @@
+package paths
+
+import (
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"vault-plugin-stellar-sign/internal/backend/handlers"
+	"vault-plugin-stellar-sign/internal/backend/stellar"
+)
+
+func MuxedAddress(m *stellar.Manager) *framework.Path {
+	return &framework.Path{
+		Pattern:      "accounts/" + framework.GenericNameRegex("publicKey") + "/muxed/(?P<id>[0-9]+)",
+		HelpSynopsis: "Generate the muxed account address of a Stellar account for a mux ID.",
+		HelpDescription: `
+
+    GET - return the M-address of the account for the mux ID
+
+    `,
+		Fields: map[string]*framework.FieldSchema{
+			"publicKey": {
+				Type:        framework.TypeString,
+				Description: "The public key of the account.",
+			},
+			"id": {
+				Type:        framework.TypeString,
+				Description: "The 64-bit mux ID.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: handlers.NewMuxedAddressHandler(m),
+		},
+	}
+}
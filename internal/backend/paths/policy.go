@@ -0,0 +1,95 @@
+package paths
+
+import (
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"vault-plugin-stellar-sign/internal/backend/handlers"
+	"vault-plugin-stellar-sign/internal/backend/stellar"
+)
+
+func ListPolicies(m *stellar.Manager) *framework.Path {
+	return &framework.Path{
+		Pattern:      "policies/?",
+		HelpSynopsis: "List the signing policies maintained by the plugin backend.",
+		HelpDescription: `
+
+    LIST - list all signing policies
+
+    `,
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ListOperation: handlers.NewListPoliciesHandler(m),
+		},
+	}
+}
+
+func ReadWriteAndDeletePolicy(m *stellar.Manager) *framework.Path {
+	return &framework.Path{
+		Pattern:      "policies/" + framework.GenericNameRegex("name"),
+		HelpSynopsis: "Create, get or delete a signing policy by name",
+		HelpDescription: `
+			GET - return the policy by name
+			POST - create or replace the policy
+			DELETE - deletes the policy by name`,
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "The name of the signing policy.",
+			},
+			"soroban_contracts": {
+				Type: framework.TypeSlice,
+				Description: "Allowlist of Soroban contract calls. Each entry is an object with a 'contract' C-address, " +
+					"an optional list of 'functions' and optional 'args' constraints of the form " +
+					"{\"index\": 0, \"values\": [\"...\"], \"function\": \"...\"}. Setting any soroban_* field " +
+					"denies every contract call that is not listed.",
+			},
+			"soroban_max_resource_fee": {
+				Type:        framework.TypeInt,
+				Description: "Maximum declared Soroban resource fee in stroops. 0 means no cap.",
+			},
+			"soroban_allow_wasm_upload": {
+				Type:        framework.TypeBool,
+				Description: "Whether transactions uploading contract Wasm may be signed.",
+			},
+			"soroban_allow_contract_creation": {
+				Type:        framework.TypeBool,
+				Description: "Whether transactions creating contracts may be signed.",
+			},
+			"require_time_bounds": {
+				Type:        framework.TypeBool,
+				Description: "Whether transactions must set a max time.",
+			},
+			"max_validity_window": {
+				Type:        framework.TypeDurationSecond,
+				Description: "Maximum time between now and the transaction max time. Implies time bounds are required.",
+			},
+			"require_ledger_bounds": {
+				Type:        framework.TypeBool,
+				Description: "Whether transactions must set a max ledger (CAP-21).",
+			},
+			"min_sequence_age": {
+				Type:        framework.TypeDurationSecond,
+				Description: "Minimum sequence age precondition transactions must set (CAP-21).",
+			},
+			"min_sequence_ledger_gap": {
+				Type:        framework.TypeInt,
+				Description: "Minimum sequence ledger gap precondition transactions must set (CAP-21).",
+			},
+			"required_extra_signers": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "Signer keys transactions must list as extra signers (CAP-21).",
+			},
+			"approval_tiers": {
+				Type: framework.TypeSlice,
+				Description: "Route transactions by their value in XLM to the number of approvals they need. Each entry is an " +
+					"object of the form {\"max_value\": \"1000\", \"approvals\": 0}, optionally with 'approvers', " +
+					"'approver_groups' and 'distinct_groups'. The tier without max_value covers any higher value and " +
+					"transactions whose value cannot be determined. Tiers without approvers use those of the account.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation:   handlers.NewReadPolicyHandler(m),
+			logical.UpdateOperation: handlers.NewWritePolicyHandler(m),
+			logical.DeleteOperation: handlers.NewDeletePolicyHandler(m),
+		},
+	}
+}
@@ -10,16 +10,80 @@ import (
 func ReadAndDelete(m *stellar.Manager) *framework.Path {
 	return &framework.Path{
 		Pattern:      "accounts/" + framework.GenericNameRegex("publicKey"),
-		HelpSynopsis: "Create, get or delete a Stellar account by publicKey",
+		HelpSynopsis: "Create, get or delete a Stellar account by publicKey. Reads also accept a muxed account address.",
 		HelpDescription: `
 			GET - return the account by the publicKey
+			POST - update the account metadata
 			DELETE - deletes the account by the publicKey`,
 		Fields: map[string]*framework.FieldSchema{
 			"publicKey": {Type: framework.TypeString},
+			"name": {
+				Type:        framework.TypeString,
+				Description: "An optional label of the account. Listings can filter and sort on it.",
+			},
+			"policy": {
+				Type:        framework.TypeString,
+				Description: "The name of the signing policy to enforce when this account signs transactions.",
+			},
+			"allowed_sources": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "The ledger accounts (G- or M-addresses) this account may sign for, checked against the transaction source and every operation source. Defaults to the account itself. Use '*' to allow any source account.",
+			},
+			"network": {
+				Type:        framework.TypeString,
+				Description: "When reading, fetch the on-ledger state of the account from the Horizon configured for this network ('Public' or 'Testnet').",
+			},
+			"mux_policies": {
+				Type:        framework.TypeKVPairs,
+				Description: "Signing policies that replace the account policy when the account is addressed with a given mux ID, as a map of mux ID to policy name.",
+			},
+			"owner_group": {
+				Type:        framework.TypeString,
+				Description: "The name or ID of an identity group of the caller that owns the account.",
+			},
+			"shared_with": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "The IDs of other entities that may use the account when ownership is enforced.",
+			},
+			"approvers": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "The IDs of the identity entities that may approve transactions of the account.",
+			},
+			"approver_groups": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "The names or IDs of identity groups whose members may approve transactions of the account.",
+			},
+			"approval_quorum": {
+				Type:        framework.TypeInt,
+				Description: "The number of approvals a transaction of the account needs before it is signed. 0 leaves approval to the approval tiers of the account's policies.",
+			},
+			"approval_distinct_groups": {
+				Type:        framework.TypeBool,
+				Description: "Require the approvals to come from distinct approver groups.",
+			},
+			"approval_ttl": {
+				Type:        framework.TypeDurationSecond,
+				Description: "How long an approval request stays pending before it expires. Defaults to 24h.",
+			},
+			"max_signatures": {
+				Type:        framework.TypeInt,
+				Description: "The number of signatures the account may produce over its lifetime. 0 is unlimited.",
+			},
+			"not_before": {
+				Type:        framework.TypeString,
+				Description: "The RFC 3339 time before which the account refuses to sign. Empty clears it.",
+			},
+			"not_after": {
+				Type:        framework.TypeString,
+				Description: "The RFC 3339 time from which the account refuses to sign. Empty clears it.",
+			},
 		},
 		ExistenceCheck: m.AccountExistenceCheck,
 		Operations: map[logical.Operation]framework.OperationHandler{
-			logical.ReadOperation:   handlers.NewReadAccountHandler(m),
+			logical.ReadOperation: handlers.NewReadAccountHandler(m),
+			// Writes to an account that does not exist arrive as CreateOperation and fail
+			logical.CreateOperation: handlers.NewUpdateAccountHandler(m),
+			logical.UpdateOperation: handlers.NewUpdateAccountHandler(m),
 			logical.DeleteOperation: handlers.NewDeleteAccountHandler(m),
 		},
 	}
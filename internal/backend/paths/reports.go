@@ -0,0 +1,32 @@
+package paths
+
+import (
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"vault-plugin-stellar-sign/internal/backend/handlers"
+	"vault-plugin-stellar-sign/internal/backend/stellar"
+)
+
+func UnusedKeysReport(m *stellar.Manager) *framework.Path {
+	return &framework.Path{
+		Pattern:      "reports/unused-keys",
+		HelpSynopsis: "Report the accounts that have not signed for a number of days.",
+		HelpDescription: `
+
+    GET - list the unused accounts with their creation time, last signature and signature count
+
+    The report is regenerated daily by the periodic function, for the unused_key_days
+    of the configuration. Other periods are computed when requested.
+
+    `,
+		Fields: map[string]*framework.FieldSchema{
+			"days": {
+				Type:        framework.TypeInt,
+				Description: "After how many days without a signature an account is unused. Defaults to unused_key_days of the configuration.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: handlers.NewReadUnusedKeysReportHandler(m),
+		},
+	}
+}
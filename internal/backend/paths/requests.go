@@ -0,0 +1,115 @@
+package paths
+
+import (
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"vault-plugin-stellar-sign/internal/backend/handlers"
+	"vault-plugin-stellar-sign/internal/backend/stellar"
+)
+
+func CreateAndListApprovalRequests(m *stellar.Manager) *framework.Path {
+	return &framework.Path{
+		Pattern:      "requests/?",
+		HelpSynopsis: "Submit a transaction for approval or list the approval requests.",
+		HelpDescription: `
+
+    POST - submit a transaction of an account that requires approval
+    LIST - list all approval requests with their status
+
+    `,
+		Fields: map[string]*framework.FieldSchema{
+			"publicKey": {
+				Type:        framework.TypeString,
+				Description: "The public key of the account to sign with, or a muxed account address of it.",
+			},
+			"transaction": {
+				Type:        framework.TypeString,
+				Description: "The base64 encoded Stellar transaction envelope to sign once approved.",
+			},
+			"network": {
+				Type:        framework.TypeString,
+				Description: "The network for the transaction ('Public' or 'Testnet').",
+			},
+			"comment": {
+				Type:        framework.TypeString,
+				Description: "A note for the approvers.",
+			},
+			"submit": {
+				Type:        framework.TypeBool,
+				Description: "Submit the transaction to the Horizon configured for the network once it is approved and signed.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: handlers.NewCreateApprovalRequestHandler(m),
+			logical.ListOperation:   handlers.NewListApprovalRequestsHandler(m),
+		},
+	}
+}
+
+func ReadApprovalRequest(m *stellar.Manager) *framework.Path {
+	return &framework.Path{
+		Pattern:      "requests/" + framework.GenericNameRegex("id"),
+		HelpSynopsis: "Get an approval request by ID.",
+		HelpDescription: `
+
+    GET - return the approval request with its decisions. The signed transaction is
+    only returned to the requester once the request is approved.
+
+    `,
+		Fields: map[string]*framework.FieldSchema{
+			"id": {
+				Type:        framework.TypeString,
+				Description: "The ID of the approval request.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: handlers.NewReadApprovalRequestHandler(m),
+		},
+	}
+}
+
+func ApproveRequest(m *stellar.Manager) *framework.Path {
+	return &framework.Path{
+		Pattern:      "requests/" + framework.GenericNameRegex("id") + "/approve",
+		HelpSynopsis: "Approve a pending approval request.",
+		HelpDescription: `
+
+    Record the approval of the calling entity. The transaction is signed once the
+    quorum of the account's approval rule is reached.
+
+    `,
+		Fields: decisionFields(),
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: handlers.NewApproveRequestHandler(m),
+		},
+	}
+}
+
+func RejectRequest(m *stellar.Manager) *framework.Path {
+	return &framework.Path{
+		Pattern:      "requests/" + framework.GenericNameRegex("id") + "/reject",
+		HelpSynopsis: "Reject a pending approval request.",
+		HelpDescription: `
+
+    Record the rejection of the calling entity, which rejects the request.
+
+    `,
+		Fields: decisionFields(),
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: handlers.NewRejectRequestHandler(m),
+		},
+	}
+}
+
+func decisionFields() map[string]*framework.FieldSchema {
+	return map[string]*framework.FieldSchema{
+		"id": {
+			Type:        framework.TypeString,
+			Description: "The ID of the approval request.",
+		},
+		"comment": {
+			Type:        framework.TypeString,
+			Description: "The reason for the decision, recorded with it.",
+		},
+	}
+}
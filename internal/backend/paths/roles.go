@@ -0,0 +1,132 @@
+package paths
+
+import (
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"vault-plugin-stellar-sign/internal/backend/handlers"
+	"vault-plugin-stellar-sign/internal/backend/stellar"
+)
+
+func ListRoles(m *stellar.Manager) *framework.Path {
+	return &framework.Path{
+		Pattern:      "roles/?",
+		HelpSynopsis: "List the roles maintained by the plugin backend.",
+		HelpDescription: `
+
+    LIST - list all roles
+
+    `,
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ListOperation: handlers.NewListRolesHandler(m),
+		},
+	}
+}
+
+func ReadWriteAndDeleteRole(m *stellar.Manager) *framework.Path {
+	return &framework.Path{
+		Pattern:      "roles/" + framework.GenericNameRegex("name"),
+		HelpSynopsis: "Create, get or delete a role by name",
+		HelpDescription: `
+			GET - return the role by name
+			POST - create or replace the role
+			DELETE - deletes the role by name`,
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "The name of the role.",
+			},
+			"accounts": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "The public keys of the stored accounts the role may sign with.",
+			},
+			"networks": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "The networks the role may sign for ('Public', 'Testnet'). Any network when empty.",
+			},
+			"policy": {
+				Type:        framework.TypeString,
+				Description: "The name of a signing policy enforced for the role, in addition to the policies of the account.",
+			},
+			"network": {
+				Type:        framework.TypeString,
+				Description: "The network ephemeral accounts are created on or added as signers on ('Public' or 'Testnet').",
+			},
+			"funding_account": {
+				Type:        framework.TypeString,
+				Description: "The public key of a stored account that creates and funds ephemeral accounts. They are merged back into it on revocation.",
+			},
+			"starting_balance": {
+				Type:        framework.TypeString,
+				Description: "The XLM balance ephemeral accounts are created with. Defaults to 2.",
+			},
+			"parent_account": {
+				Type:        framework.TypeString,
+				Description: "The public key of a stored account that gets ephemeral accounts added as signers. They are removed on revocation.",
+			},
+			"signer_weight": {
+				Type:        framework.TypeInt,
+				Description: "The weight of ephemeral accounts as signers of the parent account.",
+				Default:     1,
+			},
+			"ttl": {
+				Type:        framework.TypeDurationSecond,
+				Description: "The lease TTL of ephemeral accounts. Defaults to one hour.",
+			},
+			"max_ttl": {
+				Type:        framework.TypeDurationSecond,
+				Description: "The maximum lease TTL of ephemeral accounts. Defaults to the mount's maximum.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation:   handlers.NewReadRoleHandler(m),
+			logical.UpdateOperation: handlers.NewWriteRoleHandler(m),
+			logical.DeleteOperation: handlers.NewDeleteRoleHandler(m),
+		},
+	}
+}
+
+func SignWithRole(m *stellar.Manager) *framework.Path {
+	return &framework.Path{
+		Pattern:      "roles/" + framework.GenericNameRegex("name") + "/sign",
+		HelpSynopsis: "Sign a provided Stellar transaction envelope through a role.",
+		HelpDescription: `
+
+    Sign a Stellar transaction envelope with one of the accounts of the role, for one
+    of its networks and under its signing policy.
+
+    `,
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "The name of the role.",
+			},
+			"publicKey": {
+				Type:        framework.TypeString,
+				Description: "The public key of the account to sign with, or a muxed account address of it. May be omitted when the role has a single account.",
+			},
+			"transaction": {
+				Type:        framework.TypeString,
+				Description: "The base64 encoded Stellar transaction envelope to sign.",
+			},
+			"network": {
+				Type:        framework.TypeString,
+				Description: "The network for the transaction ('Public' or 'Testnet').",
+			},
+			"idempotency_key": {
+				Type:        framework.TypeString,
+				Description: "Client chosen key identifying the request. Repeating it returns the previously signed transaction instead of signing again. Requires replay protection.",
+			},
+			"submit": {
+				Type:        framework.TypeBool,
+				Description: "Submit the signed transaction to the Horizon configured for the network.",
+			},
+			"async": {
+				Type:        framework.TypeBool,
+				Description: "With submit, return immediately and track the outcome at submissions/<hash>.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: handlers.NewSignWithRoleHandler(m),
+		},
+	}
+}
@@ -0,0 +1,39 @@
+package paths
+
+import (
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"vault-plugin-stellar-sign/internal/backend/handlers"
+	"vault-plugin-stellar-sign/internal/backend/stellar"
+)
+
+func Sequence(m *stellar.Manager) *framework.Path {
+	return &framework.Path{
+		Pattern:      "accounts/" + framework.GenericNameRegex("publicKey") + "/sequence",
+		HelpSynopsis: "Manage the sequence number tracked for a Stellar account.",
+		HelpDescription: `
+
+    GET - return the next sequence number the build endpoint allocates for the account
+    POST - resynchronise the sequence number from Horizon, or set it with next_sequence
+
+    `,
+		Fields: map[string]*framework.FieldSchema{
+			"publicKey": {
+				Type:        framework.TypeString,
+				Description: "The public key of the source account.",
+			},
+			"network": {
+				Type:        framework.TypeString,
+				Description: "The network of the sequence number ('Public' or 'Testnet').",
+			},
+			"next_sequence": {
+				Type:        framework.TypeString,
+				Description: "The next sequence number to allocate. Fetched from Horizon when not provided.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation:   handlers.NewReadSequenceHandler(m),
+			logical.UpdateOperation: handlers.NewSyncSequenceHandler(m),
+		},
+	}
+}
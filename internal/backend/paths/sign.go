@@ -7,6 +7,30 @@ import (
 	"vault-plugin-stellar-sign/internal/backend/stellar"
 )
 
+// AuditNonHMACRequestKeys are the request fields of the signing endpoints that hold no
+// secrets and stay readable in audit logs. Vault takes non-HMAC keys from the mount
+// configuration, so they are applied with the audit_non_hmac_request_keys tune option.
+// Secret keys of imported accounts are not listed and remain HMACed.
+var AuditNonHMACRequestKeys = []string{"publicKey", "network", "idempotency_key", "submit", "async"}
+
+// AuditNonHMACResponseKeys are the response fields of the signing endpoints that hold
+// no secrets, applied with the audit_non_hmac_response_keys tune option. error_code
+// tells denied and failed requests apart from successful ones.
+var AuditNonHMACResponseKeys = []string{"transaction_hash", "source", "sequence", "operation_count", "submission_status", "ledger", "error_code"}
+
+// AuditTuneFlags returns the flags of vault secrets enable and vault secrets tune
+// applying the non-HMAC keys to a mount
+func AuditTuneFlags() []string {
+	flags := make([]string, 0, len(AuditNonHMACRequestKeys)+len(AuditNonHMACResponseKeys))
+	for _, key := range AuditNonHMACRequestKeys {
+		flags = append(flags, "-audit-non-hmac-request-keys="+key)
+	}
+	for _, key := range AuditNonHMACResponseKeys {
+		flags = append(flags, "-audit-non-hmac-response-keys="+key)
+	}
+	return flags
+}
+
 func Sign(m *stellar.Manager) *framework.Path {
 	return &framework.Path{
 		Pattern:      "accounts/" + framework.GenericNameRegex("publicKey") + "/sign",
@@ -19,7 +43,7 @@ func Sign(m *stellar.Manager) *framework.Path {
 		Fields: map[string]*framework.FieldSchema{
 			"publicKey": {
 				Type:        framework.TypeString,
-				Description: "The public key of the account to use for signing.",
+				Description: "The public key of the account to use for signing, or a muxed account address of it.",
 			},
 			"transaction": {
 				Type:        framework.TypeString,
@@ -29,9 +53,23 @@ func Sign(m *stellar.Manager) *framework.Path {
 				Type:        framework.TypeString,
 				Description: "The network for the transaction ('Public' or 'Testnet').",
 			},
+			"idempotency_key": {
+				Type:        framework.TypeString,
+				Description: "Client chosen key identifying the request. Repeating it returns the previously signed transaction instead of signing again. Requires replay protection.",
+			},
+			"submit": {
+				Type:        framework.TypeBool,
+				Description: "Submit the signed transaction to the Horizon configured for the network.",
+			},
+			"async": {
+				Type:        framework.TypeBool,
+				Description: "With submit, return immediately and track the outcome at submissions/<hash>.",
+			},
 		},
 		ExistenceCheck: m.AccountExistenceCheck,
 		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: handlers.NewSignTxHandler(m),
+			// Signing with an account that does not exist arrives as CreateOperation and fails
 			logical.CreateOperation: handlers.NewSignTxHandler(m),
 		},
 	}
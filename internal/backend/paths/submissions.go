@@ -0,0 +1,33 @@
+package paths
+
+import (
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"vault-plugin-stellar-sign/internal/backend/handlers"
+	"vault-plugin-stellar-sign/internal/backend/stellar"
+)
+
+func Submission(m *stellar.Manager) *framework.Path {
+	return &framework.Path{
+		Pattern:      "accounts/" + framework.GenericNameRegex("publicKey") + "/submissions/(?P<hash>[0-9a-f]{64})",
+		HelpSynopsis: "Get the status of a transaction submitted to Horizon by the plugin.",
+		HelpDescription: `
+
+    GET - return the status of a transaction the account submitted, by transaction hash
+
+    `,
+		Fields: map[string]*framework.FieldSchema{
+			"publicKey": {
+				Type:        framework.TypeString,
+				Description: "The public key of the account that submitted the transaction.",
+			},
+			"hash": {
+				Type:        framework.TypeString,
+				Description: "The hex encoded transaction hash.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: handlers.NewReadSubmissionHandler(m),
+		},
+	}
+}
@@ -0,0 +1,808 @@
+package stellar
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stellar/go/txnbuild"
+	"math/big"
+	"time"
+)
+
+const (
+	defaultApprovalTTL int64 = 86400
+
+	ApprovalStatusPending  = "pending"
+	ApprovalStatusApproved = "approved"
+	ApprovalStatusRejected = "rejected"
+	ApprovalStatusExpired  = "expired"
+	// ApprovalStatusFailed is set when the quorum was reached but signing was refused,
+	// e.g. because a policy changed while the request was pending
+	ApprovalStatusFailed = "failed"
+)
+
+// ApprovalRule requires a quorum of approvers before an account signs. Approvers are
+// identity entities listed by ID or members of the approver groups. With distinct
+// groups, each approval must come from a different approver group.
+type ApprovalRule struct {
+	Approvers      []string `json:"approvers,omitempty"`
+	ApproverGroups []string `json:"approver_groups,omitempty"`
+	Quorum         int      `json:"quorum"`
+	DistinctGroups bool     `json:"distinct_groups,omitempty"`
+	// TTL is how long, in seconds, a request stays pending before it expires
+	TTL int64 `json:"ttl,omitempty"`
+	// Also lists the rules of other approvers that must be satisfied as well, e.g. when
+	// an approval tier names its own approvers
+	Also []ApprovalRule `json:"also,omitempty"`
+}
+
+// ApprovalDecision records an approver's approval or rejection of a request
+type ApprovalDecision struct {
+	Entity string `json:"entity"`
+	Group  string `json:"group,omitempty"`
+	// Groups are the approver groups of the request the entity was a member of
+	Groups    []string  `json:"groups,omitempty"`
+	Approved  bool      `json:"approved"`
+	Comment   string    `json:"comment,omitempty"`
+	DecidedAt time.Time `json:"decided_at"`
+}
+
+// ApprovalRequest is a transaction waiting for the quorum of the account's approval
+// rule. The rule is copied when the request is made so that later changes to the
+// account do not affect pending requests.
+type ApprovalRequest struct {
+	ID        string `json:"id"`
+	PublicKey string `json:"public_key"`
+	// MuxID is the mux ID the requester addressed the account with
+	MuxID       *uint64             `json:"mux_id,omitempty"`
+	Network     string              `json:"network"`
+	Transaction string              `json:"transaction"`
+	TxHash      string              `json:"tx_hash"`
+	Summary     *TransactionSummary `json:"summary"`
+	Submit      bool                `json:"submit,omitempty"`
+	Comment     string              `json:"comment,omitempty"`
+	RequestedBy string              `json:"requested_by,omitempty"`
+	Rule        ApprovalRule        `json:"rule"`
+	Status      string              `json:"status"`
+	Decisions   []ApprovalDecision  `json:"decisions,omitempty"`
+	CreatedAt   time.Time           `json:"created_at"`
+	ExpiresAt   time.Time           `json:"expires_at"`
+	// SignedTransaction is released to the requester once the quorum is reached
+	SignedTransaction string `json:"signed_transaction,omitempty"`
+	Error             string `json:"error,omitempty"`
+}
+
+func approvalRequestPath(id string) string {
+	return fmt.Sprintf("stellar/requests/%s", id)
+}
+
+// approvalRuleFromFieldData updates the approval rule of an account with the approval
+// fields that were provided. With a quorum of zero, the approvers are only used by the
+// approval tiers of policies.
+func approvalRuleFromFieldData(rule *ApprovalRule, data *framework.FieldData) (*ApprovalRule, error) {
+	updated := ApprovalRule{}
+	if rule != nil {
+		updated = *rule
+	}
+	changed := false
+	// Repeated approvers would count towards the quorum as one
+	if approvers, ok := data.GetOk("approvers"); ok {
+		updated.Approvers, changed = uniqueStrings(approvers.([]string)), true
+	}
+	if groups, ok := data.GetOk("approver_groups"); ok {
+		updated.ApproverGroups, changed = uniqueStrings(groups.([]string)), true
+	}
+	if quorum, ok := data.GetOk("approval_quorum"); ok {
+		updated.Quorum, changed = quorum.(int), true
+	}
+	if distinct, ok := data.GetOk("approval_distinct_groups"); ok {
+		updated.DistinctGroups, changed = distinct.(bool), true
+	}
+	if ttl, ok := data.GetOk("approval_ttl"); ok {
+		updated.TTL, changed = int64(ttl.(int)), true
+	}
+	if !changed {
+		return rule, nil
+	}
+
+	switch {
+	case updated.Quorum < 0:
+		return nil, invalidRequest("approval_quorum must not be negative")
+	case len(updated.Approvers) == 0 && len(updated.ApproverGroups) == 0 && updated.Quorum == 0:
+		return nil, nil
+	case updated.TTL < 0:
+		return nil, invalidRequest("approval_ttl must not be negative")
+	case updated.DistinctGroups && updated.Quorum > len(updated.ApproverGroups):
+		return nil, invalidRequest("approval_quorum cannot exceed the number of approver_groups when approvals must come from distinct groups")
+	case len(updated.Approvers) == 0 && len(updated.ApproverGroups) == 0:
+		return nil, invalidRequest("approvers or approver_groups must be provided with approval_quorum")
+	case len(updated.ApproverGroups) == 0 && updated.Quorum > len(updated.Approvers):
+		return nil, invalidRequest("approval_quorum cannot exceed the number of approvers")
+	}
+	return &updated, nil
+}
+
+func (r *ApprovalRule) required() bool {
+	return r != nil && r.Quorum > 0
+}
+
+func (r *ApprovalRule) ttl() time.Duration {
+	if r.TTL == 0 {
+		return time.Duration(defaultApprovalTTL) * time.Second
+	}
+	return time.Duration(r.TTL) * time.Second
+}
+
+// approverGroups returns the approver groups of the rule the caller is a member of
+func (r *ApprovalRule) approverGroups(c *caller) []string {
+	var groups []string
+	for _, rule := range r.rules() {
+		for _, group := range rule.ApproverGroups {
+			if c.inGroup(group) != "" && !containsString(groups, group) {
+				groups = append(groups, group)
+			}
+		}
+	}
+	return groups
+}
+
+// rules returns the rule followed by the rules that must also be satisfied
+func (r *ApprovalRule) rules() []*ApprovalRule {
+	rules := []*ApprovalRule{r}
+	for i := range r.Also {
+		rules = append(rules, &r.Also[i])
+	}
+	return rules
+}
+
+// isApprover reports whether the caller is an approver of the rule or of a rule that
+// must also be satisfied
+func (r *ApprovalRule) isApprover(c *caller) bool {
+	for _, rule := range r.rules() {
+		if c.entityID != "" && containsString(rule.Approvers, c.entityID) {
+			return true
+		}
+	}
+	return len(r.approverGroups(c)) > 0
+}
+
+// require adds the approvals of another rule. The quorum of a rule with the same
+// approvers is raised, the rule of other approvers must be satisfied as well.
+func (r *ApprovalRule) require(other *ApprovalRule) {
+	for _, rule := range r.rules() {
+		if rule.sameApprovers(other) {
+			if other.Quorum > rule.Quorum {
+				rule.Quorum = other.Quorum
+			}
+			return
+		}
+	}
+	additional := *other
+	additional.TTL, additional.Also = 0, nil
+	r.Also = append(r.Also, additional)
+}
+
+func (r *ApprovalRule) sameApprovers(other *ApprovalRule) bool {
+	return r.DistinctGroups == other.DistinctGroups &&
+		sameStrings(r.Approvers, other.Approvers) && sameStrings(r.ApproverGroups, other.ApproverGroups)
+}
+
+// approvals counts the approvals of the decisions towards the quorum of this rule only
+func (r *ApprovalRule) approvals(decisions []ApprovalDecision) int {
+	count := 0
+	var counted []string
+	for _, decision := range decisions {
+		if !decision.Approved {
+			continue
+		}
+		groups := decision.Groups
+		if len(groups) == 0 && decision.Group != "" {
+			groups = []string{decision.Group}
+		}
+		if !r.DistinctGroups {
+			if containsString(r.Approvers, decision.Entity) || containsAny(r.ApproverGroups, groups) {
+				count++
+			}
+			continue
+		}
+		// Each approval counts for one of the approver's groups not counted yet
+		for _, group := range groups {
+			if containsString(r.ApproverGroups, group) && !containsString(counted, group) {
+				counted = append(counted, group)
+				break
+			}
+		}
+	}
+	if r.DistinctGroups {
+		return len(counted)
+	}
+	return count
+}
+
+// satisfied reports whether the decisions reach the quorum of every rule
+func (r *ApprovalRule) satisfied(decisions []ApprovalDecision) bool {
+	for _, rule := range r.rules() {
+		if rule.approvals(decisions) < rule.Quorum {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *ApprovalRule) responseData() map[string]interface{} {
+	approvers, groups := r.Approvers, r.ApproverGroups
+	if approvers == nil {
+		approvers = []string{}
+	}
+	if groups == nil {
+		groups = []string{}
+	}
+	respData := map[string]interface{}{
+		"approvers":       approvers,
+		"approver_groups": groups,
+		"quorum":          r.Quorum,
+		"distinct_groups": r.DistinctGroups,
+		"ttl":             int64(r.ttl().Seconds()),
+	}
+	if len(r.Also) > 0 {
+		also := make([]map[string]interface{}, 0, len(r.Also))
+		for i := range r.Also {
+			ruleData := r.Also[i].responseData()
+			delete(ruleData, "ttl")
+			also = append(also, ruleData)
+		}
+		respData["also"] = also
+	}
+	return respData
+}
+
+// CreateApprovalRequest stores a transaction that requires approval as pending. The
+// transaction is checked against the source binding and the signing policies right
+// away, and again when the quorum is reached.
+func (m *Manager) CreateApprovalRequest(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	address := data.Get("publicKey").(string)
+	if address == "" {
+		return nil, invalidRequest("publicKey must be provided")
+	}
+	publicKey, muxID, err := resolveAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	networkName := data.Get("network").(string)
+	networkPassphrase, ok := networkPassphrases[networkName]
+	if !ok {
+		return nil, invalidNetwork(networkName)
+	}
+	sr := &signRequest{
+		publicKey:         publicKey,
+		muxID:             muxID,
+		network:           networkName,
+		txEnvelopeBase64:  data.Get("transaction").(string),
+		networkPassphrase: networkPassphrase,
+		submit:            data.Get("submit").(bool),
+	}
+	if sr.txEnvelopeBase64 == "" {
+		return nil, invalidRequest("transaction must be provided")
+	}
+
+	account, _, err := m.retrieveAccessibleAccount(ctx, req, sr.publicKey)
+	if err != nil {
+		m.logger.Error("Error retrieving account", "error", err)
+		return nil, fmt.Errorf("error retrieving account: %w", err)
+	}
+	if account == nil {
+		return nil, notFound(ErrCodeAccountNotFound, "account not found")
+	}
+	if !account.Approval.required() {
+		return nil, invalidRequest("account %s does not require approval, sign the transaction directly", account.PublicKey)
+	}
+
+	tx, err := m.decodeTransaction(sr.txEnvelopeBase64)
+	if err != nil {
+		return nil, err
+	}
+	config, err := m.retrieveConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if err = m.checkTransaction(ctx, req.Storage, config, account, tx, sr); err != nil {
+		return nil, err
+	}
+	rule, err := m.requiredApproval(ctx, req.Storage, config, account, tx, sr)
+	if err != nil {
+		return nil, err
+	}
+	if !rule.required() {
+		return nil, invalidRequest("the transaction does not require approval, sign it directly")
+	}
+
+	sr.requester = req.EntityID
+	return m.queueApproval(ctx, req.Storage, account, tx, sr, rule, data.Get("comment").(string))
+}
+
+// requiredApproval returns the approval rule a transaction must satisfy: the strictest
+// of the account's approval rule and the approval tiers of the signing policies the
+// transaction value falls into. It is nil when the transaction can be signed right away.
+func (m *Manager) requiredApproval(ctx context.Context, storage logical.Storage, config *Config, account *Account,
+	tx *txnbuild.Transaction, sr *signRequest) (*ApprovalRule, error) {
+	var rule *ApprovalRule
+	if account.Approval.required() {
+		rule = account.Approval
+	}
+
+	var value *big.Rat
+	valued := false
+	for _, policyName := range account.signingPolicies(config, tx, sr) {
+		if policyName == "" {
+			continue
+		}
+		policy, err := m.retrievePolicy(ctx, storage, policyName)
+		if err != nil {
+			return nil, err
+		}
+		if policy == nil || len(policy.ApprovalTiers) == 0 {
+			continue
+		}
+		if !valued {
+			value, valued = transactionValue(summarizeTransaction(tx), config.Prices), true
+		}
+		tier, err := policy.approvalTier(value)
+		if err != nil {
+			m.logger.Warn("Transaction denied by signing policy", "publicKey", account.PublicKey, "error", err)
+			return nil, err
+		}
+		if tier.Approvals == 0 {
+			continue
+		}
+		tierRule, err := tier.rule(policy.Name, account.Approval)
+		if err != nil {
+			return nil, err
+		}
+		if rule == nil {
+			rule = tierRule
+			continue
+		}
+		if rule == account.Approval {
+			// The account rule is shared with the account, the request gets its own copy
+			copied := *rule
+			copied.Also = append([]ApprovalRule(nil), rule.Also...)
+			rule = &copied
+		}
+		rule.require(tierRule)
+	}
+	return rule, nil
+}
+
+// queueApproval stores the transaction as an approval request pending the quorum of
+// the rule
+func (m *Manager) queueApproval(ctx context.Context, storage logical.Storage, account *Account, tx *txnbuild.Transaction,
+	sr *signRequest, rule *ApprovalRule, comment string) (*logical.Response, error) {
+	txHash, err := tx.HashHex(sr.networkPassphrase)
+	if err != nil {
+		return nil, fmt.Errorf("error hashing transaction: %s", err)
+	}
+	txEnvelopeBase64, err := tx.Base64()
+	if err != nil {
+		return nil, fmt.Errorf("error encoding transaction: %s", err)
+	}
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate approval request ID: %s", err)
+	}
+
+	now := time.Now()
+	request := &ApprovalRequest{
+		ID:          id,
+		PublicKey:   account.PublicKey,
+		MuxID:       sr.muxID,
+		Network:     sr.network,
+		Transaction: txEnvelopeBase64,
+		TxHash:      txHash,
+		Summary:     summarizeTransaction(tx),
+		Submit:      sr.submit,
+		Comment:     comment,
+		RequestedBy: sr.requester,
+		Rule:        *rule,
+		Status:      ApprovalStatusPending,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(rule.ttl()),
+	}
+	if err = m.saveApprovalRequest(ctx, storage, request); err != nil {
+		return nil, err
+	}
+	m.logger.Info("Transaction awaiting approval", "id", id, "publicKey", account.PublicKey, "txHash", txHash, "quorum", rule.Quorum)
+
+	return &logical.Response{
+		Data: request.responseData(false),
+	}, nil
+}
+
+// ListApprovalRequests lists the requests visible to the caller: with ownership
+// enforced, those of the accounts it may use, its own and those it may decide on.
+func (m *Manager) ListApprovalRequests(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	ids, err := req.Storage.List(ctx, "stellar/requests/")
+	if err != nil {
+		m.logger.Error("Failed to list approval requests", "error", err)
+		return nil, fmt.Errorf("failed to list approval requests: %s", err)
+	}
+
+	visible := []string{}
+	keyInfo := map[string]interface{}{}
+	for _, id := range ids {
+		request, err := m.retrieveApprovalRequest(ctx, req.Storage, id)
+		if err != nil {
+			return nil, err
+		}
+		if request == nil {
+			continue
+		}
+		ok, err := m.canSeeApprovalRequest(ctx, req, request)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			visible = append(visible, id)
+			keyInfo[id] = map[string]interface{}{
+				"public_key":   request.PublicKey,
+				"network":      request.Network,
+				"status":       request.currentStatus(time.Now()),
+				"requested_by": request.RequestedBy,
+				"expires_at":   request.ExpiresAt.Format(time.RFC3339),
+			}
+		}
+	}
+
+	return logical.ListResponseWithInfo(visible, keyInfo), nil
+}
+
+// ReadApprovalRequest returns a request with its summary and decisions. The signed
+// transaction is only returned to the requester and to admins.
+func (m *Manager) ReadApprovalRequest(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	request, err := m.retrieveApprovalRequest(ctx, req.Storage, data.Get("id").(string))
+	if err != nil {
+		return nil, err
+	}
+	if request == nil {
+		return nil, notFound(ErrCodeApprovalRequestNotFound, "approval request not found")
+	}
+	visible, err := m.canSeeApprovalRequest(ctx, req, request)
+	if err != nil {
+		return nil, err
+	}
+	if !visible {
+		return nil, notFound(ErrCodeApprovalRequestNotFound, "approval request not found")
+	}
+	request.Status = request.currentStatus(time.Now())
+
+	release, err := m.isRequester(ctx, req, request)
+	if err != nil {
+		return nil, err
+	}
+	return &logical.Response{
+		Data: request.responseData(release),
+	}, nil
+}
+
+func (m *Manager) ApproveRequest(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	return m.decideApprovalRequest(ctx, req, data, true)
+}
+
+func (m *Manager) RejectRequest(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	return m.decideApprovalRequest(ctx, req, data, false)
+}
+
+// decideApprovalRequest records the decision of an approver. A single rejection
+// rejects the request, reaching the quorum signs the transaction.
+func (m *Manager) decideApprovalRequest(ctx context.Context, req *logical.Request, data *framework.FieldData, approve bool) (*logical.Response, error) {
+	m.approvalLock.Lock()
+	defer m.approvalLock.Unlock()
+
+	request, err := m.retrieveApprovalRequest(ctx, req.Storage, data.Get("id").(string))
+	if err != nil {
+		return nil, err
+	}
+	if request == nil {
+		return nil, notFound(ErrCodeApprovalRequestNotFound, "approval request not found")
+	}
+	if status := request.currentStatus(time.Now()); status != ApprovalStatusPending {
+		return nil, conflict(ErrCodeConflict, "approval request is %s", status)
+	}
+
+	if req.EntityID == "" {
+		return nil, forbidden(ErrCodePermissionDenied, "decisions must be made with a token bound to an identity entity")
+	}
+	if req.EntityID == request.RequestedBy {
+		return nil, forbidden(ErrCodePermissionDenied, "the requester cannot decide on their own request")
+	}
+	for _, decision := range request.Decisions {
+		if decision.Entity == req.EntityID {
+			return nil, conflict(ErrCodeConflict, "entity %s already decided on this request", req.EntityID)
+		}
+	}
+	config, err := m.retrieveConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	c, err := m.identify(req, config)
+	if err != nil {
+		return nil, err
+	}
+	if !request.Rule.isApprover(c) {
+		return nil, forbidden(ErrCodePermissionDenied, "entity %s is not an approver of this request", req.EntityID)
+	}
+	groups := request.Rule.approverGroups(c)
+
+	decision := ApprovalDecision{
+		Entity:    req.EntityID,
+		Groups:    groups,
+		Approved:  approve,
+		Comment:   data.Get("comment").(string),
+		DecidedAt: time.Now(),
+	}
+	if len(groups) > 0 {
+		decision.Group = groups[0]
+	}
+	if approve && !request.counts(decision) {
+		return nil, conflict(ErrCodeConflict, "approvals must come from distinct approver groups, the groups of entity %s already approved", req.EntityID)
+	}
+	request.Decisions = append(request.Decisions, decision)
+
+	switch {
+	case !approve:
+		request.Status = ApprovalStatusRejected
+		m.logger.Info("Approval request rejected", "id", request.ID, "entity", req.EntityID)
+	case request.Rule.satisfied(request.Decisions):
+		m.releaseApprovalRequest(ctx, req.Storage, request)
+	}
+	if err = m.saveApprovalRequest(ctx, req.Storage, request); err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: request.responseData(false),
+	}, nil
+}
+
+// releaseApprovalRequest signs the transaction of a request that reached its quorum.
+// Source binding, the signing policies and replay protection apply as for any
+// signature; when they refuse, the request fails with the reason.
+func (m *Manager) releaseApprovalRequest(ctx context.Context, storage logical.Storage, request *ApprovalRequest) {
+	signed, err := m.signApprovedTransaction(ctx, storage, request)
+	if err != nil {
+		m.logger.Warn("Signing the approved transaction failed", "id", request.ID, "error", err)
+		request.Status = ApprovalStatusFailed
+		request.Error = err.Error()
+		return
+	}
+	m.logger.Info("Approval request reached its quorum", "id", request.ID, "txHash", request.TxHash)
+	request.Status = ApprovalStatusApproved
+	request.SignedTransaction = signed
+}
+
+func (m *Manager) signApprovedTransaction(ctx context.Context, storage logical.Storage, request *ApprovalRequest) (string, error) {
+	account, err := m.retrieveRequesterAccount(ctx, storage, request)
+	if err != nil {
+		return "", err
+	}
+	tx, err := m.decodeTransaction(request.Transaction)
+	if err != nil {
+		return "", err
+	}
+
+	sr := &signRequest{
+		publicKey:         account.PublicKey,
+		muxID:             request.MuxID,
+		network:           request.Network,
+		txEnvelopeBase64:  request.Transaction,
+		networkPassphrase: networkPassphrases[request.Network],
+		submit:            request.Submit,
+		async:             true,
+		approvalRequest:   request.ID,
+	}
+	resp, err := m.signTransaction(ctx, storage, account, tx, sr)
+	if err != nil {
+		return "", err
+	}
+	return resp.Data["signed_transaction"].(string), nil
+}
+
+// ExpireApprovalRequests marks the pending requests whose TTL has passed as expired.
+// The requests and their decisions are kept as a record.
+func (m *Manager) ExpireApprovalRequests(ctx context.Context, req *logical.Request) error {
+	m.approvalLock.Lock()
+	defer m.approvalLock.Unlock()
+
+	ids, err := req.Storage.List(ctx, "stellar/requests/")
+	if err != nil {
+		return fmt.Errorf("failed to list approval requests: %s", err)
+	}
+	now := time.Now()
+	for _, id := range ids {
+		request, err := m.retrieveApprovalRequest(ctx, req.Storage, id)
+		if err != nil {
+			return err
+		}
+		if request == nil || request.Status != ApprovalStatusPending || request.currentStatus(now) != ApprovalStatusExpired {
+			continue
+		}
+		request.Status = ApprovalStatusExpired
+		if err = m.saveApprovalRequest(ctx, req.Storage, request); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// retrieveRequesterAccount retrieves the account of the request on behalf of the
+// requester, which must still be allowed to use it when ownership is enforced. Requests
+// without a requester entity were made by admins.
+func (m *Manager) retrieveRequesterAccount(ctx context.Context, storage logical.Storage, request *ApprovalRequest) (*Account, error) {
+	account, err := m.retrieveAccount(ctx, storage, request.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		return nil, notFound(ErrCodeAccountNotFound, "account %s no longer exists", request.PublicKey)
+	}
+	config, err := m.retrieveConfig(ctx, storage)
+	if err != nil {
+		return nil, err
+	}
+	if !config.Ownership || request.RequestedBy == "" {
+		return account, nil
+	}
+	c, err := m.identifyEntity(request.RequestedBy, config)
+	if err != nil {
+		return nil, err
+	}
+	if c.access(account) == accessNone {
+		return nil, forbidden(ErrCodePermissionDenied, "the requester may no longer use account %s", account.PublicKey)
+	}
+	return account, nil
+}
+
+// canSeeApprovalRequest reports whether the caller may use the account of the request,
+// made the request or is one of its approvers
+func (m *Manager) canSeeApprovalRequest(ctx context.Context, req *logical.Request, request *ApprovalRequest) (bool, error) {
+	if req.EntityID != "" && req.EntityID == request.RequestedBy {
+		return true, nil
+	}
+	config, err := m.retrieveConfig(ctx, req.Storage)
+	if err != nil {
+		return false, err
+	}
+	if !config.Ownership {
+		return true, nil
+	}
+	c, err := m.identify(req, config)
+	if err != nil {
+		return false, err
+	}
+	if request.Rule.isApprover(c) {
+		return true, nil
+	}
+	visible, err := m.filterAccessibleAccounts(ctx, req, []string{request.PublicKey})
+	if err != nil {
+		return false, err
+	}
+	return len(visible) > 0, nil
+}
+
+// isRequester reports whether the caller made the request, or is an admin
+func (m *Manager) isRequester(ctx context.Context, req *logical.Request, request *ApprovalRequest) (bool, error) {
+	if req.EntityID != "" && req.EntityID == request.RequestedBy {
+		return true, nil
+	}
+	config, err := m.retrieveConfig(ctx, req.Storage)
+	if err != nil {
+		return false, err
+	}
+	c, err := m.identify(req, config)
+	if err != nil {
+		return false, err
+	}
+	return c.admin, nil
+}
+
+func (m *Manager) saveApprovalRequest(ctx context.Context, storage logical.Storage, request *ApprovalRequest) error {
+	entry, err := logical.StorageEntryJSON(approvalRequestPath(request.ID), request)
+	if err != nil {
+		return err
+	}
+	if err = storage.Put(ctx, entry); err != nil {
+		m.logger.Error("Failed to save the approval request", "id", request.ID, "error", err)
+		return err
+	}
+	return nil
+}
+
+func (m *Manager) retrieveApprovalRequest(ctx context.Context, storage logical.Storage, id string) (*ApprovalRequest, error) {
+	entry, err := storage.Get(ctx, approvalRequestPath(id))
+	if err != nil {
+		m.logger.Error("Failed to retrieve the approval request", "id", id, "error", err)
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+	var request ApprovalRequest
+	if err = entry.DecodeJSON(&request); err != nil {
+		return nil, fmt.Errorf("failed to decode approval request %q: %s", id, err)
+	}
+	return &request, nil
+}
+
+// currentStatus accounts for requests that expired since the last periodic run
+func (r *ApprovalRequest) currentStatus(now time.Time) string {
+	if r.Status == ApprovalStatusPending && now.After(r.ExpiresAt) {
+		return ApprovalStatusExpired
+	}
+	return r.Status
+}
+
+// approvals counts the approvals towards the quorum of the request's rule
+func (r *ApprovalRequest) approvals() int {
+	return r.Rule.approvals(r.Decisions)
+}
+
+// counts reports whether an approval adds to the approvals of one of the rules, it
+// does not when the approver's groups were all counted already
+func (r *ApprovalRequest) counts(decision ApprovalDecision) bool {
+	decisions := append(append([]ApprovalDecision(nil), r.Decisions...), decision)
+	for _, rule := range r.Rule.rules() {
+		if rule.approvals(decisions) > rule.approvals(r.Decisions) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *ApprovalRequest) responseData(release bool) map[string]interface{} {
+	decisions := make([]map[string]interface{}, 0, len(r.Decisions))
+	for _, decision := range r.Decisions {
+		decisionData := map[string]interface{}{
+			"entity":     decision.Entity,
+			"approved":   decision.Approved,
+			"decided_at": decision.DecidedAt.Format(time.RFC3339),
+		}
+		if decision.Group != "" {
+			decisionData["group"] = decision.Group
+		}
+		if decision.Comment != "" {
+			decisionData["comment"] = decision.Comment
+		}
+		decisions = append(decisions, decisionData)
+	}
+
+	respData := map[string]interface{}{
+		"id":               r.ID,
+		"public_key":       r.PublicKey,
+		"network":          r.Network,
+		"transaction":      r.Transaction,
+		"transaction_hash": r.TxHash,
+		"summary":          r.Summary.responseData(),
+		"requested_by":     r.RequestedBy,
+		"status":           r.Status,
+		"approvals":        r.approvals(),
+		"quorum":           r.Rule.Quorum,
+		"rule":             r.Rule.responseData(),
+		"decisions":        decisions,
+		"created_at":       r.CreatedAt.Format(time.RFC3339),
+		"expires_at":       r.ExpiresAt.Format(time.RFC3339),
+	}
+	if r.MuxID != nil {
+		respData["mux_id"] = *r.MuxID
+	}
+	if r.Comment != "" {
+		respData["comment"] = r.Comment
+	}
+	if r.Error != "" {
+		respData["error"] = r.Error
+	}
+	if release && r.SignedTransaction != "" {
+		respData["signed_transaction"] = r.SignedTransaction
+	}
+	return respData
+}
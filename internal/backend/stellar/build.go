@@ -0,0 +1,434 @@
+package stellar
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stellar/go/txnbuild"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultBuildTimeout = 300
+
+// OperationSpec is the JSON description of an operation to build. Only the fields
+// relevant to the operation type are used.
+type OperationSpec struct {
+	Type   string `json:"type"`
+	Source string `json:"source,omitempty"`
+
+	Destination string `json:"destination,omitempty"`
+	Asset       string `json:"asset,omitempty"`
+	Amount      string `json:"amount,omitempty"`
+
+	SendAsset  string   `json:"send_asset,omitempty"`
+	SendAmount string   `json:"send_amount,omitempty"`
+	SendMax    string   `json:"send_max,omitempty"`
+	DestAsset  string   `json:"dest_asset,omitempty"`
+	DestAmount string   `json:"dest_amount,omitempty"`
+	DestMin    string   `json:"dest_min,omitempty"`
+	Path       []string `json:"path,omitempty"`
+
+	Limit           string `json:"limit,omitempty"`
+	StartingBalance string `json:"starting_balance,omitempty"`
+
+	Name  string  `json:"name,omitempty"`
+	Value *string `json:"value,omitempty"`
+
+	HomeDomain    *string `json:"home_domain,omitempty"`
+	MasterWeight  *uint8  `json:"master_weight,omitempty"`
+	LowThreshold  *uint8  `json:"low_threshold,omitempty"`
+	MedThreshold  *uint8  `json:"med_threshold,omitempty"`
+	HighThreshold *uint8  `json:"high_threshold,omitempty"`
+	SignerKey     string  `json:"signer_key,omitempty"`
+	SignerWeight  *uint8  `json:"signer_weight,omitempty"`
+
+	Claimants []string `json:"claimants,omitempty"`
+	BalanceID string   `json:"balance_id,omitempty"`
+}
+
+// operationBuilders maps the supported operation types to their builder
+var operationBuilders = map[string]func(spec OperationSpec) (txnbuild.Operation, error){
+	"payment": func(spec OperationSpec) (txnbuild.Operation, error) {
+		asset, err := parseAsset(spec.Asset)
+		if err != nil {
+			return nil, err
+		}
+		return &txnbuild.Payment{Destination: spec.Destination, Amount: spec.Amount, Asset: asset, SourceAccount: spec.Source}, nil
+	},
+	"path_payment_strict_receive": func(spec OperationSpec) (txnbuild.Operation, error) {
+		sendAsset, destAsset, path, err := parsePathPaymentAssets(spec)
+		if err != nil {
+			return nil, err
+		}
+		return &txnbuild.PathPaymentStrictReceive{SendAsset: sendAsset, SendMax: spec.SendMax, Destination: spec.Destination,
+			DestAsset: destAsset, DestAmount: spec.DestAmount, Path: path, SourceAccount: spec.Source}, nil
+	},
+	"path_payment_strict_send": func(spec OperationSpec) (txnbuild.Operation, error) {
+		sendAsset, destAsset, path, err := parsePathPaymentAssets(spec)
+		if err != nil {
+			return nil, err
+		}
+		return &txnbuild.PathPaymentStrictSend{SendAsset: sendAsset, SendAmount: spec.SendAmount, Destination: spec.Destination,
+			DestAsset: destAsset, DestMin: spec.DestMin, Path: path, SourceAccount: spec.Source}, nil
+	},
+	"change_trust": func(spec OperationSpec) (txnbuild.Operation, error) {
+		asset, err := parseAsset(spec.Asset)
+		if err != nil {
+			return nil, err
+		}
+		line, err := asset.ToChangeTrustAsset()
+		if err != nil {
+			return nil, err
+		}
+		limit := spec.Limit
+		if limit == "" {
+			limit = txnbuild.MaxTrustlineLimit
+		}
+		return &txnbuild.ChangeTrust{Line: line, Limit: limit, SourceAccount: spec.Source}, nil
+	},
+	"create_account": func(spec OperationSpec) (txnbuild.Operation, error) {
+		return &txnbuild.CreateAccount{Destination: spec.Destination, Amount: spec.StartingBalance, SourceAccount: spec.Source}, nil
+	},
+	"begin_sponsoring_future_reserves": func(spec OperationSpec) (txnbuild.Operation, error) {
+		return &txnbuild.BeginSponsoringFutureReserves{SponsoredID: spec.Destination, SourceAccount: spec.Source}, nil
+	},
+	"end_sponsoring_future_reserves": func(spec OperationSpec) (txnbuild.Operation, error) {
+		return &txnbuild.EndSponsoringFutureReserves{SourceAccount: spec.Source}, nil
+	},
+	"manage_data": func(spec OperationSpec) (txnbuild.Operation, error) {
+		op := &txnbuild.ManageData{Name: spec.Name, SourceAccount: spec.Source}
+		if spec.Value != nil {
+			op.Value = []byte(*spec.Value)
+		}
+		return op, nil
+	},
+	"set_options": func(spec OperationSpec) (txnbuild.Operation, error) {
+		op := &txnbuild.SetOptions{HomeDomain: spec.HomeDomain, SourceAccount: spec.Source}
+		op.MasterWeight = threshold(spec.MasterWeight)
+		op.LowThreshold = threshold(spec.LowThreshold)
+		op.MediumThreshold = threshold(spec.MedThreshold)
+		op.HighThreshold = threshold(spec.HighThreshold)
+		if spec.SignerKey != "" {
+			if spec.SignerWeight == nil {
+				return nil, invalidRequest("signer_weight is required with signer_key")
+			}
+			op.Signer = &txnbuild.Signer{Address: spec.SignerKey, Weight: txnbuild.Threshold(*spec.SignerWeight)}
+		}
+		return op, nil
+	},
+	"account_merge": func(spec OperationSpec) (txnbuild.Operation, error) {
+		return &txnbuild.AccountMerge{Destination: spec.Destination, SourceAccount: spec.Source}, nil
+	},
+	"create_claimable_balance": func(spec OperationSpec) (txnbuild.Operation, error) {
+		asset, err := parseAsset(spec.Asset)
+		if err != nil {
+			return nil, err
+		}
+		if len(spec.Claimants) == 0 {
+			return nil, invalidRequest("at least one claimant is required")
+		}
+		claimants := make([]txnbuild.Claimant, 0, len(spec.Claimants))
+		for _, claimant := range spec.Claimants {
+			claimants = append(claimants, txnbuild.NewClaimant(claimant, nil))
+		}
+		return &txnbuild.CreateClaimableBalance{Amount: spec.Amount, Asset: asset, Destinations: claimants, SourceAccount: spec.Source}, nil
+	},
+	"claim_claimable_balance": func(spec OperationSpec) (txnbuild.Operation, error) {
+		return &txnbuild.ClaimClaimableBalance{BalanceID: spec.BalanceID, SourceAccount: spec.Source}, nil
+	},
+}
+
+// SupportedOperationTypes returns the operation types the build endpoint accepts
+func SupportedOperationTypes() []string {
+	types := make([]string, 0, len(operationBuilders))
+	for opType := range operationBuilders {
+		types = append(types, opType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+func buildOperations(specs []OperationSpec) ([]txnbuild.Operation, error) {
+	if len(specs) == 0 {
+		return nil, invalidRequest("at least one operation must be provided")
+	}
+	ops := make([]txnbuild.Operation, 0, len(specs))
+	for i, spec := range specs {
+		builder, ok := operationBuilders[spec.Type]
+		if !ok {
+			return nil, invalidRequest("operation %d has unsupported type %q, supported types are: %s",
+				i, spec.Type, strings.Join(SupportedOperationTypes(), ", "))
+		}
+		op, err := builder(spec)
+		if err != nil {
+			return nil, invalidRequest("invalid %s operation %d: %s", spec.Type, i, err)
+		}
+		if err = op.Validate(); err != nil {
+			return nil, invalidRequest("invalid %s operation %d: %s", spec.Type, i, err)
+		}
+		ops = append(ops, op)
+	}
+	return ops, nil
+}
+
+// parseAsset parses "native" or "XLM" for lumens and "CODE:ISSUER" for credit assets
+func parseAsset(asset string) (txnbuild.Asset, error) {
+	if asset == "" {
+		return nil, invalidRequest("asset must be provided")
+	}
+	if asset == "native" || asset == "XLM" {
+		return txnbuild.NativeAsset{}, nil
+	}
+	parts := strings.Split(asset, ":")
+	if len(parts) != 2 {
+		return nil, invalidRequest("invalid asset %q, expected 'native' or 'CODE:ISSUER'", asset)
+	}
+	return txnbuild.CreditAsset{Code: parts[0], Issuer: parts[1]}, nil
+}
+
+func parsePathPaymentAssets(spec OperationSpec) (txnbuild.Asset, txnbuild.Asset, []txnbuild.Asset, error) {
+	sendAsset, err := parseAsset(spec.SendAsset)
+	if err != nil {
+		return nil, nil, nil, invalidRequest("send_asset: %s", err)
+	}
+	destAsset, err := parseAsset(spec.DestAsset)
+	if err != nil {
+		return nil, nil, nil, invalidRequest("dest_asset: %s", err)
+	}
+	path := make([]txnbuild.Asset, 0, len(spec.Path))
+	for _, hop := range spec.Path {
+		asset, err := parseAsset(hop)
+		if err != nil {
+			return nil, nil, nil, invalidRequest("path: %s", err)
+		}
+		path = append(path, asset)
+	}
+	return sendAsset, destAsset, path, nil
+}
+
+func threshold(weight *uint8) *txnbuild.Threshold {
+	if weight == nil {
+		return nil
+	}
+	t := txnbuild.Threshold(*weight)
+	return &t
+}
+
+// BuildTx builds a transaction from a JSON description of its operations, runs it
+// through the same checks as SignTx and returns it signed.
+func (m *Manager) BuildTx(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	sr, err := parseSignRequest(data.Get("publicKey").(string), data)
+	if err != nil {
+		return nil, err
+	}
+
+	account, _, err := m.retrieveAccessibleAccount(ctx, req, sr.publicKey)
+	if err != nil {
+		m.logger.Error("Error retrieving account", "error", err)
+		return nil, fmt.Errorf("error retrieving account: %w", err)
+	}
+	if account == nil {
+		return nil, notFound(ErrCodeAccountNotFound, "account not found")
+	}
+
+	specs, err := operationSpecs(data)
+	if err != nil {
+		return nil, err
+	}
+
+	opts, err := parseBuildOptions(data)
+	if err != nil {
+		return nil, err
+	}
+	if explicit, ok := data.GetOk("sequence"); ok {
+		opts.sequence, err = strconv.ParseInt(explicit.(string), 10, 64)
+		if err != nil || opts.sequence <= 0 {
+			return nil, invalidRequest("invalid sequence: %s", explicit)
+		}
+	}
+
+	source := data.Get("source").(string)
+	if source == "" {
+		source = account.PublicKey
+	}
+	return m.buildTransaction(ctx, req.Storage, account, source, specs, opts, sr)
+}
+
+// buildOptions are the transaction level settings of a built transaction
+type buildOptions struct {
+	memo       txnbuild.Memo
+	timeBounds txnbuild.TimeBounds
+	fee        int64
+	// sequence is allocated from the tracked state when zero
+	sequence int64
+}
+
+func defaultBuildOptions() *buildOptions {
+	return &buildOptions{
+		timeBounds: txnbuild.NewTimeout(defaultBuildTimeout),
+		fee:        txnbuild.MinBaseFee,
+	}
+}
+
+func parseBuildOptions(data *framework.FieldData) (*buildOptions, error) {
+	memo, err := parseMemo(data)
+	if err != nil {
+		return nil, err
+	}
+
+	timeBounds, err := parseTimeBounds(data)
+	if err != nil {
+		return nil, err
+	}
+
+	fee := int64(data.Get("fee").(int))
+	if fee < txnbuild.MinBaseFee {
+		return nil, invalidRequest("fee must be at least %d stroops", txnbuild.MinBaseFee)
+	}
+	return &buildOptions{memo: memo, timeBounds: timeBounds, fee: fee}, nil
+}
+
+func operationSpecs(data *framework.FieldData) ([]OperationSpec, error) {
+	var specs []OperationSpec
+	if err := decodeObjectList(data.Get("operations").([]interface{}), &specs); err != nil {
+		return nil, invalidRequest("invalid operations: %s", err)
+	}
+	return specs, nil
+}
+
+// buildTransaction builds the transaction for the source account with the next
+// sequence number, then checks, signs and optionally submits it like SignTx.
+func (m *Manager) buildTransaction(ctx context.Context, storage logical.Storage, account *Account, source string,
+	specs []OperationSpec, opts *buildOptions, sr *signRequest) (*logical.Response, error) {
+	// A retried build would get a new sequence number and time bounds, so it returns the
+	// transaction built under its idempotency key instead of building another one
+	if resp, err := m.previousBuild(ctx, storage, account, sr); resp != nil || err != nil {
+		return resp, err
+	}
+
+	ops, err := buildOperations(specs)
+	if err != nil {
+		return nil, err
+	}
+
+	sourceAccountID, _, err := resolveAddress(source)
+	if err != nil {
+		return nil, err
+	}
+
+	sequence, allocated, err := m.nextSequence(ctx, storage, sr.network, sourceAccountID, opts.sequence)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := txnbuild.NewTransaction(txnbuild.TransactionParams{
+		SourceAccount: &txnbuild.SimpleAccount{AccountID: source, Sequence: sequence},
+		Operations:    ops,
+		BaseFee:       opts.fee,
+		Memo:          opts.memo,
+		Preconditions: txnbuild.Preconditions{TimeBounds: opts.timeBounds},
+	})
+	if err != nil {
+		if allocated {
+			m.releaseSequence(ctx, storage, sr.network, sourceAccountID, sequence)
+		}
+		return nil, invalidRequest("error building transaction: %s", err)
+	}
+	txHash, err := tx.HashHex(sr.networkPassphrase)
+	if err != nil {
+		return nil, fmt.Errorf("error hashing transaction: %s", err)
+	}
+
+	resp, err := m.signTransaction(ctx, storage, account, tx, sr)
+	if err != nil {
+		// A transaction that was denied before signing leaves its sequence number unused
+		if allocated {
+			if record, _ := m.retrieveHistory(ctx, storage, account.PublicKey, txHash); record == nil {
+				m.releaseSequence(ctx, storage, sr.network, sourceAccountID, sequence)
+			}
+		}
+		return nil, err
+	}
+	resp.Data["transaction_hash"] = txHash
+	resp.Data["sequence"] = strconv.FormatInt(sequence, 10)
+	return resp, nil
+}
+
+// previousBuild returns the transaction the account signed under the idempotency key of
+// the request, or nil if there is none
+func (m *Manager) previousBuild(ctx context.Context, storage logical.Storage, account *Account, sr *signRequest) (*logical.Response, error) {
+	if sr.idempotencyKey == "" {
+		return nil, nil
+	}
+	config, err := m.retrieveConfig(ctx, storage)
+	if err != nil || !config.ReplayProtection {
+		return nil, err
+	}
+	record, err := m.idempotentSignature(ctx, storage, account.PublicKey, sr.idempotencyKey)
+	if err != nil || record == nil {
+		return nil, err
+	}
+	tx, err := m.decodeTransaction(record.SignedTransaction)
+	if err != nil {
+		return nil, err
+	}
+	envelope := tx.ToXDR()
+	source := envelope.SourceAccount()
+	return &logical.Response{Data: map[string]interface{}{
+		"signed_transaction": record.SignedTransaction,
+		"transaction_hash":   record.Hash,
+		"source":             source.Address(),
+		"sequence":           strconv.FormatInt(tx.SequenceNumber(), 10),
+		"operation_count":    len(envelope.Operations()),
+	}}, nil
+}
+
+// nextSequence returns the sequence number of the transaction to build. A number given
+// explicitly moves the tracked state past it, otherwise one is allocated from the
+// tracked state. allocated tells whether it must be released if signing fails.
+func (m *Manager) nextSequence(ctx context.Context, storage logical.Storage, networkName string, source string,
+	explicit int64) (sequence int64, allocated bool, err error) {
+	if explicit != 0 {
+		return explicit, false, m.observeSequence(ctx, storage, networkName, source, explicit)
+	}
+
+	sequence, err = m.allocateSequence(ctx, storage, networkName, source)
+	return sequence, err == nil, err
+}
+
+func parseMemo(data *framework.FieldData) (txnbuild.Memo, error) {
+	memoText, hasText := data.GetOk("memo")
+	memoID, hasID := data.GetOk("memo_id")
+	switch {
+	case hasText && hasID:
+		return nil, invalidRequest("only one of memo and memo_id can be provided")
+	case hasText:
+		return txnbuild.MemoText(memoText.(string)), nil
+	case hasID:
+		id, err := strconv.ParseUint(memoID.(string), 10, 64)
+		if err != nil {
+			return nil, invalidRequest("invalid memo_id: %s", err)
+		}
+		return txnbuild.MemoID(id), nil
+	}
+	return nil, nil
+}
+
+func parseTimeBounds(data *framework.FieldData) (txnbuild.TimeBounds, error) {
+	minTime := int64(data.Get("min_time").(int))
+	if maxTime, ok := data.GetOk("max_time"); ok {
+		if maxTime.(int) <= 0 || int64(maxTime.(int)) < minTime {
+			return txnbuild.TimeBounds{}, invalidRequest("max_time must be positive and after min_time")
+		}
+		return txnbuild.NewTimebounds(minTime, int64(maxTime.(int))), nil
+	}
+	timeout := int64(data.Get("timeout").(int))
+	if timeout <= 0 {
+		timeout = defaultBuildTimeout
+	}
+	return txnbuild.NewTimebounds(minTime, time.Now().Unix()+timeout), nil
+}
@@ -0,0 +1,267 @@
+package stellar
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stellar/go/keypair"
+	"github.com/stellar/go/txnbuild"
+	"strconv"
+	"time"
+)
+
+const (
+	maxBulkAccounts = 1000
+	// maxTransactionOperations is the number of operations a Stellar transaction may hold
+	maxTransactionOperations = 100
+	// maxTransactionSignatures is the number of signatures a transaction envelope may hold
+	maxTransactionSignatures = 20
+)
+
+// BulkCreateAccounts generates several accounts sharing their settings. It optionally
+// adds them to a role and returns the transactions of a funding account creating them
+// on-ledger, for the funding account to sign. Either every account is created or none.
+func (m *Manager) BulkCreateAccounts(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	count := data.Get("count").(int)
+	if count < 1 || count > maxBulkAccounts {
+		return nil, invalidRequest("count must be between 1 and %d", maxBulkAccounts)
+	}
+
+	policyName := data.Get("policy").(string)
+	if err := m.validatePolicyReference(ctx, req.Storage, policyName); err != nil {
+		return nil, err
+	}
+	var allowedSources []string
+	if sources, ok := data.GetOk("allowed_sources"); ok {
+		var err error
+		if allowedSources, err = parseAllowedSources(sources.([]string)); err != nil {
+			return nil, err
+		}
+	}
+	ownerGroup, err := m.ownerGroupID(ctx, req, data.Get("owner_group").(string))
+	if err != nil {
+		return nil, err
+	}
+	template := &Account{
+		Name:        data.Get("name").(string),
+		Policy:      policyName,
+		OwnerEntity: req.EntityID,
+		OwnerGroup:  ownerGroup,
+	}
+	if err = accountLimitsFromFieldData(template, data); err != nil {
+		return nil, err
+	}
+
+	funding, err := bulkFundingFromFieldData(data)
+	if err != nil {
+		return nil, err
+	}
+	if funding != nil {
+		if err = m.checkBulkFunding(ctx, req, funding); err != nil {
+			return nil, err
+		}
+	}
+	role, err := m.retrieveBulkRole(ctx, req, data.Get("role").(string))
+	if err != nil {
+		return nil, err
+	}
+
+	// Everything is validated before the first account is stored
+	pairs := make([]*keypair.Full, 0, count)
+	for i := 0; i < count; i++ {
+		pair, err := keypair.Random()
+		if err != nil {
+			m.logger.Error("Error generating new keypair", "error", err)
+			return nil, fmt.Errorf("error generating new keypair")
+		}
+		pairs = append(pairs, pair)
+	}
+
+	accounts := make([]*Account, 0, count)
+	publicKeys := make([]string, 0, count)
+	for _, pair := range pairs {
+		account := *template
+		account.PublicKey = pair.Address()
+		account.SecretKey = pair.Seed()
+		account.AllowedSources = allowedSources
+		if account.AllowedSources == nil {
+			account.AllowedSources = []string{pair.Address()}
+		}
+		account.CreatedAt = time.Now()
+		if err = m.createAccount(ctx, req.Storage, &account); err != nil {
+			m.logger.Error("Failed to save the new stellar account to storage", "error", err)
+			return nil, m.rollBackAccounts(ctx, req.Storage, accounts, err)
+		}
+		accounts = append(accounts, &account)
+		publicKeys = append(publicKeys, account.PublicKey)
+	}
+
+	respData := map[string]interface{}{
+		"public_keys": publicKeys,
+	}
+	if funding != nil {
+		transactions, err := m.fundingTransactions(ctx, req.Storage, funding, pairs)
+		if err != nil {
+			return nil, m.rollBackAccounts(ctx, req.Storage, accounts, fmt.Errorf("building the funding transactions failed: %w", err))
+		}
+		respData["transactions"] = transactions
+	}
+	// The role is updated last, nothing can fail after it
+	if role != nil {
+		role.Accounts = append(role.Accounts, publicKeys...)
+		if err = m.saveRole(ctx, req.Storage, role); err != nil {
+			return nil, m.rollBackAccounts(ctx, req.Storage, accounts, err)
+		}
+		respData["role"] = role.Name
+	}
+	for _, publicKey := range publicKeys {
+		m.sendEvent(ctx, EventAccountCreate, "public_key", publicKey)
+	}
+	return &logical.Response{
+		Data: respData,
+	}, nil
+}
+
+// retrieveBulkRole returns the role bulk accounts are added to, nil when no role is
+// given. The caller must be able to use every account of the role, as to write it, and
+// other roles are hidden from it.
+func (m *Manager) retrieveBulkRole(ctx context.Context, req *logical.Request, name string) (*Role, error) {
+	if name == "" {
+		return nil, nil
+	}
+	role, err := m.retrieveAccessibleRole(ctx, req, name)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, notFound(ErrCodeRoleNotFound, "role %q does not exist", name)
+	}
+	return role, nil
+}
+
+// bulkFundingFromFieldData returns the funding of bulk accounts, nil when no funding
+// account is given
+func bulkFundingFromFieldData(data *framework.FieldData) (*accountFunding, error) {
+	funding, err := accountFundingFromFieldData(data)
+	if err != nil || funding == nil {
+		return nil, err
+	}
+	funding.timeout = int64(data.Get("timeout").(int))
+	if funding.timeout <= 0 {
+		funding.timeout = defaultBuildTimeout
+	}
+	if explicit, ok := data.GetOk("sequence"); ok {
+		sequence, err := strconv.ParseInt(explicit.(string), 10, 64)
+		if err != nil || sequence <= 0 {
+			return nil, invalidRequest("invalid sequence: %s", explicit)
+		}
+		funding.sequence = sequence
+	}
+	return funding, nil
+}
+
+// checkBulkFunding checks that the caller may use the funding account when it is stored.
+// The sequence numbers of other funding accounts are not tracked, so they are given.
+func (m *Manager) checkBulkFunding(ctx context.Context, req *logical.Request, funding *accountFunding) error {
+	stored, err := m.retrieveAccount(ctx, req.Storage, funding.accountID)
+	if err != nil {
+		return err
+	}
+	if stored != nil {
+		funding.tracked = true
+		return m.checkAccountAccess(ctx, req, funding.accountID)
+	}
+	if funding.sequence == 0 {
+		return invalidRequest("sequence is required when funding_account is not an account of the mount")
+	}
+	return nil
+}
+
+// fundingTransactions builds the transactions of the funding account creating the
+// accounts, as many as the operation limit requires. Sponsored accounts sign the
+// transactions of their creation, the funding account signature is left to the caller.
+// If one cannot be built, the sequence numbers allocated for all of them are released.
+func (m *Manager) fundingTransactions(ctx context.Context, storage logical.Storage, funding *accountFunding,
+	pairs []*keypair.Full) ([]map[string]interface{}, error) {
+	perTransaction := maxTransactionOperations / len(funding.operationSpecs(""))
+	if funding.sponsored && perTransaction > maxTransactionSignatures-1 {
+		// The funding account signs in addition to the sponsored accounts
+		perTransaction = maxTransactionSignatures - 1
+	}
+
+	transactions := []map[string]interface{}{}
+	var allocated []int64
+	for start := 0; start < len(pairs); start += perTransaction {
+		end := start + perTransaction
+		if end > len(pairs) {
+			end = len(pairs)
+		}
+		explicit := funding.sequence
+		if explicit != 0 {
+			explicit += int64(len(transactions))
+		}
+		transaction, sequence, isAllocated, err := m.fundingTransaction(ctx, storage, funding, pairs[start:end], explicit)
+		if isAllocated {
+			allocated = append(allocated, sequence)
+		}
+		if err != nil {
+			// A number is only given back while it is the last one handed out
+			for i := len(allocated) - 1; i >= 0; i-- {
+				m.releaseSequence(ctx, storage, funding.network, funding.accountID, allocated[i])
+			}
+			return nil, err
+		}
+		transactions = append(transactions, transaction)
+	}
+	return transactions, nil
+}
+
+// fundingTransaction builds the transaction creating the accounts of pairs. allocated
+// tells whether its sequence number must be released if it is not used.
+func (m *Manager) fundingTransaction(ctx context.Context, storage logical.Storage, funding *accountFunding,
+	pairs []*keypair.Full, explicit int64) (transaction map[string]interface{}, sequence int64, allocated bool, err error) {
+	var specs []OperationSpec
+	publicKeys := make([]string, 0, len(pairs))
+	for _, pair := range pairs {
+		specs = append(specs, funding.operationSpecs(pair.Address())...)
+		publicKeys = append(publicKeys, pair.Address())
+	}
+	ops, err := buildOperations(specs)
+	if err != nil {
+		return nil, 0, false, err
+	}
+
+	sequence = explicit
+	if funding.tracked {
+		if sequence, allocated, err = m.nextSequence(ctx, storage, funding.network, funding.accountID, explicit); err != nil {
+			return nil, 0, false, err
+		}
+	}
+	tx, err := txnbuild.NewTransaction(txnbuild.TransactionParams{
+		SourceAccount: &txnbuild.SimpleAccount{AccountID: funding.account, Sequence: sequence},
+		Operations:    ops,
+		BaseFee:       txnbuild.MinBaseFee,
+		Preconditions: txnbuild.Preconditions{TimeBounds: txnbuild.NewTimeout(funding.timeout)},
+	})
+	if err == nil && funding.sponsored {
+		tx, err = tx.Sign(networkPassphrases[funding.network], pairs...)
+	}
+	if err != nil {
+		return nil, sequence, allocated, fmt.Errorf("error building the funding transaction: %s", err)
+	}
+	envelope, err := tx.Base64()
+	if err != nil {
+		return nil, sequence, allocated, fmt.Errorf("error encoding the funding transaction: %s", err)
+	}
+	txHash, err := tx.HashHex(networkPassphrases[funding.network])
+	if err != nil {
+		return nil, sequence, allocated, fmt.Errorf("error hashing the funding transaction: %s", err)
+	}
+	return map[string]interface{}{
+		"transaction":      envelope,
+		"transaction_hash": txHash,
+		"sequence":         strconv.FormatInt(sequence, 10),
+		"public_keys":      publicKeys,
+	}, sequence, allocated, nil
+}
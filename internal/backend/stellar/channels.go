@@ -0,0 +1,585 @@
+package stellar
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stellar/go/keypair"
+	"strings"
+	"time"
+)
+
+const (
+	// SecretTypeChannel is the type of the Vault leases handed out for channel accounts
+	SecretTypeChannel = "channel"
+
+	defaultChannelLeaseTTL        int64 = 60
+	defaultChannelStartingBalance       = "2"
+	// maxChannelPoolSize keeps the funding transaction within the operation limit
+	maxChannelPoolSize = 100
+)
+
+// ChannelPool is a set of Vault accounts used as transaction sources on behalf of a
+// main account, so that its transactions do not compete for its sequence number.
+type ChannelPool struct {
+	Name     string   `json:"name"`
+	Account  string   `json:"account"`
+	Network  string   `json:"network"`
+	Channels []string `json:"channels"`
+	LeaseTTL int64    `json:"lease_ttl"`
+	// MaxLeaseTTL caps how long a lease can be renewed for, the mount's maximum when 0
+	MaxLeaseTTL int64 `json:"max_lease_ttl,omitempty"`
+}
+
+// ChannelLease reserves a channel of a pool for the holder of a Vault lease
+type ChannelLease struct {
+	Pool      string    `json:"pool"`
+	Channel   string    `json:"channel"`
+	ID        string    `json:"id"`
+	LeasedAt  time.Time `json:"leased_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type channelSigner struct {
+	account *Account
+	lease   *ChannelLease
+}
+
+func channelPoolPath(name string) string {
+	return fmt.Sprintf("stellar/pools/%s", name)
+}
+
+func channelLeasePath(pool string, channel string) string {
+	return fmt.Sprintf("stellar/channel-leases/%s/%s", pool, channel)
+}
+
+// ChannelSecret describes the Vault leases of channel accounts. Revoking or letting
+// such a lease expire returns the channel to its pool.
+func (m *Manager) ChannelSecret() *framework.Secret {
+	return &framework.Secret{
+		Type: SecretTypeChannel,
+		Fields: map[string]*framework.FieldSchema{
+			"pool": {
+				Type:        framework.TypeString,
+				Description: "The name of the channel pool.",
+			},
+			"channel": {
+				Type:        framework.TypeString,
+				Description: "The public key of the leased channel account.",
+			},
+		},
+		Renew:  m.RenewChannelLease,
+		Revoke: m.RevokeChannelLease,
+	}
+}
+
+func (m *Manager) ListChannelPools(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	pools, err := req.Storage.List(ctx, "stellar/pools/")
+	if err != nil {
+		m.logger.Error("Failed to list channel pools", "error", err)
+		return nil, fmt.Errorf("failed to list channel pools: %s", err)
+	}
+
+	visible := []string{}
+	for _, name := range pools {
+		pool, err := m.retrieveAccessibleChannelPool(ctx, req, name)
+		if err != nil {
+			return nil, err
+		}
+		if pool != nil {
+			visible = append(visible, name)
+		}
+	}
+	return logical.ListResponse(visible), nil
+}
+
+func (m *Manager) ReadChannelPool(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	pool, err := m.retrieveAccessibleChannelPool(ctx, req, data.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if pool == nil {
+		return nil, notFound(ErrCodeChannelPoolNotFound, "channel pool does not exist")
+	}
+
+	leased := []string{}
+	now := time.Now()
+	for _, channel := range pool.Channels {
+		lease, err := m.retrieveChannelLease(ctx, req.Storage, pool.Name, channel)
+		if err != nil {
+			return nil, err
+		}
+		if lease != nil && now.Before(lease.ExpiresAt) {
+			leased = append(leased, channel)
+		}
+	}
+
+	respData := pool.responseData()
+	respData["leased"] = leased
+	return &logical.Response{
+		Data: respData,
+	}, nil
+}
+
+// WriteChannelPool creates a pool with new channel accounts, optionally creating them
+// on-ledger funded by the main account. Existing pools only change their lease TTL.
+func (m *Manager) WriteChannelPool(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+	if name == "" {
+		return nil, invalidRequest("missing pool name")
+	}
+
+	pool, err := m.retrieveChannelPool(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if pool != nil {
+		if pool, err = m.retrieveAccessibleChannelPool(ctx, req, name); err != nil {
+			return nil, err
+		}
+		if pool == nil {
+			return nil, notFound(ErrCodeChannelPoolNotFound, "channel pool does not exist")
+		}
+		for _, field := range []string{"account", "network", "size", "fund"} {
+			if _, ok := data.GetOk(field); ok {
+				return nil, invalidRequest("%s cannot be changed on an existing channel pool", field)
+			}
+		}
+		if leaseTTL, ok := data.GetOk("lease_ttl"); ok {
+			pool.LeaseTTL = int64(leaseTTL.(int))
+		}
+		if maxLeaseTTL, ok := data.GetOk("max_lease_ttl"); ok {
+			pool.MaxLeaseTTL = int64(maxLeaseTTL.(int))
+		}
+		if err = m.saveChannelPool(ctx, req.Storage, pool); err != nil {
+			return nil, err
+		}
+		return &logical.Response{
+			Data: pool.responseData(),
+		}, nil
+	}
+
+	networkName := data.Get("network").(string)
+	if _, ok := networkPassphrases[networkName]; !ok {
+		return nil, invalidNetwork(networkName)
+	}
+	size := data.Get("size").(int)
+	if size < 1 || size > maxChannelPoolSize {
+		return nil, invalidRequest("size must be between 1 and %d", maxChannelPoolSize)
+	}
+	account, access, err := m.retrieveAccessibleAccount(ctx, req, data.Get("account").(string))
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		return nil, notFound(ErrCodeAccountNotFound, "account not found")
+	}
+	// The channels are funded by and build on behalf of the main account
+	if access != accessOwner {
+		return nil, forbidden(ErrCodePermissionDenied, "only the owner of the account can create a channel pool for it")
+	}
+
+	pool = &ChannelPool{
+		Name:        name,
+		Account:     account.PublicKey,
+		Network:     networkName,
+		Channels:    make([]string, 0, size),
+		LeaseTTL:    int64(data.Get("lease_ttl").(int)),
+		MaxLeaseTTL: int64(data.Get("max_lease_ttl").(int)),
+	}
+	channels := make([]*Account, 0, size)
+	for i := 0; i < size; i++ {
+		pair, err := keypair.Random()
+		if err != nil {
+			m.logger.Error("Error generating new keypair", "error", err)
+			return nil, fmt.Errorf("error generating new keypair")
+		}
+		// Channels belong to the owners of the main account
+		channel := &Account{
+			PublicKey:      pair.Address(),
+			SecretKey:      pair.Seed(),
+			AllowedSources: []string{pair.Address()},
+			OwnerEntity:    account.OwnerEntity,
+			OwnerGroup:     account.OwnerGroup,
+			SharedWith:     account.SharedWith,
+			CreatedAt:      time.Now(),
+		}
+		if err = m.createAccount(ctx, req.Storage, channel); err != nil {
+			return nil, m.rollBackAccounts(ctx, req.Storage, channels, err)
+		}
+		channels = append(channels, channel)
+		pool.Channels = append(pool.Channels, channel.PublicKey)
+	}
+	if err = m.saveChannelPool(ctx, req.Storage, pool); err != nil {
+		return nil, m.rollBackAccounts(ctx, req.Storage, channels, err)
+	}
+
+	resp := &logical.Response{
+		Data: pool.responseData(),
+	}
+	if data.Get("fund").(bool) {
+		startingBalance := data.Get("starting_balance").(string)
+		if startingBalance == "" {
+			startingBalance = defaultChannelStartingBalance
+		}
+		// The pool is kept, as the funding transaction may still make it into a ledger.
+		// Its channels can be funded from the main account afterwards.
+		funded, err := m.fundChannels(ctx, req.Storage, pool, account, startingBalance)
+		if err != nil {
+			m.logger.Warn("Funding the channels of the pool failed", "pool", name, "error", err)
+			resp.AddWarning(fmt.Sprintf("funding the channels failed: %s", err))
+		} else {
+			resp.Data["funding_transaction_hash"] = funded.Data["transaction_hash"]
+		}
+	}
+	return resp, nil
+}
+
+// fundChannels creates the channel accounts on-ledger in one transaction of the main account
+func (m *Manager) fundChannels(ctx context.Context, storage logical.Storage, pool *ChannelPool, account *Account,
+	startingBalance string) (*logical.Response, error) {
+	specs := make([]OperationSpec, 0, len(pool.Channels))
+	for _, channel := range pool.Channels {
+		specs = append(specs, OperationSpec{Type: "create_account", Destination: channel, StartingBalance: startingBalance})
+	}
+	sr := &signRequest{
+		publicKey:         account.PublicKey,
+		network:           pool.Network,
+		networkPassphrase: networkPassphrases[pool.Network],
+		submit:            true,
+	}
+	return m.buildTransaction(ctx, storage, account, account.PublicKey, specs, defaultBuildOptions(), sr)
+}
+
+// DeleteChannelPool removes a pool without active leases. The channel accounts stay
+// stored so their remaining balance can be merged back.
+func (m *Manager) DeleteChannelPool(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+
+	m.channelLock.Lock()
+	defer m.channelLock.Unlock()
+
+	pool, err := m.retrieveAccessibleChannelPool(ctx, req, name)
+	if err != nil || pool == nil {
+		return nil, err
+	}
+	now := time.Now()
+	for _, channel := range pool.Channels {
+		lease, err := m.retrieveChannelLease(ctx, req.Storage, name, channel)
+		if err != nil {
+			return nil, err
+		}
+		if lease != nil && now.Before(lease.ExpiresAt) {
+			return nil, conflict(ErrCodeConflict, "channel %s of pool %s is leased until %s", channel, name, lease.ExpiresAt.Format(time.RFC3339))
+		}
+		if err = req.Storage.Delete(ctx, channelLeasePath(name, channel)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = req.Storage.Delete(ctx, channelPoolPath(name)); err != nil {
+		m.logger.Error("Failed to delete the channel pool from storage", "name", name, "error", err)
+		return nil, err
+	}
+	return nil, nil
+}
+
+// LeaseChannel reserves a free channel of the pool and returns it as a Vault lease
+func (m *Manager) LeaseChannel(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	pool, err := m.retrieveAccessibleChannelPool(ctx, req, data.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if pool == nil {
+		return nil, notFound(ErrCodeChannelPoolNotFound, "channel pool does not exist")
+	}
+
+	lease, err := m.acquireChannel(ctx, req.Storage, pool)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := m.ChannelSecret().Response(map[string]interface{}{
+		"pool":     pool.Name,
+		"channel":  lease.Channel,
+		"network":  pool.Network,
+		"lease_id": lease.ID,
+	}, map[string]interface{}{
+		"pool":     pool.Name,
+		"channel":  lease.Channel,
+		"lease_id": lease.ID,
+	})
+	resp.Secret.TTL = pool.leaseTTL()
+	resp.Secret.MaxTTL = pool.maxLeaseTTL()
+	return resp, nil
+}
+
+func (m *Manager) RenewChannelLease(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	ref := leaseFromSecret(req.Secret)
+
+	m.channelLock.Lock()
+	defer m.channelLock.Unlock()
+
+	lease, err := m.retrieveChannelLease(ctx, req.Storage, ref.Pool, ref.Channel)
+	if err != nil {
+		return nil, err
+	}
+	if lease == nil || lease.ID != ref.ID {
+		return nil, conflict(ErrCodeConflict, "channel %s is no longer leased", ref.Channel)
+	}
+	pool, err := m.retrieveChannelPool(ctx, req.Storage, ref.Pool)
+	if err != nil {
+		return nil, err
+	}
+	if pool == nil {
+		return nil, notFound(ErrCodeChannelPoolNotFound, "channel pool does not exist")
+	}
+
+	lease.ExpiresAt = time.Now().Add(pool.leaseTTL())
+	if err = m.saveChannelLease(ctx, req.Storage, lease); err != nil {
+		return nil, err
+	}
+	resp := &logical.Response{Secret: req.Secret}
+	resp.Secret.TTL = pool.leaseTTL()
+	resp.Secret.MaxTTL = pool.maxLeaseTTL()
+	return resp, nil
+}
+
+func (m *Manager) RevokeChannelLease(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	return nil, m.releaseChannel(ctx, req.Storage, leaseFromSecret(req.Secret))
+}
+
+func leaseFromSecret(secret *logical.Secret) *ChannelLease {
+	lease := &ChannelLease{}
+	if secret == nil {
+		return lease
+	}
+	lease.Pool, _ = secret.InternalData["pool"].(string)
+	lease.Channel, _ = secret.InternalData["channel"].(string)
+	lease.ID, _ = secret.InternalData["lease_id"].(string)
+	return lease
+}
+
+// BuildChannelTx builds a transaction with a leased channel as source and the pool's
+// main account as the source of every operation, signed by both. Only the holder of
+// the lease, identified by its lease_id, builds on the channel. The channel is released
+// once the submission outcome is known.
+func (m *Manager) BuildChannelTx(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	pool, err := m.retrieveAccessibleChannelPool(ctx, req, data.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if pool == nil {
+		return nil, notFound(ErrCodeChannelPoolNotFound, "channel pool does not exist")
+	}
+
+	channel := data.Get("channel").(string)
+	if !containsString(pool.Channels, channel) {
+		return nil, invalidRequest("channel %s is not part of pool %s", channel, pool.Name)
+	}
+	leaseID := data.Get("lease_id").(string)
+	if leaseID == "" {
+		return nil, invalidRequest("missing lease_id, lease a channel at channels/%s/lease first", pool.Name)
+	}
+	lease, err := m.retrieveChannelLease(ctx, req.Storage, pool.Name, channel)
+	if err != nil {
+		return nil, err
+	}
+	if lease == nil || time.Now().After(lease.ExpiresAt) {
+		return nil, conflict(ErrCodeConflict, "channel %s is not leased, lease it at channels/%s/lease first", channel, pool.Name)
+	}
+	if lease.ID != leaseID {
+		return nil, conflict(ErrCodeConflict, "channel %s is leased under another lease", channel)
+	}
+
+	account, _, err := m.retrieveAccessibleAccount(ctx, req, pool.Account)
+	if err != nil {
+		return nil, err
+	}
+	channelAccount, _, err := m.retrieveAccessibleAccount(ctx, req, channel)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil || channelAccount == nil {
+		return nil, notFound(ErrCodeAccountNotFound, "account not found")
+	}
+
+	specs, err := operationSpecs(data)
+	if err != nil {
+		return nil, err
+	}
+	for i := range specs {
+		if specs[i].Source == "" {
+			specs[i].Source = account.PublicKey
+		}
+	}
+	opts, err := parseBuildOptions(data)
+	if err != nil {
+		return nil, err
+	}
+
+	sr := &signRequest{
+		publicKey:         account.PublicKey,
+		network:           pool.Network,
+		networkPassphrase: networkPassphrases[pool.Network],
+		idempotencyKey:    data.Get("idempotency_key").(string),
+		submit:            data.Get("submit").(bool),
+		async:             data.Get("async").(bool),
+		channel:           &channelSigner{account: channelAccount, lease: lease},
+	}
+	resp, err := m.buildTransaction(ctx, req.Storage, account, channel, specs, opts, sr)
+	if err != nil {
+		return nil, err
+	}
+	resp.Data["channel"] = channel
+	return resp, nil
+}
+
+// acquireChannel leases the first channel of the pool without an active lease
+func (m *Manager) acquireChannel(ctx context.Context, storage logical.Storage, pool *ChannelPool) (*ChannelLease, error) {
+	m.channelLock.Lock()
+	defer m.channelLock.Unlock()
+
+	now := time.Now()
+	for _, channel := range pool.Channels {
+		lease, err := m.retrieveChannelLease(ctx, storage, pool.Name, channel)
+		if err != nil {
+			return nil, err
+		}
+		if lease != nil && now.Before(lease.ExpiresAt) {
+			continue
+		}
+
+		id, err := uuid.GenerateUUID()
+		if err != nil {
+			return nil, err
+		}
+		lease = &ChannelLease{Pool: pool.Name, Channel: channel, ID: id, LeasedAt: now, ExpiresAt: now.Add(pool.leaseTTL())}
+		if err = m.saveChannelLease(ctx, storage, lease); err != nil {
+			return nil, err
+		}
+		return lease, nil
+	}
+	return nil, conflict(ErrCodeConflict, "no channel of pool %s is available, all %d are leased", pool.Name, len(pool.Channels))
+}
+
+// releaseChannel ends the lease, unless the channel was leased again in the meantime
+func (m *Manager) releaseChannel(ctx context.Context, storage logical.Storage, ref *ChannelLease) error {
+	m.channelLock.Lock()
+	defer m.channelLock.Unlock()
+
+	lease, err := m.retrieveChannelLease(ctx, storage, ref.Pool, ref.Channel)
+	if err != nil || lease == nil || lease.ID != ref.ID {
+		return err
+	}
+	if err = storage.Delete(ctx, channelLeasePath(ref.Pool, ref.Channel)); err != nil {
+		m.logger.Error("Failed to release channel", "pool", ref.Pool, "channel", ref.Channel, "error", err)
+		return err
+	}
+	return nil
+}
+
+func (m *Manager) ChannelPoolExistenceCheck(ctx context.Context, req *logical.Request, data *framework.FieldData) (bool, error) {
+	pool, err := m.retrieveChannelPool(ctx, req.Storage, data.Get("name").(string))
+	if err != nil {
+		return false, fmt.Errorf("existence check failed: %v", err)
+	}
+	return pool != nil, nil
+}
+
+// retrieveAccessibleChannelPool retrieves a pool and, when ownership is enforced, hides
+// it from callers that may not use its main account
+func (m *Manager) retrieveAccessibleChannelPool(ctx context.Context, req *logical.Request, name string) (*ChannelPool, error) {
+	pool, err := m.retrieveChannelPool(ctx, req.Storage, name)
+	if err != nil || pool == nil {
+		return nil, err
+	}
+	visible, err := m.filterAccessibleAccounts(ctx, req, []string{pool.Account})
+	if err != nil || len(visible) == 0 {
+		return nil, err
+	}
+	return pool, nil
+}
+
+func (m *Manager) saveChannelPool(ctx context.Context, storage logical.Storage, pool *ChannelPool) error {
+	entry, err := logical.StorageEntryJSON(channelPoolPath(pool.Name), pool)
+	if err != nil {
+		return err
+	}
+	if err = storage.Put(ctx, entry); err != nil {
+		m.logger.Error("Failed to save the channel pool to storage", "name", pool.Name, "error", err)
+		return err
+	}
+	return nil
+}
+
+func (m *Manager) retrieveChannelPool(ctx context.Context, storage logical.Storage, name string) (*ChannelPool, error) {
+	if name == "" || strings.Contains(name, "/") {
+		return nil, invalidRequest("invalid pool name %q", name)
+	}
+	entry, err := storage.Get(ctx, channelPoolPath(name))
+	if err != nil {
+		m.logger.Error("Failed to retrieve the channel pool", "name", name, "error", err)
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+	var pool ChannelPool
+	if err = entry.DecodeJSON(&pool); err != nil {
+		return nil, err
+	}
+	return &pool, nil
+}
+
+func (m *Manager) saveChannelLease(ctx context.Context, storage logical.Storage, lease *ChannelLease) error {
+	entry, err := logical.StorageEntryJSON(channelLeasePath(lease.Pool, lease.Channel), lease)
+	if err != nil {
+		return err
+	}
+	if err = storage.Put(ctx, entry); err != nil {
+		m.logger.Error("Failed to save the channel lease", "pool", lease.Pool, "channel", lease.Channel, "error", err)
+		return err
+	}
+	return nil
+}
+
+func (m *Manager) retrieveChannelLease(ctx context.Context, storage logical.Storage, pool string, channel string) (*ChannelLease, error) {
+	entry, err := storage.Get(ctx, channelLeasePath(pool, channel))
+	if err != nil {
+		m.logger.Error("Failed to retrieve the channel lease", "pool", pool, "channel", channel, "error", err)
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+	var lease ChannelLease
+	if err = entry.DecodeJSON(&lease); err != nil {
+		return nil, err
+	}
+	return &lease, nil
+}
+
+func (p *ChannelPool) leaseTTL() time.Duration {
+	if p.LeaseTTL <= 0 {
+		return time.Duration(defaultChannelLeaseTTL) * time.Second
+	}
+	return time.Duration(p.LeaseTTL) * time.Second
+}
+
+func (p *ChannelPool) maxLeaseTTL() time.Duration {
+	return time.Duration(p.MaxLeaseTTL) * time.Second
+}
+
+func (p *ChannelPool) responseData() map[string]interface{} {
+	return map[string]interface{}{
+		"name":          p.Name,
+		"account":       p.Account,
+		"network":       p.Network,
+		"channels":      p.Channels,
+		"lease_ttl":     int64(p.leaseTTL().Seconds()),
+		"max_lease_ttl": p.MaxLeaseTTL,
+	}
+}
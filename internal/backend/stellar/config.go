@@ -0,0 +1,192 @@
+package stellar
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"net/url"
+)
+
+const configPath = "stellar/config"
+
+// Config holds the mount-wide settings of the plugin
+type Config struct {
+	Policy              string `json:"policy,omitempty"`
+	ReplayProtection    bool   `json:"replay_protection"`
+	ReplayWindow        int64  `json:"replay_window,omitempty"`
+	RejectSequenceReuse bool   `json:"reject_sequence_reuse"`
+	// HorizonURLs maps network names to the Horizon used for that network
+	HorizonURLs     map[string]string `json:"horizon_urls,omitempty"`
+	HorizonCacheTTL int64             `json:"horizon_cache_ttl,omitempty"`
+	// Ownership scopes accounts to the entity or group owning them, except for the admin group
+	Ownership  bool   `json:"ownership"`
+	AdminGroup string `json:"admin_group,omitempty"`
+	// Prices maps assets (CODE:ISSUER) to their value in XLM, for the approval tiers of policies
+	Prices map[string]string `json:"prices,omitempty"`
+	// MetricsAccountLabel is the label signatures and denials are counted by
+	MetricsAccountLabel string `json:"metrics_account_label,omitempty"`
+	// UnusedKeyDays is after how many days without a signature a key is reported as unused
+	UnusedKeyDays int64 `json:"unused_key_days,omitempty"`
+	// HistoryRetentionDays is for how many days signing history records are kept
+	HistoryRetentionDays int64 `json:"history_retention_days,omitempty"`
+}
+
+func (m *Manager) ReadConfig(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := m.retrieveConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: config.responseData(),
+	}, nil
+}
+
+func (m *Manager) WriteConfig(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := m.retrieveConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	if policyName, ok := data.GetOk("policy"); ok {
+		if err = m.validatePolicyReference(ctx, req.Storage, policyName.(string)); err != nil {
+			return nil, err
+		}
+		config.Policy = policyName.(string)
+	}
+	if replayProtection, ok := data.GetOk("replay_protection"); ok {
+		config.ReplayProtection = replayProtection.(bool)
+	}
+	if replayWindow, ok := data.GetOk("replay_window"); ok {
+		if replayWindow.(int) < 0 {
+			return nil, invalidRequest("replay_window must not be negative")
+		}
+		config.ReplayWindow = int64(replayWindow.(int))
+	}
+	if rejectSequenceReuse, ok := data.GetOk("reject_sequence_reuse"); ok {
+		config.RejectSequenceReuse = rejectSequenceReuse.(bool)
+	}
+	if horizonURLs, ok := data.GetOk("horizon_urls"); ok {
+		config.HorizonURLs = map[string]string{}
+		for networkName, horizonURL := range horizonURLs.(map[string]string) {
+			if _, ok := networkPassphrases[networkName]; !ok {
+				return nil, badRequest(ErrCodeInvalidNetwork, "invalid network in horizon_urls: %s", networkName)
+			}
+			if horizonURL == "" {
+				continue
+			}
+			parsed, err := url.Parse(horizonURL)
+			if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+				return nil, invalidRequest("invalid Horizon URL for network %s: %s", networkName, horizonURL)
+			}
+			config.HorizonURLs[networkName] = horizonURL
+		}
+	}
+	if horizonCacheTTL, ok := data.GetOk("horizon_cache_ttl"); ok {
+		if horizonCacheTTL.(int) < 0 {
+			return nil, invalidRequest("horizon_cache_ttl must not be negative")
+		}
+		config.HorizonCacheTTL = int64(horizonCacheTTL.(int))
+	}
+
+	if ownership, ok := data.GetOk("ownership"); ok {
+		config.Ownership = ownership.(bool)
+	}
+	if adminGroup, ok := data.GetOk("admin_group"); ok {
+		config.AdminGroup = adminGroup.(string)
+	}
+	if label, ok := data.GetOk("metrics_account_label"); ok {
+		if err = validateMetricsAccountLabel(label.(string)); err != nil {
+			return nil, err
+		}
+		config.MetricsAccountLabel = label.(string)
+	}
+	if unusedKeyDays, ok := data.GetOk("unused_key_days"); ok {
+		if unusedKeyDays.(int) < 0 {
+			return nil, invalidRequest("unused_key_days must not be negative")
+		}
+		config.UnusedKeyDays = int64(unusedKeyDays.(int))
+	}
+	if retentionDays, ok := data.GetOk("history_retention_days"); ok {
+		if retentionDays.(int) < 0 {
+			return nil, invalidRequest("history_retention_days must not be negative")
+		}
+		config.HistoryRetentionDays = int64(retentionDays.(int))
+	}
+	if prices, ok := data.GetOk("prices"); ok {
+		if config.Prices, err = parsePrices(prices.(map[string]string)); err != nil {
+			return nil, err
+		}
+	}
+
+	entry, err := logical.StorageEntryJSON(configPath, config)
+	if err != nil {
+		return nil, err
+	}
+	if err = req.Storage.Put(ctx, entry); err != nil {
+		m.logger.Error("Failed to save the plugin configuration", "error", err)
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: config.responseData(),
+	}, nil
+}
+
+// retrieveConfig returns the stored configuration, or an empty one if none was written yet.
+func (m *Manager) retrieveConfig(ctx context.Context, storage logical.Storage) (*Config, error) {
+	entry, err := storage.Get(ctx, configPath)
+	if err != nil {
+		m.logger.Error("Failed to retrieve the plugin configuration", "error", err)
+		return nil, fmt.Errorf("failed to retrieve the plugin configuration: %s", err)
+	}
+	config := &Config{}
+	if entry == nil {
+		return config, nil
+	}
+	if err = entry.DecodeJSON(config); err != nil {
+		return nil, fmt.Errorf("failed to decode the plugin configuration: %s", err)
+	}
+	return config, nil
+}
+
+// replayWindow returns how long signed transactions are remembered, in seconds
+func (c *Config) replayWindow() int64 {
+	if c.ReplayWindow == 0 {
+		return defaultReplayWindow
+	}
+	return c.ReplayWindow
+}
+
+// horizonCacheTTL returns how long Horizon lookups are cached, in seconds
+func (c *Config) horizonCacheTTL() int64 {
+	if c.HorizonCacheTTL == 0 {
+		return defaultHorizonCacheTTL
+	}
+	return c.HorizonCacheTTL
+}
+
+func (c *Config) responseData() map[string]interface{} {
+	horizonURLs, prices := c.HorizonURLs, c.Prices
+	if horizonURLs == nil {
+		horizonURLs = map[string]string{}
+	}
+	if prices == nil {
+		prices = map[string]string{}
+	}
+	return map[string]interface{}{
+		"policy":                 c.Policy,
+		"replay_protection":      c.ReplayProtection,
+		"replay_window":          c.replayWindow(),
+		"reject_sequence_reuse":  c.RejectSequenceReuse,
+		"horizon_urls":           horizonURLs,
+		"horizon_cache_ttl":      c.horizonCacheTTL(),
+		"ownership":              c.Ownership,
+		"admin_group":            c.AdminGroup,
+		"prices":                 prices,
+		"metrics_account_label":  c.metricsAccountLabel(),
+		"unused_key_days":        c.unusedKeyDays(),
+		"history_retention_days": c.historyRetentionDays(),
+	}
+}
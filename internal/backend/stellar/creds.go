@@ -0,0 +1,235 @@
+package stellar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stellar/go/keypair"
+	"time"
+	"vault-plugin-stellar-sign/internal/backend/horizon"
+)
+
+// SecretTypeCreds is the type of the Vault leases of ephemeral accounts
+const SecretTypeCreds = "creds"
+
+// CredsSecret describes the Vault leases of ephemeral accounts. Revoking or letting
+// such a lease expire merges the account back into the funding account or removes it
+// from the signers of the parent account, then deletes it.
+func (m *Manager) CredsSecret() *framework.Secret {
+	return &framework.Secret{
+		Type: SecretTypeCreds,
+		Fields: map[string]*framework.FieldSchema{
+			"public_key": {
+				Type:        framework.TypeString,
+				Description: "The public key of the ephemeral account.",
+			},
+			"secret_key": {
+				Type:        framework.TypeString,
+				Description: "The secret key of the ephemeral account.",
+			},
+		},
+		Renew:  m.RenewCreds,
+		Revoke: m.RevokeCreds,
+	}
+}
+
+// IssueCreds generates an ephemeral account for the role, creating it on-ledger or
+// adding it as a signer of the parent account when the role says so.
+func (m *Manager) IssueCreds(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	role, err := m.retrieveRole(ctx, req.Storage, data.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, notFound(ErrCodeRoleNotFound, "role does not exist")
+	}
+
+	pair, err := keypair.Random()
+	if err != nil {
+		m.logger.Error("Error generating new keypair", "error", err)
+		return nil, fmt.Errorf("error generating new keypair")
+	}
+	account := &Account{
+		PublicKey:      pair.Address(),
+		SecretKey:      pair.Seed(),
+		AllowedSources: []string{pair.Address()},
+		IssuedBy:       role.Name,
+		// The account is owned by the caller it is issued to
+		OwnerEntity: req.EntityID,
+		CreatedAt:   time.Now(),
+	}
+	if role.ParentAccount != "" {
+		account.AllowedSources = []string{role.ParentAccount}
+	}
+	if err = m.createAccount(ctx, req.Storage, account); err != nil {
+		return nil, err
+	}
+
+	respData := map[string]interface{}{
+		"public_key": account.PublicKey,
+		"secret_key": account.SecretKey,
+		"role":       role.Name,
+	}
+	if err = m.provisionCreds(ctx, req, role, account); err != nil {
+		if errDelete := m.deleteAccount(ctx, req.Storage, account); errDelete != nil {
+			m.logger.Error("Failed to delete the unprovisioned ephemeral account", "publicKey", account.PublicKey, "error", errDelete)
+		}
+		return nil, err
+	}
+	if role.Network != "" {
+		respData["network"] = role.Network
+	}
+	m.sendEvent(ctx, EventAccountCreate, "public_key", account.PublicKey, "issued_by", role.Name)
+
+	// The revocation uses the role settings at issuance, the role may change or go away
+	resp := m.CredsSecret().Response(respData, map[string]interface{}{
+		"role":            role.Name,
+		"public_key":      account.PublicKey,
+		"network":         role.Network,
+		"funding_account": role.FundingAccount,
+		"parent_account":  role.ParentAccount,
+	})
+	resp.Secret.TTL = role.ttl()
+	resp.Secret.MaxTTL = role.maxTTL()
+	return resp, nil
+}
+
+// provisionCreds creates the ephemeral account on-ledger or adds it as a signer. The
+// caller must be allowed to use the funding or parent account of the role.
+func (m *Manager) provisionCreds(ctx context.Context, req *logical.Request, role *Role, account *Account) error {
+	var specs []OperationSpec
+	source := role.FundingAccount
+	switch {
+	case role.FundingAccount != "":
+		specs = []OperationSpec{{Type: "create_account", Destination: account.PublicKey, StartingBalance: role.StartingBalance}}
+	case role.ParentAccount != "":
+		weight := uint8(role.SignerWeight)
+		specs = []OperationSpec{{Type: "set_options", SignerKey: account.PublicKey, SignerWeight: &weight}}
+		source = role.ParentAccount
+	default:
+		return nil
+	}
+
+	sourceAccount, _, err := m.retrieveAccessibleAccount(ctx, req, source)
+	if err != nil {
+		return err
+	}
+	if sourceAccount == nil {
+		return accountNotFound(source)
+	}
+	if err = m.submitBuiltTransaction(ctx, req.Storage, role.Network, sourceAccount, specs); err != nil {
+		if role.FundingAccount != "" {
+			return fmt.Errorf("failed to create the ephemeral account on-ledger: %w", err)
+		}
+		return fmt.Errorf("failed to add the ephemeral account as signer: %w", err)
+	}
+	return nil
+}
+
+func (m *Manager) RenewCreds(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	roleName, _ := req.Secret.InternalData["role"].(string)
+	role, err := m.retrieveRole(ctx, req.Storage, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, notFound(ErrCodeRoleNotFound, "role %q no longer exists", roleName)
+	}
+
+	resp := &logical.Response{Secret: req.Secret}
+	resp.Secret.TTL = role.ttl()
+	resp.Secret.MaxTTL = role.maxTTL()
+	return resp, nil
+}
+
+// RevokeCreds undoes the provisioning of an ephemeral account and deletes it. Errors
+// are returned so that Vault retries the revocation.
+func (m *Manager) RevokeCreds(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	internal := req.Secret.InternalData
+	publicKey, _ := internal["public_key"].(string)
+	networkName, _ := internal["network"].(string)
+	fundingAccount, _ := internal["funding_account"].(string)
+	parentAccount, _ := internal["parent_account"].(string)
+
+	account, err := m.retrieveAccount(ctx, req.Storage, publicKey)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		return nil, nil
+	}
+
+	switch {
+	case fundingAccount != "":
+		exists, err := m.existsOnLedger(ctx, req.Storage, networkName, publicKey)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			specs := []OperationSpec{{Type: "account_merge", Destination: fundingAccount}}
+			if err = m.submitBuiltTransaction(ctx, req.Storage, networkName, account, specs); err != nil {
+				return nil, fmt.Errorf("failed to merge the ephemeral account %s: %w", publicKey, err)
+			}
+		}
+	case parentAccount != "":
+		weight := uint8(0)
+		specs := []OperationSpec{{Type: "set_options", SignerKey: publicKey, SignerWeight: &weight}}
+		// Revocations are made by Vault itself, the lease was issued under the ownership checks
+		parent, err := m.retrieveAccount(ctx, req.Storage, parentAccount)
+		if err != nil {
+			return nil, err
+		}
+		if parent == nil {
+			return nil, accountNotFound(parentAccount)
+		}
+		if err = m.submitBuiltTransaction(ctx, req.Storage, networkName, parent, specs); err != nil {
+			return nil, fmt.Errorf("failed to remove the ephemeral signer %s: %w", publicKey, err)
+		}
+	}
+
+	if networkName != "" {
+		if err = m.invalidateSequence(ctx, req.Storage, networkName, publicKey); err != nil {
+			return nil, err
+		}
+	}
+	if err = m.deleteAccount(ctx, req.Storage, account); err != nil {
+		m.logger.Error("Failed to delete the ephemeral account from storage", "publicKey", publicKey, "error", err)
+		return nil, err
+	}
+	m.sendEvent(ctx, EventAccountDelete, "public_key", publicKey, "issued_by", account.IssuedBy)
+	return nil, nil
+}
+
+// submitBuiltTransaction builds a transaction of the account and submits it
+func (m *Manager) submitBuiltTransaction(ctx context.Context, storage logical.Storage, networkName string, account *Account,
+	specs []OperationSpec) error {
+	sr := &signRequest{
+		publicKey:         account.PublicKey,
+		network:           networkName,
+		networkPassphrase: networkPassphrases[networkName],
+		submit:            true,
+	}
+	_, err := m.buildTransaction(ctx, storage, account, account.PublicKey, specs, defaultBuildOptions(), sr)
+	return err
+}
+
+func (m *Manager) existsOnLedger(ctx context.Context, storage logical.Storage, networkName string, accountID string) (bool, error) {
+	config, err := m.retrieveConfig(ctx, storage)
+	if err != nil {
+		return false, err
+	}
+	client, err := config.horizonClient(networkName)
+	if err != nil {
+		return false, err
+	}
+	_, err = client.Account(ctx, accountID)
+	switch {
+	case errors.Is(err, horizon.ErrNotFound):
+		return false, nil
+	case err != nil:
+		return false, horizonUnavailable("failed to fetch account from Horizon: %s", err)
+	}
+	return true, nil
+}
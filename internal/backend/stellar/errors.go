@@ -0,0 +1,135 @@
+package stellar
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hashicorp/vault/sdk/logical"
+	"net/http"
+)
+
+// Error codes returned in the error_code field of failed requests. They are stable, so
+// clients can act on them without matching error messages.
+const (
+	ErrCodeInvalidRequest          = "invalid_request"
+	ErrCodeInvalidNetwork          = "invalid_network"
+	ErrCodeInvalidAddress          = "invalid_address"
+	ErrCodeInvalidEnvelope         = "invalid_envelope"
+	ErrCodeAccountNotFound         = "account_not_found"
+	ErrCodeAccountExists           = "account_exists"
+	ErrCodeAccountUnusable         = "account_unusable"
+	ErrCodePolicyNotFound          = "policy_not_found"
+	ErrCodeRoleNotFound            = "role_not_found"
+	ErrCodeChannelPoolNotFound     = "channel_pool_not_found"
+	ErrCodeApprovalRequestNotFound = "approval_request_not_found"
+	ErrCodeSubmissionNotFound      = "submission_not_found"
+	ErrCodePermissionDenied        = "permission_denied"
+	ErrCodePolicyDenied            = "policy_denied"
+	ErrCodeSourceNotAllowed        = "source_not_allowed"
+	ErrCodeApprovalRequired        = "approval_required"
+	ErrCodeReplayRejected          = "replay_rejected"
+	ErrCodeTransactionExpired      = "transaction_expired"
+	ErrCodeConflict                = "conflict"
+	ErrCodeTransactionFailed       = "transaction_failed"
+	ErrCodeSubmissionPending       = "submission_pending"
+	ErrCodeHorizonUnavailable      = "horizon_unavailable"
+)
+
+// Error is a failure caused by the request rather than by the plugin, with the HTTP
+// status it is answered with. It implements logical.HTTPCodedError.
+type Error struct {
+	Status    int
+	ErrorCode string
+	Message   string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Code returns the HTTP status of the error
+func (e *Error) Code() int {
+	return e.Status
+}
+
+func newError(status int, code string, format string, args ...interface{}) *Error {
+	return &Error{Status: status, ErrorCode: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func invalidRequest(format string, args ...interface{}) *Error {
+	return newError(http.StatusBadRequest, ErrCodeInvalidRequest, format, args...)
+}
+
+func badRequest(code string, format string, args ...interface{}) *Error {
+	return newError(http.StatusBadRequest, code, format, args...)
+}
+
+func invalidNetwork(networkName string) *Error {
+	return newError(http.StatusBadRequest, ErrCodeInvalidNetwork, "invalid network: %s", networkName)
+}
+
+func accountNotFound(publicKey string) *Error {
+	return newError(http.StatusNotFound, ErrCodeAccountNotFound, "account %s not found", publicKey)
+}
+
+func notFound(code string, format string, args ...interface{}) *Error {
+	return newError(http.StatusNotFound, code, format, args...)
+}
+
+func forbidden(code string, format string, args ...interface{}) *Error {
+	return newError(http.StatusForbidden, code, format, args...)
+}
+
+func conflict(code string, format string, args ...interface{}) *Error {
+	return newError(http.StatusConflict, code, format, args...)
+}
+
+// horizonUnavailable reports a failed request to Horizon
+func horizonUnavailable(format string, args ...interface{}) *Error {
+	return newError(http.StatusBadGateway, ErrCodeHorizonUnavailable, format, args...)
+}
+
+// submissionPending reports a submission Horizon did not settle in time
+func submissionPending(format string, args ...interface{}) *Error {
+	return newError(http.StatusGatewayTimeout, ErrCodeSubmissionPending, format, args...)
+}
+
+// Code returns the HTTP status of policy denials
+func (d *PolicyDenial) Code() int {
+	return http.StatusForbidden
+}
+
+// ErrorResponse answers an error caused by the request with an error response and the
+// error Vault maps to its HTTP status. The error code, and the policy and the rule that
+// failed for policy denials, are the data of the response, which Vault returns next to
+// the error. Other errors are internal and returned as is.
+func ErrorResponse(err error) (*logical.Response, error) {
+	data := map[string]interface{}{}
+	var status int
+	var denial *PolicyDenial
+	var coded *Error
+	switch {
+	case errors.As(err, &denial):
+		status = denial.Code()
+		data["error_code"] = ErrCodePolicyDenied
+		data["policy"] = denial.Policy
+		data["rule"] = denial.Rule
+	case errors.As(err, &coded):
+		status = coded.Status
+		data["error_code"] = coded.ErrorCode
+	default:
+		return nil, err
+	}
+
+	resp := logical.ErrorResponse(err.Error())
+	resp.Data["data"] = data
+	switch status {
+	case http.StatusBadRequest:
+		return resp, logical.ErrInvalidRequest
+	case http.StatusForbidden:
+		return resp, logical.ErrPermissionDenied
+	}
+	// Vault answers error responses with a 400 unless the error is coded, and only keeps
+	// the code of the error when the response carries none
+	delete(resp.Data, "error")
+	return resp, logical.CodedError(status, err.Error())
+}
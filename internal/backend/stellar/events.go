@@ -0,0 +1,78 @@
+package stellar
+
+import (
+	"context"
+	"errors"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stellar/go/txnbuild"
+	"strconv"
+	"strings"
+)
+
+// Event types emitted on the Vault event bus. Their metadata never holds secret keys
+// or signed envelopes.
+const (
+	EventAccountCreate = "stellar/account-create"
+	EventAccountImport = "stellar/account-import"
+	EventAccountDelete = "stellar/account-delete"
+	EventSign          = "stellar/sign"
+	EventPolicyDenial  = "stellar/policy-denial"
+)
+
+// sendEvent emits an event with the given metadata pairs. Events are best effort:
+// failing to send one is logged and never fails the request.
+func (m *Manager) sendEvent(ctx context.Context, eventType string, metadata ...string) {
+	if m.events == nil {
+		return
+	}
+	err := logical.SendEvent(ctx, m.events, eventType, metadata...)
+	switch {
+	case errors.Is(err, framework.ErrNoEvents):
+	case err != nil:
+		m.logger.Warn("Failed to send event", "eventType", eventType, "error", err)
+	}
+}
+
+// sendSignEvent emits the event of a successful signature
+func (m *Manager) sendSignEvent(ctx context.Context, account *Account, tx *txnbuild.Transaction, txHash string, sr *signRequest) {
+	metadata := append([]string{"public_key", account.PublicKey, "tx_hash", txHash}, transactionMetadata(tx, sr)...)
+	if sr.role != "" {
+		metadata = append(metadata, "role", sr.role)
+	}
+	if sr.approvalRequest != "" {
+		metadata = append(metadata, "approval_request", sr.approvalRequest)
+	}
+	m.sendEvent(ctx, EventSign, metadata...)
+}
+
+// sendDenialEvent emits the event of a transaction denied by a signing policy. Other
+// errors are not denials and emit nothing.
+func (m *Manager) sendDenialEvent(ctx context.Context, account *Account, tx *txnbuild.Transaction, sr *signRequest, err error) {
+	var denial *PolicyDenial
+	if !errors.As(err, &denial) {
+		return
+	}
+	metadata := append([]string{"public_key", account.PublicKey, "policy", denial.Policy, "rule", denial.Rule},
+		transactionMetadata(tx, sr)...)
+	if txHash, err := tx.HashHex(sr.networkPassphrase); err == nil {
+		metadata = append(metadata, "tx_hash", txHash)
+	}
+	m.sendEvent(ctx, EventPolicyDenial, metadata...)
+}
+
+// transactionMetadata summarises the transaction for event metadata
+func transactionMetadata(tx *txnbuild.Transaction, sr *signRequest) []string {
+	summary := summarizeTransaction(tx)
+	types := make([]string, 0, len(summary.Operations))
+	for _, op := range summary.Operations {
+		types = append(types, op.Type)
+	}
+	return []string{
+		"network", sr.network,
+		"source", summary.Source,
+		"sequence", strconv.FormatInt(summary.Sequence, 10),
+		"operation_count", strconv.Itoa(len(summary.Operations)),
+		"operation_types", strings.Join(types, ","),
+	}
+}
@@ -0,0 +1,141 @@
+package stellar
+
+import (
+	"context"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stellar/go/xdr"
+)
+
+// accountFunding describes how new accounts are created on-ledger by a funding account
+type accountFunding struct {
+	// account is the address of the funding account, accountID its G-address
+	account         string
+	accountID       string
+	network         string
+	startingBalance string
+	sponsored       bool
+	// sequence and timeout of the unsigned transactions of bulk creations. tracked tells
+	// whether the funding account is stored, only their sequence numbers are tracked.
+	sequence int64
+	timeout  int64
+	tracked  bool
+}
+
+// accountFundingFromFieldData returns the funding of the request, nil when no funding
+// account is given
+func accountFundingFromFieldData(data *framework.FieldData) (*accountFunding, error) {
+	fundingAccount := data.Get("funding_account").(string)
+	if fundingAccount == "" {
+		return nil, nil
+	}
+	fundingAccountID, _, err := resolveAddress(fundingAccount)
+	if err != nil {
+		return nil, err
+	}
+	funding := &accountFunding{
+		account:         fundingAccount,
+		accountID:       fundingAccountID,
+		network:         data.Get("network").(string),
+		startingBalance: data.Get("starting_balance").(string),
+		sponsored:       data.Get("sponsored").(bool),
+	}
+	if _, ok := networkPassphrases[funding.network]; !ok {
+		return nil, invalidNetwork(funding.network)
+	}
+	switch {
+	case funding.sponsored && funding.startingBalance != "":
+		return nil, invalidRequest("sponsored accounts are created with a zero balance, starting_balance cannot be set")
+	case !funding.sponsored && funding.startingBalance == "":
+		return nil, invalidRequest("starting_balance is required unless the reserves are sponsored")
+	}
+	// The operations are checked before any account is stored
+	if _, err = buildOperations(funding.operationSpecs(fundingAccountID)); err != nil {
+		return nil, err
+	}
+	return funding, nil
+}
+
+// operationSpecs creates the account on-ledger from the source of the transaction, either
+// with a starting balance or with zero balance and reserves sponsored by the source
+func (f *accountFunding) operationSpecs(publicKey string) []OperationSpec {
+	if !f.sponsored {
+		return []OperationSpec{{Type: "create_account", Destination: publicKey, StartingBalance: f.startingBalance}}
+	}
+	return []OperationSpec{
+		{Type: "begin_sponsoring_future_reserves", Destination: publicKey},
+		{Type: "create_account", Destination: publicKey, StartingBalance: "0"},
+		{Type: "end_sponsoring_future_reserves", Source: publicKey},
+	}
+}
+
+// fundAccount builds the transaction of the funding account creating the account
+// on-ledger and signs it with the funding account, and with the new account for
+// sponsored reserves.
+func (m *Manager) fundAccount(ctx context.Context, storage logical.Storage, funder *Account, account *Account,
+	funding *accountFunding) (*logical.Response, error) {
+	sr := &signRequest{
+		publicKey:         funder.PublicKey,
+		network:           funding.network,
+		networkPassphrase: networkPassphrases[funding.network],
+	}
+	if funding.sponsored {
+		sr.cosigner = account
+	}
+	return m.buildTransaction(ctx, storage, funder, funder.PublicKey, funding.operationSpecs(account.PublicKey), defaultBuildOptions(), sr)
+}
+
+// submitFunding submits the signed funding transaction when Horizon is configured for
+// the network, and adds the outcome to the response data
+func (m *Manager) submitFunding(ctx context.Context, storage logical.Storage, funder *Account, funding *accountFunding,
+	signed *logical.Response, respData map[string]interface{}) error {
+	config, err := m.retrieveConfig(ctx, storage)
+	if err != nil {
+		return err
+	}
+	if config.HorizonURLs[funding.network] == "" {
+		return nil
+	}
+	submission := &Submission{
+		TxHash:     signed.Data["transaction_hash"].(string),
+		PublicKey:  funder.PublicKey,
+		Network:    funding.network,
+		TxEnvelope: signed.Data["signed_transaction"].(string),
+	}
+	if err = m.submit(ctx, storage, config, submission, false); err != nil {
+		return err
+	}
+	respData["submission_status"] = submission.Status
+	if submission.Ledger != 0 {
+		respData["ledger"] = submission.Ledger
+	}
+	return nil
+}
+
+// checkCosignedSourceBinding verifies a transaction co-signed by another stored account.
+// The operations the co-signer is the source of are bound by its own allowed sources,
+// the transaction source and the other operations by those of the account.
+func (a *Account) checkCosignedSourceBinding(cosigner *Account, envelope xdr.TransactionEnvelope) error {
+	allowed := a.allowedSources()
+	anyAllowed := containsString(allowed, anySource)
+	txSource := envelope.SourceAccount()
+	if !anyAllowed && !sourceAllowed(allowed, txSource) {
+		return forbidden(ErrCodeSourceNotAllowed, "account %s is not allowed to sign for source account %s", a.PublicKey, txSource.Address())
+	}
+	cosignerAllowed := cosigner.allowedSources()
+	for i, op := range envelope.Operations() {
+		if op.SourceAccount == nil {
+			continue
+		}
+		accountID := op.SourceAccount.ToAccountId()
+		signer, sources := a, allowed
+		if accountID.Address() == cosigner.PublicKey {
+			signer, sources = cosigner, cosignerAllowed
+		}
+		if !containsString(sources, anySource) && !sourceAllowed(sources, *op.SourceAccount) {
+			return forbidden(ErrCodeSourceNotAllowed, "account %s is not allowed to sign for operation %d source account %s",
+				signer.PublicKey, i, op.SourceAccount.Address())
+		}
+	}
+	return nil
+}
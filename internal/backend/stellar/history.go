@@ -0,0 +1,206 @@
+package stellar
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stellar/go/txnbuild"
+	"github.com/stellar/go/xdr"
+	"strings"
+	"time"
+)
+
+const (
+	// historyDayPrefix indexes the history records by the day they were signed on, as
+	// <YYYYMMDD>/<public key>.<tx hash>, so that expired days are pruned without reading
+	// every record
+	historyDayPrefix   = "stellar/history-days/"
+	historyDayLayout   = "20060102"
+	defaultHistoryDays = 365
+)
+
+// HistoryRecord describes a transaction signed by an account
+type HistoryRecord struct {
+	TxHash         string    `json:"tx_hash"`
+	Network        string    `json:"network"`
+	MuxID          *uint64   `json:"mux_id,omitempty"`
+	Source         string    `json:"source"`
+	Sequence       int64     `json:"sequence"`
+	OperationCount int       `json:"operation_count"`
+	SignedAt       time.Time `json:"signed_at"`
+	// Role is set when the transaction was signed through roles/<name>/sign
+	Role string `json:"role,omitempty"`
+	// ApprovalRequest is set when the transaction was signed once an approval request reached its quorum
+	ApprovalRequest string `json:"approval_request,omitempty"`
+	// Outcome of the submission to Horizon, when the plugin submitted the transaction
+	SubmissionStatus string `json:"submission_status,omitempty"`
+	Ledger           int32  `json:"ledger,omitempty"`
+	ResultCode       string `json:"result_code,omitempty"`
+}
+
+func historyPath(publicKey string, txHash string) string {
+	return fmt.Sprintf("stellar/history/%s/%s", publicKey, txHash)
+}
+
+// newHistoryRecord describes a transaction about to be signed. The mux ID is the one the
+// account was addressed with, or the one of the transaction source if it is the same account.
+func newHistoryRecord(tx *txnbuild.Transaction, txHash string, sr *signRequest) *HistoryRecord {
+	envelope := tx.ToXDR()
+	source := envelope.SourceAccount()
+	sourceAccountID := source.ToAccountId()
+
+	muxID := sr.muxID
+	if muxID == nil && source.Type == xdr.CryptoKeyTypeKeyTypeMuxedEd25519 && sourceAccountID.Address() == sr.publicKey {
+		id := uint64(source.Med25519.Id)
+		muxID = &id
+	}
+
+	return &HistoryRecord{
+		TxHash:          txHash,
+		Network:         sr.network,
+		MuxID:           muxID,
+		Source:          source.Address(),
+		Sequence:        tx.SequenceNumber(),
+		OperationCount:  len(envelope.Operations()),
+		SignedAt:        time.Now(),
+		Role:            sr.role,
+		ApprovalRequest: sr.approvalRequest,
+	}
+}
+
+func (m *Manager) ListHistory(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	publicKey, _, err := resolveAddress(data.Get("publicKey").(string))
+	if err != nil {
+		return nil, err
+	}
+	if err = m.checkAccountAccess(ctx, req, publicKey); err != nil {
+		return nil, err
+	}
+
+	hashes, err := req.Storage.List(ctx, fmt.Sprintf("stellar/history/%s/", publicKey))
+	if err != nil {
+		m.logger.Error("Failed to list signing history", "publicKey", publicKey, "error", err)
+		return nil, fmt.Errorf("failed to list signing history: %s", err)
+	}
+
+	keyInfo := map[string]interface{}{}
+	for _, hash := range hashes {
+		record, err := m.retrieveHistory(ctx, req.Storage, publicKey, hash)
+		if err != nil {
+			return nil, err
+		}
+		if record != nil {
+			keyInfo[hash] = record.responseData()
+		}
+	}
+
+	return logical.ListResponseWithInfo(hashes, keyInfo), nil
+}
+
+func historyDayPath(publicKey string, record *HistoryRecord) string {
+	return fmt.Sprintf("%s%s/%s.%s", historyDayPrefix, record.SignedAt.UTC().Format(historyDayLayout), publicKey, record.TxHash)
+}
+
+func (m *Manager) recordHistory(ctx context.Context, storage logical.Storage, publicKey string, record *HistoryRecord) error {
+	entry, err := logical.StorageEntryJSON(historyPath(publicKey, record.TxHash), record)
+	if err != nil {
+		return err
+	}
+	if err = storage.Put(ctx, entry); err != nil {
+		return err
+	}
+	return storage.Put(ctx, &logical.StorageEntry{Key: historyDayPath(publicKey, record), Value: []byte{}})
+}
+
+// PruneHistory deletes the history records signed before the retention of the
+// configuration, a whole day at a time.
+func (m *Manager) PruneHistory(ctx context.Context, req *logical.Request) error {
+	config, err := m.retrieveConfig(ctx, req.Storage)
+	if err != nil {
+		return err
+	}
+	cutoff := time.Now().UTC().AddDate(0, 0, -int(config.historyRetentionDays())).Format(historyDayLayout)
+
+	days, err := req.Storage.List(ctx, historyDayPrefix)
+	if err != nil {
+		return err
+	}
+	pruned := 0
+	for _, day := range days {
+		// Days sort as their keys, the ones before the cutoff have expired
+		if !strings.HasSuffix(day, "/") || strings.TrimSuffix(day, "/") >= cutoff {
+			continue
+		}
+		keys, err := req.Storage.List(ctx, historyDayPrefix+day)
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			publicKey, txHash, ok := strings.Cut(key, ".")
+			if ok {
+				if err = req.Storage.Delete(ctx, historyPath(publicKey, txHash)); err != nil {
+					return err
+				}
+				pruned++
+			}
+			if err = req.Storage.Delete(ctx, historyDayPrefix+day+key); err != nil {
+				return err
+			}
+		}
+	}
+	if pruned > 0 {
+		m.logger.Debug("Pruned expired signing history records", "count", pruned)
+	}
+	return nil
+}
+
+// historyRetentionDays returns for how many days signing history records are kept
+func (c *Config) historyRetentionDays() int64 {
+	if c.HistoryRetentionDays == 0 {
+		return defaultHistoryDays
+	}
+	return c.HistoryRetentionDays
+}
+
+func (m *Manager) retrieveHistory(ctx context.Context, storage logical.Storage, publicKey string, txHash string) (*HistoryRecord, error) {
+	entry, err := storage.Get(ctx, historyPath(publicKey, txHash))
+	if err != nil {
+		m.logger.Error("Failed to retrieve signing history record", "publicKey", publicKey, "txHash", txHash, "error", err)
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+	var record HistoryRecord
+	if err = entry.DecodeJSON(&record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (r *HistoryRecord) responseData() map[string]interface{} {
+	respData := map[string]interface{}{
+		"tx_hash":         r.TxHash,
+		"network":         r.Network,
+		"source":          r.Source,
+		"sequence":        r.Sequence,
+		"operation_count": r.OperationCount,
+		"signed_at":       r.SignedAt.Format(time.RFC3339),
+	}
+	if r.MuxID != nil {
+		respData["mux_id"] = *r.MuxID
+	}
+	if r.Role != "" {
+		respData["role"] = r.Role
+	}
+	if r.ApprovalRequest != "" {
+		respData["approval_request"] = r.ApprovalRequest
+	}
+	if r.SubmissionStatus != "" {
+		respData["submission_status"] = r.SubmissionStatus
+		respData["ledger"] = r.Ledger
+		respData["result_code"] = r.ResultCode
+	}
+	return respData
+}
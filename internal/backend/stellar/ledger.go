@@ -0,0 +1,151 @@
+package stellar
+
+import (
+	"context"
+	"errors"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stellar/go/network"
+	"sync"
+	"time"
+	"vault-plugin-stellar-sign/internal/backend/horizon"
+)
+
+const (
+	defaultHorizonCacheTTL int64 = 30
+	// maxLedgerCacheEntries bounds the number of accounts whose ledger state is cached
+	maxLedgerCacheEntries = 1000
+)
+
+// networkPassphrases maps the network names accepted by the plugin to their passphrases
+var networkPassphrases = map[string]string{
+	"Public":  network.PublicNetworkPassphrase,
+	"Testnet": network.TestNetworkPassphrase,
+}
+
+// LedgerState is the on-ledger view of a stored account
+type LedgerState struct {
+	Exists     bool
+	Sequence   string
+	Balances   []horizon.Balance
+	Thresholds horizon.Thresholds
+	SignerFor  []string
+}
+
+type ledgerCacheEntry struct {
+	state     *LedgerState
+	expiresAt time.Time
+}
+
+// ledgerCache keeps Horizon lookups for the configured TTL. Expired entries are evicted
+// when new ones are added, and the entries closest to expiry when it is full.
+type ledgerCache struct {
+	mu      sync.Mutex
+	entries map[string]ledgerCacheEntry
+}
+
+func (c *ledgerCache) get(key string, now time.Time) (*LedgerState, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || now.After(entry.expiresAt) {
+		return nil, false
+	}
+	return entry.state, true
+}
+
+func (c *ledgerCache) put(key string, state *LedgerState, expiresAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = map[string]ledgerCacheEntry{}
+	}
+	if _, ok := c.entries[key]; !ok {
+		c.evict(time.Now())
+	}
+	c.entries[key] = ledgerCacheEntry{state: state, expiresAt: expiresAt}
+}
+
+// evict makes room for a new entry
+func (c *ledgerCache) evict(now time.Time) {
+	for key, entry := range c.entries {
+		if now.After(entry.expiresAt) {
+			delete(c.entries, key)
+		}
+	}
+	for len(c.entries) >= maxLedgerCacheEntries {
+		var oldest string
+		for key, entry := range c.entries {
+			if oldest == "" || entry.expiresAt.Before(c.entries[oldest].expiresAt) {
+				oldest = key
+			}
+		}
+		delete(c.entries, oldest)
+	}
+}
+
+// horizonClient returns a client for the Horizon configured for the network
+func (c *Config) horizonClient(networkName string) (*horizon.Client, error) {
+	if _, ok := networkPassphrases[networkName]; !ok {
+		return nil, invalidNetwork(networkName)
+	}
+	horizonURL := c.HorizonURLs[networkName]
+	if horizonURL == "" {
+		return nil, invalidRequest("no Horizon URL configured for network %s", networkName)
+	}
+	return horizon.NewClient(horizonURL), nil
+}
+
+// ledgerState fetches the on-ledger state of an account from Horizon, using the cache
+// when a recent lookup is available.
+func (m *Manager) ledgerState(ctx context.Context, storage logical.Storage, networkName string, accountID string) (*LedgerState, error) {
+	config, err := m.retrieveConfig(ctx, storage)
+	if err != nil {
+		return nil, err
+	}
+	client, err := config.horizonClient(networkName)
+	if err != nil {
+		return nil, err
+	}
+
+	cacheKey := networkName + "/" + accountID
+	now := time.Now()
+	if state, ok := m.ledgerCache.get(cacheKey, now); ok {
+		return state, nil
+	}
+
+	state := &LedgerState{Balances: []horizon.Balance{}}
+	account, err := client.Account(ctx, accountID)
+	switch {
+	case errors.Is(err, horizon.ErrNotFound):
+	case err != nil:
+		m.logger.Error("Failed to fetch account from Horizon", "network", networkName, "accountID", accountID, "error", err)
+		return nil, horizonUnavailable("failed to fetch account from Horizon: %s", err)
+	default:
+		state.Exists = true
+		state.Sequence = account.Sequence
+		state.Balances = account.Balances
+		state.Thresholds = account.Thresholds
+	}
+
+	state.SignerFor, err = client.AccountsForSigner(ctx, accountID)
+	if err != nil && !errors.Is(err, horizon.ErrNotFound) {
+		m.logger.Error("Failed to fetch accounts for signer from Horizon", "network", networkName, "accountID", accountID, "error", err)
+		return nil, horizonUnavailable("failed to fetch accounts for signer from Horizon: %s", err)
+	}
+	if state.SignerFor == nil {
+		state.SignerFor = []string{}
+	}
+
+	m.ledgerCache.put(cacheKey, state, now.Add(time.Duration(config.horizonCacheTTL())*time.Second))
+	return state, nil
+}
+
+func (s *LedgerState) responseData() map[string]interface{} {
+	return map[string]interface{}{
+		"exists":     s.Exists,
+		"sequence":   s.Sequence,
+		"balances":   s.Balances,
+		"thresholds": s.Thresholds,
+		"signer_for": s.SignerFor,
+	}
+}
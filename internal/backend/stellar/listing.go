@@ -0,0 +1,363 @@
+package stellar
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// accountIndexPrefix holds one key per account in public key order, carrying its
+	// creation time and name, so that listings filter without reading the account metadata
+	accountIndexPrefix = "stellar/account-index/"
+	// accountNameIndexPrefix and accountCreatedIndexPrefix hold the same entries under
+	// keys that sort by name and by creation time
+	accountNameIndexPrefix    = "stellar/account-index-name/"
+	accountCreatedIndexPrefix = "stellar/account-index-created/"
+	// deletedAccountIndexPrefix keeps the index entries of deleted accounts for a day, so
+	// that a listing continues after an account deleted between two pages
+	deletedAccountIndexPrefix = "stellar/account-index-deleted/"
+	deletedAccountIndexTTL    = 24 * time.Hour
+	// accountListBatch is how many listed accounts are access-checked at a time when no
+	// limit is given
+	accountListBatch = 100
+
+	SortByPublicKey = "public_key"
+	SortByName      = "name"
+	SortByCreatedAt = "created_at"
+)
+
+// accountIndexEntry is the part of an account encoded in its index keys
+type accountIndexEntry struct {
+	publicKey string
+	createdAt int64
+	name      string
+}
+
+func newAccountIndexEntry(account *Account) accountIndexEntry {
+	entry := accountIndexEntry{publicKey: account.PublicKey, name: account.Name}
+	if !account.CreatedAt.IsZero() {
+		entry.createdAt = account.CreatedAt.UnixNano()
+	}
+	return entry
+}
+
+func accountIndexPrefixFor(sortBy string) string {
+	switch sortBy {
+	case SortByName:
+		return accountNameIndexPrefix
+	case SortByCreatedAt:
+		return accountCreatedIndexPrefix
+	}
+	return accountIndexPrefix
+}
+
+// indexKey encodes the entry so that the keys of an index sort in its order:
+// <public key>.<creation time in ns>.<hex name>, <hex name>.<public key>, or
+// <zero-padded creation time in ns>.<public key>.<hex name>
+func (e accountIndexEntry) indexKey(sortBy string) string {
+	name := hex.EncodeToString([]byte(e.name))
+	switch sortBy {
+	case SortByName:
+		return fmt.Sprintf("%s.%s", name, e.publicKey)
+	case SortByCreatedAt:
+		return fmt.Sprintf("%020d.%s.%s", e.createdAt, e.publicKey, name)
+	}
+	return fmt.Sprintf("%s.%d.%s", e.publicKey, e.createdAt, name)
+}
+
+// parseAccountIndexKey decodes a key of the index of the sort order. Keys of the name
+// index carry no creation time.
+func parseAccountIndexKey(sortBy string, key string) (accountIndexEntry, bool) {
+	var publicKey, createdAt, name string
+	switch sortBy {
+	case SortByName:
+		parts := strings.SplitN(key, ".", 2)
+		if len(parts) != 2 {
+			return accountIndexEntry{}, false
+		}
+		name, publicKey, createdAt = parts[0], parts[1], "0"
+	case SortByCreatedAt:
+		parts := strings.SplitN(key, ".", 3)
+		if len(parts) != 3 {
+			return accountIndexEntry{}, false
+		}
+		createdAt, publicKey, name = parts[0], parts[1], parts[2]
+	default:
+		parts := strings.SplitN(key, ".", 3)
+		if len(parts) != 3 {
+			return accountIndexEntry{}, false
+		}
+		publicKey, createdAt, name = parts[0], parts[1], parts[2]
+	}
+	entry := accountIndexEntry{publicKey: publicKey}
+	var err error
+	if entry.createdAt, err = strconv.ParseInt(createdAt, 10, 64); err != nil {
+		return accountIndexEntry{}, false
+	}
+	decoded, err := hex.DecodeString(name)
+	if err != nil {
+		return accountIndexEntry{}, false
+	}
+	entry.name = string(decoded)
+	return entry, true
+}
+
+func (m *Manager) indexAccount(ctx context.Context, storage logical.Storage, account *Account) error {
+	entry := newAccountIndexEntry(account)
+	for _, sortBy := range []string{SortByPublicKey, SortByName, SortByCreatedAt} {
+		key := accountIndexPrefixFor(sortBy) + entry.indexKey(sortBy)
+		if err := storage.Put(ctx, &logical.StorageEntry{Key: key, Value: []byte{}}); err != nil {
+			return fmt.Errorf("failed to index account %s: %s", account.PublicKey, err)
+		}
+	}
+	return nil
+}
+
+func (m *Manager) unindexAccount(ctx context.Context, storage logical.Storage, account *Account) error {
+	entry := newAccountIndexEntry(account)
+	for _, sortBy := range []string{SortByPublicKey, SortByName, SortByCreatedAt} {
+		if err := storage.Delete(ctx, accountIndexPrefixFor(sortBy)+entry.indexKey(sortBy)); err != nil {
+			return fmt.Errorf("failed to remove account %s from the index: %s", account.PublicKey, err)
+		}
+	}
+	return nil
+}
+
+// reindexAccount replaces the index keys of the account when its name changed
+func (m *Manager) reindexAccount(ctx context.Context, storage logical.Storage, previous *Account, account *Account) error {
+	if newAccountIndexEntry(previous) == newAccountIndexEntry(account) {
+		return nil
+	}
+	if err := m.unindexAccount(ctx, storage, previous); err != nil {
+		return err
+	}
+	return m.indexAccount(ctx, storage, account)
+}
+
+// deletedAccountIndex is the index entry of a deleted account
+type deletedAccountIndex struct {
+	IndexKey  string    `json:"index_key"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// rememberDeletedAccount keeps the position of a deleted account in the listings
+func (m *Manager) rememberDeletedAccount(ctx context.Context, storage logical.Storage, account *Account) error {
+	entry, err := logical.StorageEntryJSON(deletedAccountIndexPrefix+account.PublicKey, &deletedAccountIndex{
+		IndexKey:  newAccountIndexEntry(account).indexKey(SortByPublicKey),
+		DeletedAt: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	return storage.Put(ctx, entry)
+}
+
+// PruneDeletedAccounts forgets the listing positions of accounts deleted over a day ago
+func (m *Manager) PruneDeletedAccounts(ctx context.Context, req *logical.Request) error {
+	publicKeys, err := req.Storage.List(ctx, deletedAccountIndexPrefix)
+	if err != nil {
+		return err
+	}
+	for _, publicKey := range publicKeys {
+		entry, err := req.Storage.Get(ctx, deletedAccountIndexPrefix+publicKey)
+		if err != nil {
+			return err
+		}
+		if entry == nil {
+			continue
+		}
+		var deleted deletedAccountIndex
+		if err = entry.DecodeJSON(&deleted); err != nil || time.Since(deleted.DeletedAt) > deletedAccountIndexTTL {
+			if err = req.Storage.Delete(ctx, deletedAccountIndexPrefix+publicKey); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// migrateAccountIndex indexes the accounts stored before the index existed
+func (m *Manager) migrateAccountIndex(ctx context.Context, storage logical.Storage) error {
+	publicKeys, err := storage.List(ctx, "stellar/accounts/")
+	if err != nil {
+		return fmt.Errorf("failed to list stellar accounts: %s", err)
+	}
+	for _, publicKey := range publicKeys {
+		account, err := m.retrieveAccount(ctx, storage, publicKey)
+		if err != nil {
+			return err
+		}
+		if account == nil {
+			continue
+		}
+		if err = m.indexAccount(ctx, storage, account); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// accountListing selects a page of the account index
+type accountListing struct {
+	after  string
+	limit  int
+	sortBy string
+	prefix string
+}
+
+func accountListingFromFieldData(data *framework.FieldData) (*accountListing, error) {
+	listing := &accountListing{
+		after:  data.Get("after").(string),
+		limit:  data.Get("limit").(int),
+		sortBy: data.Get("sort").(string),
+		prefix: data.Get("prefix").(string),
+	}
+	if listing.limit < 0 {
+		return nil, invalidRequest("limit must not be negative")
+	}
+	switch listing.sortBy {
+	case SortByPublicKey, SortByName, SortByCreatedAt:
+	default:
+		return nil, invalidRequest("sort must be one of %s, %s or %s", SortByPublicKey, SortByName, SortByCreatedAt)
+	}
+	return listing, nil
+}
+
+// matches tells whether the address or the name of the account starts with the prefix
+func (l *accountListing) matches(entry accountIndexEntry) bool {
+	return strings.HasPrefix(entry.publicKey, l.prefix) || strings.HasPrefix(entry.name, l.prefix)
+}
+
+// cursor returns the index key the listing starts after. The other orders position the
+// after account by its index entry, which is kept for a day once it was deleted.
+func (m *Manager) cursor(ctx context.Context, storage logical.Storage, l *accountListing) (string, error) {
+	if l.after == "" {
+		return "", nil
+	}
+	if l.sortBy == SortByPublicKey {
+		// Sorts after every key of the account, whose public key is followed by a digit
+		return l.after + ".~", nil
+	}
+	entry, err := m.accountIndexEntry(ctx, storage, l.after)
+	if err != nil {
+		return "", err
+	}
+	if entry == nil {
+		return "", invalidRequest("after must be an account of the listing: %s", l.after)
+	}
+	return entry.indexKey(l.sortBy), nil
+}
+
+// accountIndexEntry returns the index entry of a stored or recently deleted account
+func (m *Manager) accountIndexEntry(ctx context.Context, storage logical.Storage, publicKey string) (*accountIndexEntry, error) {
+	account, err := m.retrieveAccount(ctx, storage, publicKey)
+	if err != nil {
+		return nil, err
+	}
+	if account != nil {
+		entry := newAccountIndexEntry(account)
+		return &entry, nil
+	}
+	stored, err := storage.Get(ctx, deletedAccountIndexPrefix+publicKey)
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil {
+		return nil, nil
+	}
+	var deleted deletedAccountIndex
+	if err = stored.DecodeJSON(&deleted); err != nil {
+		return nil, err
+	}
+	entry, ok := parseAccountIndexKey(SortByPublicKey, deleted.IndexKey)
+	if !ok {
+		return nil, nil
+	}
+	return &entry, nil
+}
+
+// listAccountPage returns up to limit accessible accounts of the listing, all of them
+// when limit is 0. The index of the sort order is listed once and paged from the cursor.
+func (m *Manager) listAccountPage(ctx context.Context, req *logical.Request, listing *accountListing) ([]string, error) {
+	cursor, err := m.cursor(ctx, req.Storage, listing)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := req.Storage.List(ctx, accountIndexPrefixFor(listing.sortBy))
+	if err != nil {
+		m.logger.Error("Failed to list the account index", "error", err)
+		return nil, fmt.Errorf("failed to list stellar accounts: %s", err)
+	}
+	sort.Strings(keys)
+	keys = keys[sort.SearchStrings(keys, cursor):]
+	if len(keys) > 0 && keys[0] == cursor {
+		keys = keys[1:]
+	}
+
+	var candidates []string
+	for _, key := range keys {
+		if entry, ok := parseAccountIndexKey(listing.sortBy, key); ok && listing.matches(entry) {
+			candidates = append(candidates, entry.publicKey)
+		}
+	}
+	batch := listing.limit
+	if batch == 0 {
+		batch = accountListBatch
+	}
+
+	// Inaccessible accounts are skipped, so pages are filled batch by batch to only read
+	// the accounts the page needs
+	page := []string{}
+	for start := 0; start < len(candidates); start += batch {
+		end := start + batch
+		if end > len(candidates) {
+			end = len(candidates)
+		}
+		visible, err := m.filterAccessibleAccounts(ctx, req, candidates[start:end])
+		if err != nil {
+			return nil, err
+		}
+		for _, publicKey := range visible {
+			page = append(page, publicKey)
+			if len(page) == listing.limit {
+				return page, nil
+			}
+		}
+	}
+	return page, nil
+}
+
+// accountKeyInfo describes the listed accounts with their metadata, and their on-ledger
+// state when a network is given
+func (m *Manager) accountKeyInfo(ctx context.Context, storage logical.Storage, publicKeys []string, networkName string) (map[string]interface{}, error) {
+	keyInfo := map[string]interface{}{}
+	for _, publicKey := range publicKeys {
+		account, err := m.retrieveAccount(ctx, storage, publicKey)
+		if err != nil {
+			return nil, err
+		}
+		if account == nil {
+			continue
+		}
+		info := account.responseData()
+		if networkName != "" {
+			state, err := m.ledgerState(ctx, storage, networkName, publicKey)
+			if err != nil {
+				return nil, err
+			}
+			info["network"] = networkName
+			for k, v := range state.responseData() {
+				info[k] = v
+			}
+		}
+		keyInfo[publicKey] = info
+	}
+	return keyInfo, nil
+}
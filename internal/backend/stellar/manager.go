@@ -2,39 +2,100 @@ package stellar
 
 import (
 	"context"
+	"crypto/cipher"
 	"fmt"
 	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
 	"github.com/stellar/go/keypair"
-	"github.com/stellar/go/network"
 	"github.com/stellar/go/txnbuild"
+	"strconv"
+	"sync"
+	"time"
 )
 
 // Account is the structure of a Stellar account
 type Account struct {
 	PublicKey string `json:"public_key"`
-	SecretKey string `json:"secret_key,omitempty"`
+	// SecretKey is stored encrypted under stellar/keys/, apart from the metadata. Only
+	// accounts stored before storage schema version 1 hold it with the metadata.
+	SecretKey      string            `json:"secret_key,omitempty"`
+	Policy         string            `json:"policy,omitempty"`
+	AllowedSources []string          `json:"allowed_sources,omitempty"`
+	MuxPolicies    map[string]string `json:"mux_policies,omitempty"`
+	// Name is an optional label of the account, listings filter and sort on it
+	Name string `json:"name,omitempty"`
+	// FundedBy is the stored account that created the account on-ledger, in the
+	// transaction FundingTransaction is the hash of
+	FundedBy           string `json:"funded_by,omitempty"`
+	FundingTransaction string `json:"funding_transaction,omitempty"`
+	// IssuedBy is the role an ephemeral account was issued for
+	IssuedBy string `json:"issued_by,omitempty"`
+	// OwnerEntity and OwnerGroup own the account, SharedWith lists other entities that may use it
+	OwnerEntity string   `json:"owner_entity,omitempty"`
+	OwnerGroup  string   `json:"owner_group,omitempty"`
+	SharedWith  []string `json:"shared_with,omitempty"`
+	// Approval requires a quorum of approvers before the account signs
+	Approval *ApprovalRule `json:"approval,omitempty"`
+	// MaxSignatures caps the signatures the account may ever produce, NotBefore and
+	// NotAfter bound the period it may sign in
+	MaxSignatures uint64     `json:"max_signatures,omitempty"`
+	NotBefore     *time.Time `json:"not_before,omitempty"`
+	NotAfter      *time.Time `json:"not_after,omitempty"`
+	// Usage counters, updated whenever the account signs
+	CreatedAt         time.Time         `json:"created_at"`
+	SignatureCount    uint64            `json:"signature_count,omitempty"`
+	NetworkSignatures map[string]uint64 `json:"network_signatures,omitempty"`
+	LastSignedAt      *time.Time        `json:"last_signed_at,omitempty"`
 }
 
 type Manager struct {
-	logger hclog.Logger
+	logger       hclog.Logger
+	system       func() logical.SystemView
+	events       logical.EventSender
+	replayLock   sync.Mutex
+	sequenceLock sync.Mutex
+	channelLock  sync.Mutex
+	approvalLock sync.Mutex
+	ledgerCache  ledgerCache
+	// keyring is the cipher of the data encryption key the seeds are stored with, which
+	// is wrapped with keyEncryptionSecret
+	keyringLock         sync.Mutex
+	keyring             cipher.AEAD
+	keyEncryptionSecret []byte
+	// storage is the storage of the backend, for work that outlives a request
+	storage logical.Storage
 }
 
-func NewManager(logger hclog.Logger) *Manager {
-	return &Manager{logger: logger}
+func NewManager(logger hclog.Logger, system func() logical.SystemView, events logical.EventSender, keyEncryptionSecret string) *Manager {
+	return &Manager{logger: logger, system: system, events: events, keyEncryptionSecret: []byte(keyEncryptionSecret)}
+}
+
+// SetStorage gives the manager the storage of the backend, valid for its lifetime
+func (m *Manager) SetStorage(storage logical.Storage) {
+	m.storage = storage
 }
 
 func (m *Manager) ListAccounts(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	// List all the stored accounts under the "stellar/accounts/" path
-	accountList, err := req.Storage.List(ctx, "stellar/accounts/")
+	listing, err := accountListingFromFieldData(data)
 	if err != nil {
-		m.logger.Error("Failed to list stellar accounts", "error", err)
-		return nil, fmt.Errorf("failed to list stellar accounts: %s", err)
+		return nil, err
+	}
+	accountList, err := m.listAccountPage(ctx, req, listing)
+	if err != nil {
+		return nil, err
+	}
+	networkName := data.Get("network").(string)
+	if !data.Get("detailed").(bool) && networkName == "" {
+		return logical.ListResponse(accountList), nil
 	}
 
-	// Return the list of accounts
-	return logical.ListResponse(accountList), nil
+	// Detailed listings carry the metadata and usage of every listed account
+	keyInfo, err := m.accountKeyInfo(ctx, req.Storage, accountList, networkName)
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponseWithInfo(accountList, keyInfo), nil
 }
 
 func (m *Manager) CreateAccount(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
@@ -46,7 +107,7 @@ func (m *Manager) CreateAccount(ctx context.Context, req *logical.Request, data
 		pair, err = keypair.ParseFull(secretKeyInput)
 		if err != nil {
 			m.logger.Error("Error parsing input secret key", "error", err)
-			return nil, fmt.Errorf("error parsing input secret key")
+			return nil, invalidRequest("error parsing input secret key")
 		}
 	} else {
 		pair, err = keypair.Random()
@@ -59,24 +120,104 @@ func (m *Manager) CreateAccount(ctx context.Context, req *logical.Request, data
 	publicKey := pair.Address()
 	secretKey := pair.Seed()
 
-	accountPath := fmt.Sprintf("stellar/accounts/%s", publicKey)
+	// Importing a key must not overwrite the account, its metadata and usage
+	existing, err := req.Storage.Get(ctx, fmt.Sprintf("stellar/accounts/%s", publicKey))
+	if err != nil {
+		m.logger.Error("Failed to check for an existing account", "publicKey", publicKey, "error", err)
+		return nil, err
+	}
+	if existing != nil {
+		return nil, conflict(ErrCodeAccountExists, "stellar account %s already exists", publicKey)
+	}
+
+	policyName := data.Get("policy").(string)
+	if err = m.validatePolicyReference(ctx, req.Storage, policyName); err != nil {
+		return nil, err
+	}
 
-	accountJSON := &Account{
-		PublicKey: publicKey,
-		SecretKey: secretKey,
+	// New accounts only sign for themselves unless told otherwise
+	allowedSources := []string{publicKey}
+	if sources, ok := data.GetOk("allowed_sources"); ok {
+		if allowedSources, err = parseAllowedSources(sources.([]string)); err != nil {
+			return nil, err
+		}
 	}
 
-	entry, _ := logical.StorageEntryJSON(accountPath, accountJSON)
-	err = req.Storage.Put(ctx, entry)
+	ownerGroup, err := m.ownerGroupID(ctx, req, data.Get("owner_group").(string))
 	if err != nil {
+		return nil, err
+	}
+
+	// A funding account creates the account on-ledger once it is stored
+	funding, err := accountFundingFromFieldData(data)
+	if err != nil {
+		return nil, err
+	}
+	var funder *Account
+	if funding != nil {
+		if funder, _, err = m.retrieveAccessibleAccount(ctx, req, funding.accountID); err != nil {
+			return nil, err
+		}
+		if funder == nil {
+			return nil, accountNotFound(funding.accountID)
+		}
+	}
+
+	accountJSON := &Account{
+		PublicKey:      publicKey,
+		SecretKey:      secretKey,
+		Name:           data.Get("name").(string),
+		Policy:         policyName,
+		AllowedSources: allowedSources,
+		OwnerEntity:    req.EntityID,
+		OwnerGroup:     ownerGroup,
+		CreatedAt:      time.Now(),
+	}
+	if err = accountLimitsFromFieldData(accountJSON, data); err != nil {
+		return nil, err
+	}
+
+	if err = m.createAccount(ctx, req.Storage, accountJSON); err != nil {
 		m.logger.Error("Failed to save the new stellar account to storage", "error", err)
 		return nil, err
 	}
 
+	respData := map[string]interface{}{
+		"public_key": accountJSON.PublicKey,
+	}
+	var signed *logical.Response
+	if funding != nil {
+		// Nothing was signed when funding fails, the account is not kept
+		if signed, err = m.fundAccount(ctx, req.Storage, funder, accountJSON, funding); err != nil {
+			if errDelete := m.deleteAccount(ctx, req.Storage, accountJSON); errDelete != nil {
+				m.logger.Error("Failed to delete the unfunded account", "publicKey", publicKey, "error", errDelete)
+			}
+			return nil, err
+		}
+		accountJSON.FundedBy = funder.PublicKey
+		accountJSON.FundingTransaction = signed.Data["transaction_hash"].(string)
+		if err = m.saveAccount(ctx, req.Storage, accountJSON); err != nil {
+			return nil, fmt.Errorf("the funding transaction of account %s was signed but recording it failed: %s", publicKey, err)
+		}
+		respData["funding_transaction"] = signed.Data["signed_transaction"]
+		respData["funding_transaction_hash"] = accountJSON.FundingTransaction
+	}
+
+	eventType := EventAccountCreate
+	if secretKeyInput != "" {
+		eventType = EventAccountImport
+	}
+	m.sendEvent(ctx, eventType, "public_key", publicKey)
+
+	// The account is kept when the submission fails, the signed transaction may still land
+	if funding != nil {
+		if err = m.submitFunding(ctx, req.Storage, funder, funding, signed, respData); err != nil {
+			return nil, fmt.Errorf("account %s was created but submitting its funding transaction failed: %w", publicKey, err)
+		}
+	}
+
 	return &logical.Response{
-		Data: map[string]interface{}{
-			"public_key": accountJSON.PublicKey,
-		},
+		Data: respData,
 	}, nil
 }
 
@@ -87,29 +228,113 @@ func (m *Manager) ReadAccount(ctx context.Context, req *logical.Request, data *f
 
 	publicKey := data.Get("publicKey").(string)
 	if publicKey == "" {
-		return nil, fmt.Errorf("missing public key")
+		return nil, invalidRequest("missing public key")
+	}
+
+	address := publicKey
+	publicKey, muxID, err := resolveAddress(address)
+	if err != nil {
+		return nil, err
 	}
 
 	m.logger.Info("Retrieving Stellar account for public key", "publicKey", publicKey)
-	account, err := m.retrieveAccount(ctx, req.Storage, publicKey)
+	account, _, err := m.retrieveAccessibleAccount(ctx, req, publicKey)
 	if err != nil {
 		return nil, err
 	}
 	if account == nil {
-		return nil, fmt.Errorf("stellar account does not exist")
+		return nil, notFound(ErrCodeAccountNotFound, "stellar account does not exist")
+	}
+
+	respData := account.responseData()
+	if muxID != nil {
+		respData["muxed_address"] = address
+		respData["mux_id"] = *muxID
+		respData["policy"] = account.policyFor(muxID)
+	}
+
+	// Enrich the response with the on-ledger state when a network is requested
+	if networkName := data.Get("network").(string); networkName != "" {
+		state, err := m.ledgerState(ctx, req.Storage, networkName, account.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		respData["network"] = networkName
+		for k, v := range state.responseData() {
+			respData[k] = v
+		}
+	}
+	return &logical.Response{
+		Data: respData,
+	}, nil
+}
+
+func (m *Manager) UpdateAccount(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	publicKey := data.Get("publicKey").(string)
+
+	account, access, err := m.retrieveAccessibleAccount(ctx, req, publicKey)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		return nil, notFound(ErrCodeAccountNotFound, "stellar account does not exist")
+	}
+	if access != accessOwner {
+		return nil, forbidden(ErrCodePermissionDenied, "only the owner of the account can update it")
+	}
+	previous := *account
+
+	if name, ok := data.GetOk("name"); ok {
+		account.Name = name.(string)
+	}
+
+	if policyName, ok := data.GetOk("policy"); ok {
+		if err = m.validatePolicyReference(ctx, req.Storage, policyName.(string)); err != nil {
+			return nil, err
+		}
+		account.Policy = policyName.(string)
+	}
+	if sources, ok := data.GetOk("allowed_sources"); ok {
+		if account.AllowedSources, err = parseAllowedSources(sources.([]string)); err != nil {
+			return nil, err
+		}
+	}
+	if muxPolicies, ok := data.GetOk("mux_policies"); ok {
+		if account.MuxPolicies, err = m.parseMuxPolicies(ctx, req.Storage, muxPolicies.(map[string]string)); err != nil {
+			return nil, err
+		}
+	}
+	if ownerGroup, ok := data.GetOk("owner_group"); ok {
+		if account.OwnerGroup, err = m.ownerGroupID(ctx, req, ownerGroup.(string)); err != nil {
+			return nil, err
+		}
+	}
+	if sharedWith, ok := data.GetOk("shared_with"); ok {
+		account.SharedWith = sharedWith.([]string)
+	}
+	if account.Approval, err = approvalRuleFromFieldData(account.Approval, data); err != nil {
+		return nil, err
+	}
+	if err = accountLimitsFromFieldData(account, data); err != nil {
+		return nil, err
+	}
+
+	if err = m.saveAccount(ctx, req.Storage, account); err != nil {
+		return nil, err
+	}
+	if err = m.reindexAccount(ctx, req.Storage, &previous, account); err != nil {
+		return nil, err
 	}
 
 	return &logical.Response{
-		Data: map[string]interface{}{
-			"public_key": account.PublicKey,
-		},
+		Data: account.responseData(),
 	}, nil
 }
 
 func (m *Manager) DeleteAccount(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	publicKey := data.Get("publicKey").(string)
 
-	account, err := m.retrieveAccount(ctx, req.Storage, publicKey)
+	account, access, err := m.retrieveAccessibleAccount(ctx, req, publicKey)
 	if err != nil {
 		m.logger.Error("Failed to retrieve the Stellar account by public key", "publicKey", publicKey, "error", err)
 		return nil, err
@@ -117,45 +342,79 @@ func (m *Manager) DeleteAccount(ctx context.Context, req *logical.Request, data
 	if account == nil {
 		return nil, nil
 	}
-	if err = req.Storage.Delete(ctx, fmt.Sprintf("stellar/accounts/%s", account.PublicKey)); err != nil {
+	if access != accessOwner {
+		return nil, forbidden(ErrCodePermissionDenied, "only the owner of the account can delete it")
+	}
+	if err = m.deleteAccount(ctx, req.Storage, account); err != nil {
 		m.logger.Error("Failed to delete the Stellar account from storage", "publicKey", publicKey, "error", err)
 		return nil, err
 	}
+	m.sendEvent(ctx, EventAccountDelete, "public_key", account.PublicKey)
 	return nil, nil
 }
 
 type signRequest struct {
 	publicKey         string
+	muxID             *uint64
+	network           string
 	txEnvelopeBase64  string
 	networkPassphrase string
+	idempotencyKey    string
+	submit            bool
+	async             bool
+	// channel is the leased channel account that is the transaction source and co-signs it
+	channel *channelSigner
+	// cosigner is an account signing the operations it is the source of, such as a new
+	// account signing the sponsorship of its reserves
+	cosigner *Account
+	// role and rolePolicy are set when signing through roles/<name>/sign
+	role       string
+	rolePolicy string
+	// approvalRequest is the ID of the approval request whose quorum released the signature
+	approvalRequest string
+	// queueApproval turns a transaction requiring approval into a pending approval
+	// request of the requester entity instead of failing
+	queueApproval bool
+	requester     string
 }
 
 func validateSignRequest(data *framework.FieldData) (*signRequest, error) {
-	publicKey := data.Get("publicKey").(string)
-	if publicKey == "" {
-		return nil, fmt.Errorf("publicKey must be provided")
+	sr, err := parseSignRequest(data.Get("publicKey").(string), data)
+	if err != nil {
+		return nil, err
+	}
+
+	sr.txEnvelopeBase64 = data.Get("transaction").(string)
+	if sr.txEnvelopeBase64 == "" {
+		return nil, invalidRequest("transaction must be provided")
 	}
+	return sr, nil
+}
 
-	txEnvelopeBase64 := data.Get("transaction").(string)
-	if txEnvelopeBase64 == "" {
-		return nil, fmt.Errorf("transaction must be provided")
+// parseSignRequest reads the fields shared by the endpoints that sign a transaction
+func parseSignRequest(publicKey string, data *framework.FieldData) (*signRequest, error) {
+	if publicKey == "" {
+		return nil, invalidRequest("publicKey must be provided")
+	}
+	publicKey, muxID, err := resolveAddress(publicKey)
+	if err != nil {
+		return nil, err
 	}
 
 	networkParam := data.Get("network").(string)
-	var networkPassphrase string
-	switch networkParam {
-	case "Public":
-		networkPassphrase = network.PublicNetworkPassphrase
-	case "Testnet":
-		networkPassphrase = network.TestNetworkPassphrase
-	default:
-		return nil, fmt.Errorf("invalid network: %s", networkParam)
+	networkPassphrase, ok := networkPassphrases[networkParam]
+	if !ok {
+		return nil, invalidNetwork(networkParam)
 	}
 
 	return &signRequest{
 		publicKey:         publicKey,
-		txEnvelopeBase64:  txEnvelopeBase64,
+		muxID:             muxID,
+		network:           networkParam,
 		networkPassphrase: networkPassphrase,
+		idempotencyKey:    data.Get("idempotency_key").(string),
+		submit:            data.Get("submit").(bool),
+		async:             data.Get("async").(bool),
 	}, nil
 }
 
@@ -166,49 +425,251 @@ func (m *Manager) SignTx(ctx context.Context, req *logical.Request, data *framew
 	}
 
 	// Retrieve the account from storage
-	account, err := m.retrieveAccount(ctx, req.Storage, sr.publicKey)
+	account, _, err := m.retrieveAccessibleAccount(ctx, req, sr.publicKey)
 	if err != nil {
 		m.logger.Error("Error retrieving account", "error", err)
-		return nil, fmt.Errorf("error retrieving account: %s", err)
+		return nil, fmt.Errorf("error retrieving account: %w", err)
 	}
 	if account == nil {
-		return nil, fmt.Errorf("account not found")
+		return nil, notFound(ErrCodeAccountNotFound, "account not found")
 	}
 
-	signedTxBase64, errSign := m.sign(account, sr.txEnvelopeBase64, sr.networkPassphrase)
-	if errSign != nil {
-		m.logger.Error("Error signing transaction", "error", errSign)
-		return nil, fmt.Errorf("error signing transaction: %s", errSign)
+	tx, err := m.decodeTransaction(sr.txEnvelopeBase64)
+	if err != nil {
+		return nil, err
+	}
+
+	sr.queueApproval = true
+	sr.requester = req.EntityID
+	return m.signTransaction(ctx, req.Storage, account, tx, sr)
+}
+
+// signTransaction runs a decoded or built transaction through source binding, the
+// signing policies and replay protection, signs it and optionally submits it.
+func (m *Manager) signTransaction(ctx context.Context, storage logical.Storage, account *Account,
+	tx *txnbuild.Transaction, sr *signRequest) (*logical.Response, error) {
+	config, err := m.retrieveConfig(ctx, storage)
+	if err != nil {
+		return nil, err
+	}
+	if err = account.checkUsable(time.Now()); err != nil {
+		m.logger.Warn("Refusing to sign with the account", "publicKey", account.PublicKey, "error", err)
+		return nil, err
+	}
+	if err = m.checkTransaction(ctx, storage, config, account, tx, sr); err != nil {
+		return nil, err
+	}
+	if sr.approvalRequest == "" {
+		rule, err := m.requiredApproval(ctx, storage, config, account, tx, sr)
+		if err != nil {
+			m.sendDenialEvent(ctx, account, tx, sr, err)
+			countDenial(config, account, sr, err)
+			return nil, err
+		}
+		if rule.required() {
+			if !sr.queueApproval {
+				return nil, forbidden(ErrCodeApprovalRequired, "the transaction requires %d approvals, submit it at requests", rule.Quorum)
+			}
+			return m.queueApproval(ctx, storage, account, tx, sr, rule, "")
+		}
+	}
+
+	txHash, err := tx.HashHex(sr.networkPassphrase)
+	if err != nil {
+		return nil, fmt.Errorf("error hashing transaction: %s", err)
+	}
+
+	signedTxBase64, replayed, err := m.signOnce(ctx, storage, config, account, tx, txHash, sr)
+	if err != nil {
+		return nil, err
+	}
+	// A retried request was already signed, counted and submitted
+	if !replayed {
+		m.sendSignEvent(ctx, account, tx, txHash, sr)
+		countSignature(config, account, sr)
+	}
+
+	// The transaction metadata lets audit logs, where the envelopes are HMACed, be
+	// correlated with the ledger
+	envelope := tx.ToXDR()
+	source := envelope.SourceAccount()
+	respData := map[string]interface{}{
+		"signed_transaction": signedTxBase64,
+		"transaction_hash":   txHash,
+		"source":             source.Address(),
+		"sequence":           strconv.FormatInt(tx.SequenceNumber(), 10),
+		"operation_count":    len(envelope.Operations()),
+	}
+	if sr.submit && !replayed {
+		submission := &Submission{
+			TxHash:     txHash,
+			PublicKey:  account.PublicKey,
+			Network:    sr.network,
+			TxEnvelope: signedTxBase64,
+		}
+		if sr.channel != nil {
+			submission.Channel = sr.channel.lease
+		}
+		if err = m.submit(ctx, storage, config, submission, sr.async); err != nil {
+			m.logger.Warn("Transaction submission did not succeed", "publicKey", account.PublicKey, "txHash", txHash, "error", err)
+			return nil, err
+		}
+		respData["submission_status"] = submission.Status
+		if submission.Ledger != 0 {
+			respData["ledger"] = submission.Ledger
+		}
 	}
 	return &logical.Response{
-		Data: map[string]interface{}{
-			"signed_transaction": signedTxBase64,
-		},
+		Data: respData,
 	}, nil
 }
 
-func (m *Manager) sign(account *Account, txEnvelopeBase64 string, networkPassphrase string) (string, error) {
+// checkTransaction checks the source binding of the account and the signing policies
+func (m *Manager) checkTransaction(ctx context.Context, storage logical.Storage, config *Config, account *Account,
+	tx *txnbuild.Transaction, sr *signRequest) error {
+	bindingErr := account.checkSourceBinding(tx.ToXDR())
+	switch {
+	case sr.channel != nil:
+		bindingErr = account.checkChannelSourceBinding(sr.channel.account.PublicKey, tx.ToXDR())
+	case sr.cosigner != nil:
+		bindingErr = account.checkCosignedSourceBinding(sr.cosigner, tx.ToXDR())
+	}
+	if bindingErr != nil {
+		m.logger.Warn("Transaction denied by source account binding", "publicKey", account.PublicKey, "error", bindingErr)
+		return bindingErr
+	}
+
+	for _, policyName := range account.signingPolicies(config, tx, sr) {
+		if err := m.enforcePolicy(ctx, storage, policyName, tx); err != nil {
+			m.logger.Warn("Transaction denied by signing policy", "publicKey", account.PublicKey, "error", err)
+			m.sendDenialEvent(ctx, account, tx, sr, err)
+			countDenial(config, account, sr, err)
+			return err
+		}
+	}
+	// Checked after the policies, so that a validity policy reports its own max_time rule
+	if err := checkNotExpired(tx.ToXDR(), time.Now()); err != nil {
+		m.logger.Warn("Refusing to sign an expired transaction", "publicKey", account.PublicKey, "error", err)
+		return err
+	}
+	return nil
+}
+
+// signingPolicies returns the policies a transaction is evaluated against. The
+// mount-wide policy applies to every account, in addition to the policy of the role
+// signing and the policy of every mux ID the account signs as: the one it was addressed
+// with, and those of the transaction and operation sources.
+func (a *Account) signingPolicies(config *Config, tx *txnbuild.Transaction, sr *signRequest) []string {
+	policies := []string{config.Policy, sr.rolePolicy}
+	if sr.muxID != nil {
+		policies = append(policies, a.policyFor(sr.muxID))
+	}
+	for _, muxID := range a.sourceMuxIDs(tx.ToXDR()) {
+		policies = append(policies, a.policyFor(muxID))
+	}
+
+	seen := map[string]bool{}
+	unique := policies[:0]
+	for _, policyName := range policies {
+		if !seen[policyName] {
+			seen[policyName] = true
+			unique = append(unique, policyName)
+		}
+	}
+	return unique
+}
+
+// signOnce signs the transaction and records it in the signing history. With replay
+// protection enabled, a retried request returns the previously signed transaction and true.
+func (m *Manager) signOnce(ctx context.Context, storage logical.Storage, config *Config, account *Account,
+	tx *txnbuild.Transaction, txHash string, sr *signRequest) (string, bool, error) {
+	m.replayLock.Lock()
+	defer m.replayLock.Unlock()
+
+	previousTxBase64, guard, err := m.checkReplay(ctx, storage, config, account, tx, txHash, sr.idempotencyKey)
+	if err != nil {
+		m.logger.Warn("Refusing to sign transaction again", "publicKey", account.PublicKey, "error", err)
+		return "", false, err
+	}
+	if previousTxBase64 != "" {
+		return previousTxBase64, true, nil
+	}
+	now := time.Now()
+	if err = m.checkStoredAccountUsable(ctx, storage, account.PublicKey, now); err != nil {
+		m.logger.Warn("Refusing to sign with the account", "publicKey", account.PublicKey, "error", err)
+		return "", false, err
+	}
+
+	signers := []*Account{account}
+	if sr.channel != nil {
+		signers = append(signers, sr.channel.account)
+	}
+	// The co-signer only signs its own creation, which does not count against its quota
+	keys := signers
+	if sr.cosigner != nil {
+		keys = append(append([]*Account{}, signers...), sr.cosigner)
+	}
+	signedTxBase64, errSign := m.sign(ctx, storage, tx, sr.networkPassphrase, keys...)
+	if errSign != nil {
+		m.logger.Error("Error signing transaction", "error", errSign)
+		return "", false, fmt.Errorf("error signing transaction: %s", errSign)
+	}
+
+	if guard != nil {
+		if err = guard.record(ctx, storage, signedTxBase64); err != nil {
+			m.logger.Error("Failed to record the signed transaction", "publicKey", account.PublicKey, "error", err)
+			return "", false, fmt.Errorf("failed to record the signed transaction: %s", err)
+		}
+	}
+
+	if err = m.recordHistory(ctx, storage, account.PublicKey, newHistoryRecord(tx, txHash, sr)); err != nil {
+		m.logger.Error("Failed to record signing history", "publicKey", account.PublicKey, "error", err)
+		return "", false, fmt.Errorf("failed to record signing history: %s", err)
+	}
+	if err = m.recordUsage(ctx, storage, sr.network, signers, now); err != nil {
+		m.logger.Error("Failed to record the account usage", "publicKey", account.PublicKey, "error", err)
+		return "", false, fmt.Errorf("failed to record the account usage: %s", err)
+	}
+	return signedTxBase64, false, nil
+}
+
+func (m *Manager) decodeTransaction(txEnvelopeBase64 string) (*txnbuild.Transaction, error) {
 	// Decode the transaction envelope
 	txEnvelope, err := txnbuild.TransactionFromXDR(txEnvelopeBase64)
 	if err != nil {
 		m.logger.Error("Error decoding transaction envelope", "error", err)
-		return "", fmt.Errorf("error decoding transaction envelope: %s", err)
+		return nil, badRequest(ErrCodeInvalidEnvelope, "error decoding transaction envelope: %s", err)
 	}
 
 	// Convert to a Transaction object
 	tx, ok := txEnvelope.Transaction()
 	if !ok {
-		return "", fmt.Errorf("failed to convert to Transaction object")
+		return nil, badRequest(ErrCodeInvalidEnvelope, "failed to convert to Transaction object")
 	}
+	return tx, nil
+}
 
-	// Sign the transaction
-	kp, err := keypair.ParseFull(account.SecretKey)
-	if err != nil {
-		m.logger.Error("Error parsing keypair", "error", err)
-		return "", fmt.Errorf("error parsing keypair: %s", err)
+func (m *Manager) sign(ctx context.Context, storage logical.Storage, tx *txnbuild.Transaction, networkPassphrase string,
+	accounts ...*Account) (string, error) {
+	// Sign the transaction with the seeds, decrypted for this signature only
+	kps := make([]*keypair.Full, 0, len(accounts))
+	for _, account := range accounts {
+		secretKey := account.SecretKey
+		if secretKey == "" {
+			var err error
+			if secretKey, err = m.retrieveSecretKey(ctx, storage, account.PublicKey); err != nil {
+				return "", err
+			}
+		}
+		kp, err := keypair.ParseFull(secretKey)
+		if err != nil {
+			m.logger.Error("Error parsing keypair", "error", err)
+			return "", fmt.Errorf("error parsing keypair: %s", err)
+		}
+		kps = append(kps, kp)
 	}
 
-	signedTx, err := tx.Sign(networkPassphrase, kp)
+	signedTx, err := tx.Sign(networkPassphrase, kps...)
 	if err != nil {
 		m.logger.Error("Error signing transaction", "error", err)
 		return "", fmt.Errorf("error signing transaction: %s", err)
@@ -228,7 +689,7 @@ func (m *Manager) retrieveAccount(ctx context.Context, storage logical.Storage,
 	_, err := keypair.ParseAddress(publicKey)
 	if err != nil {
 		m.logger.Error("Failed to retrieve the account, invalid Stellar public key", "publicKey", publicKey, "error", err)
-		return nil, fmt.Errorf("failed to retrieve the account, invalid Stellar public key: %s", err)
+		return nil, badRequest(ErrCodeInvalidAddress, "failed to retrieve the account, invalid Stellar public key: %s", err)
 	}
 
 	path := fmt.Sprintf("stellar/accounts/%s", publicKey)
@@ -241,17 +702,101 @@ func (m *Manager) retrieveAccount(ctx context.Context, storage logical.Storage,
 		// Could not find the corresponding account for the public key
 		return nil, nil
 	}
+	// Only accounts not migrated yet hold their seed with the metadata, the others get it
+	// when signing
 	var account Account
 	_ = entry.DecodeJSON(&account)
 	return &account, nil
 }
 
+// validatePolicyReference makes sure a policy assigned to an account exists.
+func (m *Manager) validatePolicyReference(ctx context.Context, storage logical.Storage, name string) error {
+	if name == "" {
+		return nil
+	}
+	policy, err := m.retrievePolicy(ctx, storage, name)
+	if err != nil {
+		return err
+	}
+	if policy == nil {
+		return notFound(ErrCodePolicyNotFound, "signing policy %q does not exist", name)
+	}
+	return nil
+}
+
+// saveAccount stores the metadata of the account. The seed is stored apart by storeSecretKey.
+func (m *Manager) saveAccount(ctx context.Context, storage logical.Storage, account *Account) error {
+	metadata := *account
+	metadata.SecretKey = ""
+	entry, err := logical.StorageEntryJSON(fmt.Sprintf("stellar/accounts/%s", account.PublicKey), &metadata)
+	if err != nil {
+		return err
+	}
+	if err = storage.Put(ctx, entry); err != nil {
+		m.logger.Error("Failed to save the Stellar account to storage", "publicKey", account.PublicKey, "error", err)
+		return err
+	}
+	return nil
+}
+
+func (a *Account) responseData() map[string]interface{} {
+	respData := map[string]interface{}{
+		"public_key":      a.PublicKey,
+		"allowed_sources": a.allowedSources(),
+	}
+	if a.Name != "" {
+		respData["name"] = a.Name
+	}
+	if a.Policy != "" {
+		respData["policy"] = a.Policy
+	}
+	if len(a.MuxPolicies) > 0 {
+		respData["mux_policies"] = a.MuxPolicies
+	}
+	if a.FundedBy != "" {
+		respData["funded_by"] = a.FundedBy
+		respData["funding_transaction"] = a.FundingTransaction
+	}
+	if a.IssuedBy != "" {
+		respData["issued_by"] = a.IssuedBy
+	}
+	if a.OwnerEntity != "" {
+		respData["owner_entity"] = a.OwnerEntity
+	}
+	if a.OwnerGroup != "" {
+		respData["owner_group"] = a.OwnerGroup
+	}
+	if len(a.SharedWith) > 0 {
+		respData["shared_with"] = a.SharedWith
+	}
+	if a.Approval != nil {
+		respData["approval"] = a.Approval.responseData()
+	}
+	for k, v := range a.usageResponseData() {
+		respData[k] = v
+	}
+	return respData
+}
+
+// AccountExistenceCheck reports whether the account addressed by the path exists, so
+// that writes to an existing account, including signing with it, are update operations
 func (m *Manager) AccountExistenceCheck(ctx context.Context, req *logical.Request, data *framework.FieldData) (bool, error) {
-	out, err := req.Storage.Get(ctx, req.Path)
+	publicKey, _, err := resolveAddress(data.Get("publicKey").(string))
+	if err != nil {
+		// The handler reports the invalid address
+		return false, nil
+	}
+	out, err := req.Storage.Get(ctx, fmt.Sprintf("stellar/accounts/%s", publicKey))
 	if err != nil {
-		m.logger.Error("Path existence check failed", err)
+		m.logger.Error("Path existence check failed", "publicKey", publicKey, "error", err)
 		return false, fmt.Errorf("existence check failed: %v", err)
 	}
 
 	return out != nil, nil
 }
+
+// NewAccountExistenceCheck makes every write to accounts/ a create operation, so that
+// generating and importing keys takes the create capability
+func (m *Manager) NewAccountExistenceCheck(ctx context.Context, req *logical.Request, data *framework.FieldData) (bool, error) {
+	return false, nil
+}
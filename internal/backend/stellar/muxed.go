@@ -0,0 +1,110 @@
+package stellar
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stellar/go/strkey"
+	"github.com/stellar/go/xdr"
+	"strconv"
+)
+
+// resolveAddress maps a G- or M-address to the public key of the Vault account that
+// holds the key. The mux ID is returned for M-addresses.
+func resolveAddress(address string) (string, *uint64, error) {
+	if !strkey.IsValidMuxedAccountEd25519PublicKey(address) {
+		return address, nil, nil
+	}
+	muxed, err := xdr.AddressToMuxedAccount(address)
+	if err != nil {
+		return "", nil, badRequest(ErrCodeInvalidAddress, "invalid muxed account address: %s", err)
+	}
+	accountID := muxed.ToAccountId()
+	muxID := uint64(muxed.Med25519.Id)
+	return accountID.Address(), &muxID, nil
+}
+
+// parseMuxPolicies validates a map of mux IDs to signing policy names
+func (m *Manager) parseMuxPolicies(ctx context.Context, storage logical.Storage, raw map[string]string) (map[string]string, error) {
+	muxPolicies := map[string]string{}
+	for muxID, policyName := range raw {
+		if _, err := strconv.ParseUint(muxID, 10, 64); err != nil {
+			return nil, invalidRequest("invalid mux ID %q in mux_policies", muxID)
+		}
+		if policyName == "" {
+			continue
+		}
+		if err := m.validatePolicyReference(ctx, storage, policyName); err != nil {
+			return nil, err
+		}
+		muxPolicies[muxID] = policyName
+	}
+	return muxPolicies, nil
+}
+
+// policyFor returns the policy enforced when the account signs as the given mux ID.
+// A per-mux-ID override replaces the account policy for that mux ID only, the policies
+// of the other sources of the transaction still apply.
+func (a *Account) policyFor(muxID *uint64) string {
+	if muxID != nil {
+		if policyName, ok := a.MuxPolicies[strconv.FormatUint(*muxID, 10)]; ok {
+			return policyName
+		}
+	}
+	return a.Policy
+}
+
+// sourceMuxIDs returns the mux IDs of the transaction source and operation sources, nil
+// for a source that is not a muxed address of the account. Operations without a source
+// act for the transaction source.
+func (a *Account) sourceMuxIDs(envelope xdr.TransactionEnvelope) []*uint64 {
+	sources := []xdr.MuxedAccount{envelope.SourceAccount()}
+	for _, op := range envelope.Operations() {
+		if op.SourceAccount != nil {
+			sources = append(sources, *op.SourceAccount)
+		}
+	}
+
+	muxIDs := make([]*uint64, 0, len(sources))
+	for _, source := range sources {
+		accountID := source.ToAccountId()
+		if source.Type != xdr.CryptoKeyTypeKeyTypeMuxedEd25519 || accountID.Address() != a.PublicKey {
+			muxIDs = append(muxIDs, nil)
+			continue
+		}
+		muxID := uint64(source.Med25519.Id)
+		muxIDs = append(muxIDs, &muxID)
+	}
+	return muxIDs
+}
+
+func (m *Manager) MuxedAddress(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	publicKey := data.Get("publicKey").(string)
+	muxID, err := strconv.ParseUint(data.Get("id").(string), 10, 64)
+	if err != nil {
+		return nil, invalidRequest("invalid mux ID: %s", err)
+	}
+
+	account, _, err := m.retrieveAccessibleAccount(ctx, req, publicKey)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		return nil, notFound(ErrCodeAccountNotFound, "stellar account does not exist")
+	}
+
+	muxed, err := xdr.MuxedAccountFromAccountId(account.PublicKey, muxID)
+	if err != nil {
+		return nil, fmt.Errorf("error generating muxed address: %s", err)
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"public_key":    account.PublicKey,
+			"mux_id":        muxID,
+			"muxed_address": muxed.Address(),
+			"policy":        account.policyFor(&muxID),
+		},
+	}, nil
+}
@@ -0,0 +1,163 @@
+package stellar
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// accessLevel is what the caller of a request may do with an account when ownership is enforced
+type accessLevel int
+
+const (
+	accessNone accessLevel = iota
+	// accessShared allows using the account, it was shared with the caller's entity
+	accessShared
+	// accessOwner also allows changing who owns and shares the account
+	accessOwner
+)
+
+// caller is the Vault identity a request was made with
+type caller struct {
+	entityID string
+	groups   []*logical.Group
+	admin    bool
+}
+
+// identify resolves the entity and groups of the caller. Members of the configured admin
+// group are admins. Plugins are not given the token of a request, so root tokens and other
+// tokens without an entity are not.
+func (m *Manager) identify(req *logical.Request, config *Config) (*caller, error) {
+	return m.identifyEntity(req.EntityID, config)
+}
+
+// identifyEntity resolves the groups of an entity, also outside of its own requests
+func (m *Manager) identifyEntity(entityID string, config *Config) (*caller, error) {
+	c := &caller{entityID: entityID}
+	if entityID == "" || m.system == nil {
+		return c, nil
+	}
+	groups, err := m.system().GroupsForEntity(entityID)
+	if err != nil {
+		m.logger.Error("Failed to look up the groups of the caller", "entityID", entityID, "error", err)
+		return nil, fmt.Errorf("failed to look up the groups of the caller: %s", err)
+	}
+	c.groups = groups
+	c.admin = config.AdminGroup != "" && c.inGroup(config.AdminGroup) != ""
+	return c, nil
+}
+
+// inGroup returns the ID of the caller's group with the given name or ID, or "" if the
+// caller is not a member
+func (c *caller) inGroup(nameOrID string) string {
+	for _, group := range c.groups {
+		if group.ID == nameOrID || group.Name == nameOrID {
+			return group.ID
+		}
+	}
+	return ""
+}
+
+// access returns what the caller may do with the account
+func (c *caller) access(account *Account) accessLevel {
+	switch {
+	case c.admin:
+		return accessOwner
+	case c.entityID != "" && account.OwnerEntity == c.entityID:
+		return accessOwner
+	case account.OwnerGroup != "" && c.inGroup(account.OwnerGroup) != "":
+		return accessOwner
+	case c.entityID != "" && containsString(account.SharedWith, c.entityID):
+		return accessShared
+	}
+	return accessNone
+}
+
+// retrieveAccessibleAccount retrieves an account and, when ownership is enforced, hides
+// it from callers that neither own it nor had it shared with them.
+func (m *Manager) retrieveAccessibleAccount(ctx context.Context, req *logical.Request, publicKey string) (*Account, accessLevel, error) {
+	account, err := m.retrieveAccount(ctx, req.Storage, publicKey)
+	if err != nil || account == nil {
+		return nil, accessNone, err
+	}
+	config, err := m.retrieveConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, accessNone, err
+	}
+	if !config.Ownership {
+		return account, accessOwner, nil
+	}
+	c, err := m.identify(req, config)
+	if err != nil {
+		return nil, accessNone, err
+	}
+	level := c.access(account)
+	if level == accessNone {
+		return nil, accessNone, nil
+	}
+	return account, level, nil
+}
+
+// checkAccountAccess fails unless the caller may use the account at publicKey
+func (m *Manager) checkAccountAccess(ctx context.Context, req *logical.Request, publicKey string) error {
+	account, _, err := m.retrieveAccessibleAccount(ctx, req, publicKey)
+	if err != nil {
+		return err
+	}
+	if account == nil {
+		return notFound(ErrCodeAccountNotFound, "account not found")
+	}
+	return nil
+}
+
+// filterAccessibleAccounts keeps the public keys of the accounts the caller may use
+func (m *Manager) filterAccessibleAccounts(ctx context.Context, req *logical.Request, publicKeys []string) ([]string, error) {
+	config, err := m.retrieveConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if !config.Ownership {
+		return publicKeys, nil
+	}
+	c, err := m.identify(req, config)
+	if err != nil {
+		return nil, err
+	}
+	if c.admin {
+		return publicKeys, nil
+	}
+
+	visible := []string{}
+	for _, publicKey := range publicKeys {
+		account, err := m.retrieveAccount(ctx, req.Storage, publicKey)
+		if err != nil {
+			return nil, err
+		}
+		if account != nil && c.access(account) != accessNone {
+			visible = append(visible, publicKey)
+		}
+	}
+	return visible, nil
+}
+
+// ownerGroupID validates that the caller may give an account to the group and returns its ID
+func (m *Manager) ownerGroupID(ctx context.Context, req *logical.Request, nameOrID string) (string, error) {
+	if nameOrID == "" {
+		return "", nil
+	}
+	config, err := m.retrieveConfig(ctx, req.Storage)
+	if err != nil {
+		return "", err
+	}
+	c, err := m.identify(req, config)
+	if err != nil {
+		return "", err
+	}
+	if id := c.inGroup(nameOrID); id != "" {
+		return id, nil
+	}
+	if c.admin {
+		return nameOrID, nil
+	}
+	return "", forbidden(ErrCodePermissionDenied, "owner_group must be a group the caller is a member of")
+}
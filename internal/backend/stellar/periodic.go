@@ -0,0 +1,29 @@
+package stellar
+
+import (
+	"context"
+	"errors"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// Periodic runs the plugin's housekeeping tasks. It is registered as the backend's
+// periodic function, which Vault calls about once a minute on the active node.
+func (m *Manager) Periodic(ctx context.Context, req *logical.Request) error {
+	var errs []error
+	tasks := []func(context.Context, *logical.Request) error{
+		m.PruneReplayRecords,
+		m.PruneHistory,
+		m.PruneDeletedAccounts,
+		m.ProcessPendingSubmissions,
+		m.PruneSubmissions,
+		m.ExpireApprovalRequests,
+		m.ReportUnusedKeys,
+	}
+	for _, task := range tasks {
+		if err := task(ctx, req); err != nil {
+			m.logger.Error("Periodic task failed", "error", err)
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,204 @@
+package stellar
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stellar/go/txnbuild"
+	"time"
+)
+
+// Policy is a named set of signing rules that can be attached to accounts
+type Policy struct {
+	Name     string          `json:"name"`
+	Soroban  *SorobanPolicy  `json:"soroban,omitempty"`
+	Validity *ValidityPolicy `json:"validity,omitempty"`
+	// ApprovalTiers route transactions by value to the number of approvals they need
+	ApprovalTiers []ApprovalTier `json:"approval_tiers,omitempty"`
+}
+
+// PolicyDenial is returned when a transaction violates one of the rules of a policy
+type PolicyDenial struct {
+	Policy string
+	Rule   string
+	Reason string
+}
+
+func (d *PolicyDenial) Error() string {
+	return fmt.Sprintf("transaction denied by policy %q, rule %s: %s", d.Policy, d.Rule, d.Reason)
+}
+
+// evaluate checks the transaction against every rule of the policy and returns a
+// *PolicyDenial for the first rule that is violated.
+func (p *Policy) evaluate(tx *txnbuild.Transaction) error {
+	if p.Validity != nil {
+		if err := p.Validity.evaluate(p.Name, tx.ToXDR(), time.Now()); err != nil {
+			return err
+		}
+	}
+	if p.Soroban != nil {
+		if err := p.Soroban.evaluate(p.Name, tx.ToXDR()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Manager) ListPolicies(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	policyList, err := req.Storage.List(ctx, "stellar/policies/")
+	if err != nil {
+		m.logger.Error("Failed to list signing policies", "error", err)
+		return nil, fmt.Errorf("failed to list signing policies: %s", err)
+	}
+
+	return logical.ListResponse(policyList), nil
+}
+
+func (m *Manager) ReadPolicy(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+
+	policy, err := m.retrievePolicy(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		return nil, notFound(ErrCodePolicyNotFound, "signing policy does not exist")
+	}
+
+	return &logical.Response{
+		Data: policy.responseData(),
+	}, nil
+}
+
+func (m *Manager) WritePolicy(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+	if name == "" {
+		return nil, invalidRequest("missing policy name")
+	}
+
+	policy := &Policy{Name: name}
+
+	soroban, err := sorobanPolicyFromFieldData(data)
+	if err != nil {
+		return nil, err
+	}
+	policy.Soroban = soroban
+
+	validity, err := validityPolicyFromFieldData(data)
+	if err != nil {
+		return nil, err
+	}
+	policy.Validity = validity
+
+	if policy.ApprovalTiers, err = approvalTiersFromFieldData(data); err != nil {
+		return nil, err
+	}
+
+	entry, err := logical.StorageEntryJSON(fmt.Sprintf("stellar/policies/%s", name), policy)
+	if err != nil {
+		return nil, err
+	}
+	if err = req.Storage.Put(ctx, entry); err != nil {
+		m.logger.Error("Failed to save the signing policy to storage", "name", name, "error", err)
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: policy.responseData(),
+	}, nil
+}
+
+func (m *Manager) DeletePolicy(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+
+	if err := req.Storage.Delete(ctx, fmt.Sprintf("stellar/policies/%s", name)); err != nil {
+		m.logger.Error("Failed to delete the signing policy from storage", "name", name, "error", err)
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (m *Manager) retrievePolicy(ctx context.Context, storage logical.Storage, name string) (*Policy, error) {
+	path := fmt.Sprintf("stellar/policies/%s", name)
+	entry, err := storage.Get(ctx, path)
+	if err != nil {
+		m.logger.Error("Failed to retrieve the signing policy", "path", path, "error", err)
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+	var policy Policy
+	if err = entry.DecodeJSON(&policy); err != nil {
+		return nil, fmt.Errorf("failed to decode signing policy %q: %s", name, err)
+	}
+	return &policy, nil
+}
+
+// enforcePolicy loads the named policy and evaluates the transaction against it.
+// A policy that is referenced but missing denies the transaction.
+func (m *Manager) enforcePolicy(ctx context.Context, storage logical.Storage, name string, tx *txnbuild.Transaction) error {
+	if name == "" {
+		return nil
+	}
+	policy, err := m.retrievePolicy(ctx, storage, name)
+	if err != nil {
+		return err
+	}
+	if policy == nil {
+		return &PolicyDenial{Policy: name, Rule: "policy", Reason: "referenced policy does not exist"}
+	}
+	return policy.evaluate(tx)
+}
+
+func (p *Policy) responseData() map[string]interface{} {
+	respData := map[string]interface{}{
+		"name": p.Name,
+	}
+	if p.Soroban != nil {
+		respData["soroban_contracts"] = p.Soroban.Contracts
+		respData["soroban_max_resource_fee"] = p.Soroban.MaxResourceFee
+		respData["soroban_allow_wasm_upload"] = p.Soroban.AllowWasmUpload
+		respData["soroban_allow_contract_creation"] = p.Soroban.AllowContractCreation
+	}
+	if p.Validity != nil {
+		respData["require_time_bounds"] = p.Validity.RequireTimeBounds
+		respData["max_validity_window"] = p.Validity.MaxValidityWindow
+		respData["require_ledger_bounds"] = p.Validity.RequireLedgerBounds
+		respData["min_sequence_age"] = p.Validity.MinSequenceAge
+		respData["min_sequence_ledger_gap"] = p.Validity.MinSequenceLedgerGap
+		respData["required_extra_signers"] = p.Validity.RequiredExtraSigners
+	}
+	if len(p.ApprovalTiers) > 0 {
+		tiers := make([]map[string]interface{}, 0, len(p.ApprovalTiers))
+		for _, tier := range p.ApprovalTiers {
+			tiers = append(tiers, tier.responseData())
+		}
+		respData["approval_tiers"] = tiers
+	}
+	return respData
+}
+
+// decodeObjectList converts a TypeSlice field into a slice of structs. Elements may
+// be JSON objects or strings holding JSON objects, which is what the CLI sends.
+func decodeObjectList(raw []interface{}, out interface{}) error {
+	items := make([]json.RawMessage, 0, len(raw))
+	for _, item := range raw {
+		if s, ok := item.(string); ok {
+			items = append(items, json.RawMessage(s))
+			continue
+		}
+		b, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		items = append(items, b)
+	}
+	b, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, out)
+}
@@ -0,0 +1,233 @@
+package stellar
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stellar/go/txnbuild"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	replayPrefix              = "stellar/replay/"
+	defaultReplayWindow int64 = 24 * 60 * 60
+)
+
+var idempotencyKeyRegex = regexp.MustCompile(`^[A-Za-z0-9_.\-]{1,128}$`)
+
+// signatureRecord remembers a transaction signed by an account while replay protection is enabled
+type signatureRecord struct {
+	Hash              string    `json:"hash"`
+	SignedTransaction string    `json:"signed_transaction"`
+	SignedAt          time.Time `json:"signed_at"`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
+
+// replayReference points from a sequence number or an idempotency key to a signed transaction hash
+type replayReference struct {
+	Hash      string    `json:"hash"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type replayGuard struct {
+	publicKey      string
+	hash           string
+	sequenceKey    string
+	idempotencyKey string
+	window         time.Duration
+}
+
+// checkReplay looks for a previous signature of the same transaction. It returns the
+// previously signed envelope when the request repeats an idempotency key, an error
+// when the transaction or its sequence number was already signed, and a guard to
+// record the new signature with otherwise.
+func (m *Manager) checkReplay(ctx context.Context, storage logical.Storage, config *Config, account *Account,
+	tx *txnbuild.Transaction, hash string, idempotencyKey string) (string, *replayGuard, error) {
+	if !config.ReplayProtection {
+		if idempotencyKey != "" {
+			return "", nil, invalidRequest("idempotency_key requires replay protection to be enabled")
+		}
+		return "", nil, nil
+	}
+	if idempotencyKey != "" && !idempotencyKeyRegex.MatchString(idempotencyKey) {
+		return "", nil, invalidRequest("invalid idempotency_key, it must be 1 to 128 letters, digits, '.', '_' or '-'")
+	}
+
+	// The sequence number belongs to the ledger account, whatever mux ID it is addressed with
+	source := tx.ToXDR().SourceAccount()
+	sourceAccountID := source.ToAccountId()
+	sequence := tx.SequenceNumber()
+	guard := &replayGuard{
+		publicKey:      account.PublicKey,
+		hash:           hash,
+		sequenceKey:    fmt.Sprintf("%s:%d", sourceAccountID.Address(), sequence),
+		idempotencyKey: idempotencyKey,
+		window:         time.Duration(config.replayWindow()) * time.Second,
+	}
+	now := time.Now()
+
+	if idempotencyKey != "" {
+		var ref replayReference
+		found, err := m.getReplayEntry(ctx, storage, guard.idempotencyPath(), now, &ref)
+		if err != nil {
+			return "", nil, err
+		}
+		if found {
+			if ref.Hash != hash {
+				return "", nil, conflict(ErrCodeReplayRejected, "idempotency_key %q was already used for transaction %s", idempotencyKey, ref.Hash)
+			}
+			var record signatureRecord
+			found, err = m.getReplayEntry(ctx, storage, guard.signaturePath(), now, &record)
+			if err != nil {
+				return "", nil, err
+			}
+			if found {
+				return record.SignedTransaction, nil, nil
+			}
+		}
+	}
+
+	var record signatureRecord
+	found, err := m.getReplayEntry(ctx, storage, guard.signaturePath(), now, &record)
+	if err != nil {
+		return "", nil, err
+	}
+	if found {
+		return "", nil, conflict(ErrCodeReplayRejected, "transaction %s was already signed at %s", hash, record.SignedAt.Format(time.RFC3339))
+	}
+
+	if config.RejectSequenceReuse {
+		var ref replayReference
+		found, err = m.getReplayEntry(ctx, storage, guard.sequencePath(), now, &ref)
+		if err != nil {
+			return "", nil, err
+		}
+		if found && ref.Hash != hash {
+			return "", nil, conflict(ErrCodeReplayRejected, "a different transaction %s was already signed for source account %s and sequence number %d",
+				ref.Hash, sourceAccountID.Address(), sequence)
+		}
+	}
+
+	return "", guard, nil
+}
+
+// idempotentSignature returns the transaction the account signed under the idempotency
+// key, or nil if the key was not used within the replay window
+func (m *Manager) idempotentSignature(ctx context.Context, storage logical.Storage, publicKey string,
+	idempotencyKey string) (*signatureRecord, error) {
+	now := time.Now()
+	guard := &replayGuard{publicKey: publicKey, idempotencyKey: idempotencyKey}
+	var ref replayReference
+	found, err := m.getReplayEntry(ctx, storage, guard.idempotencyPath(), now, &ref)
+	if err != nil || !found {
+		return nil, err
+	}
+	guard.hash = ref.Hash
+	var record signatureRecord
+	found, err = m.getReplayEntry(ctx, storage, guard.signaturePath(), now, &record)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// record stores the signed transaction and the references pointing to it
+func (g *replayGuard) record(ctx context.Context, storage logical.Storage, signedTx string) error {
+	now := time.Now()
+	expiresAt := now.Add(g.window)
+
+	entries := map[string]interface{}{
+		g.signaturePath(): &signatureRecord{Hash: g.hash, SignedTransaction: signedTx, SignedAt: now, ExpiresAt: expiresAt},
+		g.sequencePath():  &replayReference{Hash: g.hash, ExpiresAt: expiresAt},
+	}
+	if g.idempotencyKey != "" {
+		entries[g.idempotencyPath()] = &replayReference{Hash: g.hash, ExpiresAt: expiresAt}
+	}
+	for path, value := range entries {
+		entry, err := logical.StorageEntryJSON(path, value)
+		if err != nil {
+			return err
+		}
+		if err = storage.Put(ctx, entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *replayGuard) signaturePath() string {
+	return fmt.Sprintf("%s%s/tx/%s", replayPrefix, g.publicKey, g.hash)
+}
+
+func (g *replayGuard) sequencePath() string {
+	return fmt.Sprintf("%s%s/seq/%s", replayPrefix, g.publicKey, g.sequenceKey)
+}
+
+func (g *replayGuard) idempotencyPath() string {
+	return fmt.Sprintf("%s%s/idem/%s", replayPrefix, g.publicKey, g.idempotencyKey)
+}
+
+// getReplayEntry decodes the entry at path into out. Expired entries are reported as not found.
+func (m *Manager) getReplayEntry(ctx context.Context, storage logical.Storage, path string, now time.Time, out interface{}) (bool, error) {
+	entry, err := storage.Get(ctx, path)
+	if err != nil {
+		m.logger.Error("Failed to retrieve replay protection record", "path", path, "error", err)
+		return false, err
+	}
+	if entry == nil {
+		return false, nil
+	}
+	var expiry replayReference
+	if err = entry.DecodeJSON(&expiry); err != nil {
+		return false, err
+	}
+	if now.After(expiry.ExpiresAt) {
+		return false, nil
+	}
+	return true, entry.DecodeJSON(out)
+}
+
+// PruneReplayRecords deletes the replay protection records whose window has passed.
+// It is run by the backend's periodic function.
+func (m *Manager) PruneReplayRecords(ctx context.Context, req *logical.Request) error {
+	accounts, err := req.Storage.List(ctx, replayPrefix)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	pruned := 0
+	for _, account := range accounts {
+		if !strings.HasSuffix(account, "/") {
+			continue
+		}
+		for _, kind := range []string{"tx/", "seq/", "idem/"} {
+			prefix := replayPrefix + account + kind
+			keys, err := req.Storage.List(ctx, prefix)
+			if err != nil {
+				return err
+			}
+			for _, key := range keys {
+				entry, err := req.Storage.Get(ctx, prefix+key)
+				if err != nil {
+					return err
+				}
+				if entry == nil {
+					continue
+				}
+				var expiry replayReference
+				if err = entry.DecodeJSON(&expiry); err != nil || now.After(expiry.ExpiresAt) {
+					if err = req.Storage.Delete(ctx, prefix+key); err != nil {
+						return err
+					}
+					pruned++
+				}
+			}
+		}
+	}
+	if pruned > 0 {
+		m.logger.Debug("Pruned expired replay protection records", "count", pruned)
+	}
+	return nil
+}
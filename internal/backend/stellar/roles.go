@@ -0,0 +1,342 @@
+package stellar
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"time"
+)
+
+const (
+	defaultCredsTTL             int64 = 3600
+	defaultCredsStartingBalance       = "2"
+)
+
+// Role grants signing with a set of accounts on a set of networks under a policy at
+// roles/<name>/sign, and describes how ephemeral accounts are issued at creds/<name>.
+// Several roles may share an account with different restrictions.
+type Role struct {
+	Name     string   `json:"name"`
+	Accounts []string `json:"accounts,omitempty"`
+	// Networks the role may sign for, any network when empty
+	Networks []string `json:"networks,omitempty"`
+	Policy   string   `json:"policy,omitempty"`
+	// Network is where ephemeral accounts are created or added as signers
+	Network string `json:"network,omitempty"`
+	// FundingAccount creates and funds ephemeral accounts, which are merged back into it on revocation
+	FundingAccount  string `json:"funding_account,omitempty"`
+	StartingBalance string `json:"starting_balance,omitempty"`
+	// ParentAccount gets ephemeral accounts added as signers, which are removed on revocation
+	ParentAccount string `json:"parent_account,omitempty"`
+	SignerWeight  int    `json:"signer_weight,omitempty"`
+	TTL           int64  `json:"ttl"`
+	MaxTTL        int64  `json:"max_ttl"`
+}
+
+func rolePath(name string) string {
+	return fmt.Sprintf("stellar/roles/%s", name)
+}
+
+func (m *Manager) ListRoles(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	names, err := req.Storage.List(ctx, "stellar/roles/")
+	if err != nil {
+		m.logger.Error("Failed to list roles", "error", err)
+		return nil, fmt.Errorf("failed to list roles: %s", err)
+	}
+
+	roles := []string{}
+	for _, name := range names {
+		role, err := m.retrieveAccessibleRole(ctx, req, name)
+		if err != nil {
+			return nil, err
+		}
+		if role != nil {
+			roles = append(roles, name)
+		}
+	}
+	return logical.ListResponse(roles), nil
+}
+
+func (m *Manager) ReadRole(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	role, err := m.retrieveAccessibleRole(ctx, req, data.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, notFound(ErrCodeRoleNotFound, "role does not exist")
+	}
+
+	return &logical.Response{
+		Data: role.responseData(),
+	}, nil
+}
+
+// WriteRole creates or replaces a role
+func (m *Manager) WriteRole(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+	if name == "" {
+		return nil, invalidRequest("missing role name")
+	}
+
+	role := &Role{
+		Name:            name,
+		Accounts:        data.Get("accounts").([]string),
+		Networks:        data.Get("networks").([]string),
+		Policy:          data.Get("policy").(string),
+		Network:         data.Get("network").(string),
+		FundingAccount:  data.Get("funding_account").(string),
+		StartingBalance: data.Get("starting_balance").(string),
+		ParentAccount:   data.Get("parent_account").(string),
+		SignerWeight:    data.Get("signer_weight").(int),
+		TTL:             int64(data.Get("ttl").(int)),
+		MaxTTL:          int64(data.Get("max_ttl").(int)),
+	}
+
+	// Replacing a role requires the access that writing it did
+	existing, err := m.retrieveRole(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		usable, err := m.canUseRole(ctx, req, existing)
+		if err != nil {
+			return nil, err
+		}
+		if !usable {
+			return nil, forbidden(ErrCodePermissionDenied, "role %q has accounts the caller cannot use", name)
+		}
+	}
+
+	for i, address := range role.Accounts {
+		publicKey, _, err := resolveAddress(address)
+		if err != nil {
+			return nil, err
+		}
+		account, _, err := m.retrieveAccessibleAccount(ctx, req, publicKey)
+		if err != nil {
+			return nil, err
+		}
+		if account == nil {
+			return nil, accountNotFound(publicKey)
+		}
+		role.Accounts[i] = publicKey
+	}
+	for _, networkName := range role.Networks {
+		if _, ok := networkPassphrases[networkName]; !ok {
+			return nil, invalidNetwork(networkName)
+		}
+	}
+	if err := m.validatePolicyReference(ctx, req.Storage, role.Policy); err != nil {
+		return nil, err
+	}
+
+	if role.FundingAccount != "" && role.ParentAccount != "" {
+		return nil, invalidRequest("only one of funding_account and parent_account can be provided")
+	}
+	if _, ok := networkPassphrases[role.Network]; role.Network != "" && !ok {
+		return nil, invalidNetwork(role.Network)
+	}
+	for _, publicKey := range []string{role.FundingAccount, role.ParentAccount} {
+		if publicKey == "" {
+			continue
+		}
+		if role.Network == "" {
+			return nil, invalidRequest("network must be provided with funding_account or parent_account")
+		}
+		account, _, err := m.retrieveAccessibleAccount(ctx, req, publicKey)
+		if err != nil {
+			return nil, err
+		}
+		if account == nil {
+			return nil, accountNotFound(publicKey)
+		}
+	}
+	if role.FundingAccount != "" && role.StartingBalance == "" {
+		role.StartingBalance = defaultCredsStartingBalance
+	}
+	if role.ParentAccount != "" && (role.SignerWeight < 1 || role.SignerWeight > 255) {
+		return nil, invalidRequest("signer_weight must be between 1 and 255")
+	}
+	if role.TTL < 0 || role.MaxTTL < 0 {
+		return nil, invalidRequest("ttl and max_ttl must not be negative")
+	}
+	if role.MaxTTL > 0 && role.ttl() > role.maxTTL() {
+		return nil, invalidRequest("ttl must not exceed max_ttl")
+	}
+
+	if err := m.saveRole(ctx, req.Storage, role); err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: role.responseData(),
+	}, nil
+}
+
+// SignWithRole signs a transaction with one of the role's accounts. The network must
+// be allowed by the role and the role's policy applies on top of the account's. The
+// caller does not need access to the account itself when ownership is enforced.
+func (m *Manager) SignWithRole(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	role, err := m.retrieveRole(ctx, req.Storage, data.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, notFound(ErrCodeRoleNotFound, "role does not exist")
+	}
+
+	address := data.Get("publicKey").(string)
+	if address == "" && len(role.Accounts) == 1 {
+		address = role.Accounts[0]
+	}
+	sr, err := parseSignRequest(address, data)
+	if err != nil {
+		return nil, err
+	}
+	if !containsString(role.Accounts, sr.publicKey) {
+		return nil, forbidden(ErrCodePermissionDenied, "role %q does not grant signing with account %s", role.Name, sr.publicKey)
+	}
+	if len(role.Networks) > 0 && !containsString(role.Networks, sr.network) {
+		return nil, forbidden(ErrCodePermissionDenied, "role %q does not grant signing for network %s", role.Name, sr.network)
+	}
+	sr.role = role.Name
+	sr.rolePolicy = role.Policy
+
+	sr.txEnvelopeBase64 = data.Get("transaction").(string)
+	if sr.txEnvelopeBase64 == "" {
+		return nil, invalidRequest("transaction must be provided")
+	}
+
+	// The role delegates the access its writer had to its accounts, Vault ACLs on the
+	// role's sign path decide who may use it
+	account, err := m.retrieveAccount(ctx, req.Storage, sr.publicKey)
+	if err != nil {
+		m.logger.Error("Error retrieving account", "error", err)
+		return nil, fmt.Errorf("error retrieving account: %w", err)
+	}
+	if account == nil {
+		return nil, notFound(ErrCodeAccountNotFound, "account not found")
+	}
+
+	tx, err := m.decodeTransaction(sr.txEnvelopeBase64)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.signTransaction(ctx, req.Storage, account, tx, sr)
+}
+
+func (m *Manager) DeleteRole(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+
+	role, err := m.retrieveAccessibleRole(ctx, req, name)
+	if err != nil || role == nil {
+		return nil, err
+	}
+	if err = req.Storage.Delete(ctx, rolePath(name)); err != nil {
+		m.logger.Error("Failed to delete the role from storage", "name", name, "error", err)
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (m *Manager) saveRole(ctx context.Context, storage logical.Storage, role *Role) error {
+	entry, err := logical.StorageEntryJSON(rolePath(role.Name), role)
+	if err != nil {
+		return err
+	}
+	if err = storage.Put(ctx, entry); err != nil {
+		m.logger.Error("Failed to save the role to storage", "name", role.Name, "error", err)
+		return err
+	}
+	return nil
+}
+
+func (m *Manager) retrieveRole(ctx context.Context, storage logical.Storage, name string) (*Role, error) {
+	entry, err := storage.Get(ctx, rolePath(name))
+	if err != nil {
+		m.logger.Error("Failed to retrieve the role", "name", name, "error", err)
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+	var role Role
+	if err = entry.DecodeJSON(&role); err != nil {
+		return nil, fmt.Errorf("failed to decode role %q: %s", name, err)
+	}
+	return &role, nil
+}
+
+// retrieveAccessibleRole retrieves a role and, when ownership is enforced, hides it from
+// callers that cannot use all of its accounts
+func (m *Manager) retrieveAccessibleRole(ctx context.Context, req *logical.Request, name string) (*Role, error) {
+	role, err := m.retrieveRole(ctx, req.Storage, name)
+	if err != nil || role == nil {
+		return nil, err
+	}
+	usable, err := m.canUseRole(ctx, req, role)
+	if err != nil || !usable {
+		return nil, err
+	}
+	return role, nil
+}
+
+// canUseRole reports whether the caller may use every account of the role, including
+// its funding or parent account, as writing the role requires
+func (m *Manager) canUseRole(ctx context.Context, req *logical.Request, role *Role) (bool, error) {
+	publicKeys := append([]string{}, role.Accounts...)
+	for _, publicKey := range []string{role.FundingAccount, role.ParentAccount} {
+		if publicKey != "" {
+			publicKeys = append(publicKeys, publicKey)
+		}
+	}
+	accessible, err := m.filterAccessibleAccounts(ctx, req, publicKeys)
+	if err != nil {
+		return false, err
+	}
+	return len(accessible) == len(publicKeys), nil
+}
+
+func (r *Role) ttl() time.Duration {
+	if r.TTL == 0 {
+		return time.Duration(defaultCredsTTL) * time.Second
+	}
+	return time.Duration(r.TTL) * time.Second
+}
+
+// maxTTL is zero when the mount's maximum applies
+func (r *Role) maxTTL() time.Duration {
+	return time.Duration(r.MaxTTL) * time.Second
+}
+
+func (r *Role) responseData() map[string]interface{} {
+	accounts, networks := r.Accounts, r.Networks
+	if accounts == nil {
+		accounts = []string{}
+	}
+	if networks == nil {
+		networks = []string{}
+	}
+	respData := map[string]interface{}{
+		"name":     r.Name,
+		"accounts": accounts,
+		"networks": networks,
+		"policy":   r.Policy,
+		"ttl":      int64(r.ttl().Seconds()),
+		"max_ttl":  r.MaxTTL,
+	}
+	if r.Network != "" {
+		respData["network"] = r.Network
+	}
+	if r.FundingAccount != "" {
+		respData["funding_account"] = r.FundingAccount
+		respData["starting_balance"] = r.StartingBalance
+	}
+	if r.ParentAccount != "" {
+		respData["parent_account"] = r.ParentAccount
+		respData["signer_weight"] = r.SignerWeight
+	}
+	return respData
+}
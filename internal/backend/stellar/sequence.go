@@ -0,0 +1,233 @@
+package stellar
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"strconv"
+	"time"
+)
+
+// SequenceState tracks the next sequence number to use for a source account, so that
+// concurrent builds get distinct numbers without a Horizon round-trip each.
+type SequenceState struct {
+	Source    string    `json:"source"`
+	Network   string    `json:"network"`
+	Next      int64     `json:"next"`
+	Allocated int64     `json:"allocated"`
+	SyncedAt  time.Time `json:"synced_at"`
+}
+
+func sequencePath(networkName string, source string) string {
+	return fmt.Sprintf("stellar/sequences/%s/%s", networkName, source)
+}
+
+// allocateSequence hands out the next sequence number of the source account. The
+// state is synchronised from Horizon the first time and after it was invalidated.
+// Allocation only happens on the active node, the paths allocating sequence numbers
+// are forwarded from performance standbys.
+func (m *Manager) allocateSequence(ctx context.Context, storage logical.Storage, networkName string, source string) (int64, error) {
+	m.sequenceLock.Lock()
+	defer m.sequenceLock.Unlock()
+
+	state, err := m.retrieveSequenceState(ctx, storage, networkName, source)
+	if err != nil {
+		return 0, err
+	}
+	if state == nil {
+		if state, err = m.syncSequenceState(ctx, storage, networkName, source); err != nil {
+			return 0, err
+		}
+	}
+
+	sequence := state.Next
+	state.Next++
+	state.Allocated++
+	if err = m.saveSequenceState(ctx, storage, state); err != nil {
+		return 0, err
+	}
+	return sequence, nil
+}
+
+// releaseSequence gives back a sequence number whose transaction was not signed. It
+// is only reusable while no later number was handed out, otherwise the gap is closed
+// by the resync following the tx_bad_seq of the next submission.
+func (m *Manager) releaseSequence(ctx context.Context, storage logical.Storage, networkName string, source string, sequence int64) {
+	m.sequenceLock.Lock()
+	defer m.sequenceLock.Unlock()
+
+	state, err := m.retrieveSequenceState(ctx, storage, networkName, source)
+	if err != nil || state == nil || state.Next != sequence+1 {
+		return
+	}
+	state.Next = sequence
+	state.Allocated--
+	if err = m.saveSequenceState(ctx, storage, state); err != nil {
+		m.logger.Warn("Failed to release sequence number", "source", source, "sequence", sequence, "error", err)
+	}
+}
+
+// observeSequence moves the tracked state past a sequence number chosen by the caller
+func (m *Manager) observeSequence(ctx context.Context, storage logical.Storage, networkName string, source string, sequence int64) error {
+	m.sequenceLock.Lock()
+	defer m.sequenceLock.Unlock()
+
+	state, err := m.retrieveSequenceState(ctx, storage, networkName, source)
+	if err != nil || state == nil || state.Next > sequence {
+		return err
+	}
+	state.Next = sequence + 1
+	return m.saveSequenceState(ctx, storage, state)
+}
+
+// invalidateSequence drops the tracked state so the next allocation resyncs from Horizon
+func (m *Manager) invalidateSequence(ctx context.Context, storage logical.Storage, networkName string, source string) error {
+	m.sequenceLock.Lock()
+	defer m.sequenceLock.Unlock()
+
+	if err := storage.Delete(ctx, sequencePath(networkName, source)); err != nil {
+		m.logger.Error("Failed to invalidate sequence number", "network", networkName, "source", source, "error", err)
+		return err
+	}
+	return nil
+}
+
+// syncSequenceState fetches the current sequence number from Horizon and stores the
+// next one. The caller must hold the sequence lock.
+func (m *Manager) syncSequenceState(ctx context.Context, storage logical.Storage, networkName string, source string) (*SequenceState, error) {
+	config, err := m.retrieveConfig(ctx, storage)
+	if err != nil {
+		return nil, err
+	}
+	client, err := config.horizonClient(networkName)
+	if err != nil {
+		return nil, invalidRequest("sequence must be provided: %s", err)
+	}
+	ledgerAccount, err := client.Account(ctx, source)
+	if err != nil {
+		return nil, horizonUnavailable("failed to fetch the sequence number of %s from Horizon: %s", source, err)
+	}
+	current, err := strconv.ParseInt(ledgerAccount.Sequence, 10, 64)
+	if err != nil {
+		return nil, horizonUnavailable("invalid sequence number from Horizon: %s", err)
+	}
+
+	state := &SequenceState{Source: source, Network: networkName, Next: current + 1, SyncedAt: time.Now()}
+	if err = m.saveSequenceState(ctx, storage, state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+func (m *Manager) saveSequenceState(ctx context.Context, storage logical.Storage, state *SequenceState) error {
+	entry, err := logical.StorageEntryJSON(sequencePath(state.Network, state.Source), state)
+	if err != nil {
+		return err
+	}
+	if err = storage.Put(ctx, entry); err != nil {
+		m.logger.Error("Failed to save sequence number", "network", state.Network, "source", state.Source, "error", err)
+		return err
+	}
+	return nil
+}
+
+func (m *Manager) retrieveSequenceState(ctx context.Context, storage logical.Storage, networkName string, source string) (*SequenceState, error) {
+	entry, err := storage.Get(ctx, sequencePath(networkName, source))
+	if err != nil {
+		m.logger.Error("Failed to retrieve sequence number", "network", networkName, "source", source, "error", err)
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+	var state SequenceState
+	if err = entry.DecodeJSON(&state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+func (m *Manager) ReadSequence(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	publicKey, networkName, err := sequenceRequest(data)
+	if err != nil {
+		return nil, err
+	}
+	if err = m.checkAccountAccess(ctx, req, publicKey); err != nil {
+		return nil, err
+	}
+
+	state, err := m.retrieveSequenceState(ctx, req.Storage, networkName, publicKey)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil {
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"public_key": publicKey,
+				"network":    networkName,
+				"tracked":    false,
+			},
+		}, nil
+	}
+	return &logical.Response{
+		Data: state.responseData(),
+	}, nil
+}
+
+// SyncSequence resynchronises the tracked sequence number from Horizon, or sets it
+// when a sequence is provided.
+func (m *Manager) SyncSequence(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	publicKey, networkName, err := sequenceRequest(data)
+	if err != nil {
+		return nil, err
+	}
+	if err = m.checkAccountAccess(ctx, req, publicKey); err != nil {
+		return nil, err
+	}
+
+	m.sequenceLock.Lock()
+	defer m.sequenceLock.Unlock()
+
+	var state *SequenceState
+	if next, ok := data.GetOk("next_sequence"); ok {
+		sequence, err := strconv.ParseInt(next.(string), 10, 64)
+		if err != nil || sequence <= 0 {
+			return nil, invalidRequest("invalid next_sequence: %s", next)
+		}
+		state = &SequenceState{Source: publicKey, Network: networkName, Next: sequence, SyncedAt: time.Now()}
+		err = m.saveSequenceState(ctx, req.Storage, state)
+		if err != nil {
+			return nil, err
+		}
+	} else if state, err = m.syncSequenceState(ctx, req.Storage, networkName, publicKey); err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: state.responseData(),
+	}, nil
+}
+
+func sequenceRequest(data *framework.FieldData) (string, string, error) {
+	publicKey, _, err := resolveAddress(data.Get("publicKey").(string))
+	if err != nil {
+		return "", "", err
+	}
+	networkName := data.Get("network").(string)
+	if _, ok := networkPassphrases[networkName]; !ok {
+		return "", "", invalidNetwork(networkName)
+	}
+	return publicKey, networkName, nil
+}
+
+func (s *SequenceState) responseData() map[string]interface{} {
+	return map[string]interface{}{
+		"public_key":    s.Source,
+		"network":       s.Network,
+		"tracked":       true,
+		"next_sequence": strconv.FormatInt(s.Next, 10),
+		"allocated":     s.Allocated,
+		"synced_at":     s.SyncedAt.Format(time.RFC3339),
+	}
+}
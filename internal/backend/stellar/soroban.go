@@ -0,0 +1,230 @@
+package stellar
+
+import (
+	"fmt"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/stellar/go/strkey"
+	"github.com/stellar/go/xdr"
+	"math/big"
+	"strconv"
+)
+
+// SorobanPolicy restricts which smart contract interactions a key may sign. Once a
+// policy has a Soroban section, any contract call that is not allowlisted is denied.
+type SorobanPolicy struct {
+	Contracts             []ContractRule `json:"contracts"`
+	MaxResourceFee        int64          `json:"max_resource_fee"`
+	AllowWasmUpload       bool           `json:"allow_wasm_upload"`
+	AllowContractCreation bool           `json:"allow_contract_creation"`
+}
+
+// ContractRule allows calls to a contract, optionally limited to a set of functions
+// and to specific argument values.
+type ContractRule struct {
+	Contract  string          `json:"contract"`
+	Functions []string        `json:"functions,omitempty"`
+	Args      []ArgConstraint `json:"args,omitempty"`
+}
+
+// ArgConstraint limits the argument at Index to one of Values. Values are compared
+// with the string form of the argument: strkeys for addresses, decimal integers,
+// and the raw text of symbols and strings.
+type ArgConstraint struct {
+	Function string   `json:"function,omitempty"`
+	Index    int      `json:"index"`
+	Values   []string `json:"values"`
+}
+
+func sorobanPolicyFromFieldData(data *framework.FieldData) (*SorobanPolicy, error) {
+	rawContracts, hasContracts := data.GetOk("soroban_contracts")
+	maxResourceFee, hasMaxFee := data.GetOk("soroban_max_resource_fee")
+	allowWasmUpload, hasWasmUpload := data.GetOk("soroban_allow_wasm_upload")
+	allowCreation, hasCreation := data.GetOk("soroban_allow_contract_creation")
+	if !hasContracts && !hasMaxFee && !hasWasmUpload && !hasCreation {
+		return nil, nil
+	}
+
+	sp := &SorobanPolicy{Contracts: []ContractRule{}}
+	if hasContracts {
+		if err := decodeObjectList(rawContracts.([]interface{}), &sp.Contracts); err != nil {
+			return nil, invalidRequest("invalid soroban_contracts: %s", err)
+		}
+	}
+	if hasMaxFee {
+		sp.MaxResourceFee = int64(maxResourceFee.(int))
+		if sp.MaxResourceFee < 0 {
+			return nil, invalidRequest("soroban_max_resource_fee must not be negative")
+		}
+	}
+	if hasWasmUpload {
+		sp.AllowWasmUpload = allowWasmUpload.(bool)
+	}
+	if hasCreation {
+		sp.AllowContractCreation = allowCreation.(bool)
+	}
+
+	for _, rule := range sp.Contracts {
+		if _, err := strkey.Decode(strkey.VersionByteContract, rule.Contract); err != nil {
+			return nil, invalidRequest("invalid contract address %q: %s", rule.Contract, err)
+		}
+		for _, arg := range rule.Args {
+			if arg.Index < 0 {
+				return nil, invalidRequest("invalid argument index %d for contract %s", arg.Index, rule.Contract)
+			}
+		}
+	}
+	return sp, nil
+}
+
+func (sp *SorobanPolicy) evaluate(policyName string, envelope xdr.TransactionEnvelope) error {
+	deny := func(rule, format string, args ...interface{}) error {
+		return &PolicyDenial{Policy: policyName, Rule: rule, Reason: fmt.Sprintf(format, args...)}
+	}
+
+	for i, op := range envelope.Operations() {
+		if op.Body.Type != xdr.OperationTypeInvokeHostFunction {
+			continue
+		}
+		invokeOp := op.Body.MustInvokeHostFunctionOp()
+		hostFn := invokeOp.HostFunction
+		switch hostFn.Type {
+		case xdr.HostFunctionTypeHostFunctionTypeInvokeContract:
+			if err := sp.checkInvocation(*hostFn.InvokeContract); err != nil {
+				return deny("soroban_contracts", "operation %d: %s", i, err)
+			}
+		case xdr.HostFunctionTypeHostFunctionTypeCreateContract:
+			if !sp.AllowContractCreation {
+				return deny("soroban_allow_contract_creation", "operation %d creates a contract", i)
+			}
+		case xdr.HostFunctionTypeHostFunctionTypeUploadContractWasm:
+			if !sp.AllowWasmUpload {
+				return deny("soroban_allow_wasm_upload", "operation %d uploads contract wasm", i)
+			}
+		default:
+			return deny("soroban_contracts", "operation %d has unknown host function type %d", i, hostFn.Type)
+		}
+
+		for _, auth := range invokeOp.Auth {
+			if rule, err := sp.checkAuthorizedInvocation(auth.RootInvocation); err != nil {
+				return deny(rule, "operation %d authorization: %s", i, err)
+			}
+		}
+	}
+
+	if sp.MaxResourceFee > 0 && envelope.Type == xdr.EnvelopeTypeEnvelopeTypeTx {
+		if sorobanData := envelope.V1.Tx.Ext.SorobanData; sorobanData != nil {
+			if int64(sorobanData.ResourceFee) > sp.MaxResourceFee {
+				return deny("soroban_max_resource_fee", "resource fee %d exceeds maximum of %d",
+					sorobanData.ResourceFee, sp.MaxResourceFee)
+			}
+		}
+	}
+	return nil
+}
+
+// checkAuthorizedInvocation walks a SorobanAuthorizedInvocation tree and checks every
+// node against the policy. It returns the name of the violated rule with the error.
+func (sp *SorobanPolicy) checkAuthorizedInvocation(inv xdr.SorobanAuthorizedInvocation) (string, error) {
+	switch inv.Function.Type {
+	case xdr.SorobanAuthorizedFunctionTypeSorobanAuthorizedFunctionTypeContractFn:
+		if err := sp.checkInvocation(*inv.Function.ContractFn); err != nil {
+			return "soroban_contracts", err
+		}
+	case xdr.SorobanAuthorizedFunctionTypeSorobanAuthorizedFunctionTypeCreateContractHostFn:
+		if !sp.AllowContractCreation {
+			return "soroban_allow_contract_creation", fmt.Errorf("authorizes contract creation")
+		}
+	default:
+		return "soroban_contracts", fmt.Errorf("unknown authorized function type %d", inv.Function.Type)
+	}
+
+	for _, sub := range inv.SubInvocations {
+		if rule, err := sp.checkAuthorizedInvocation(sub); err != nil {
+			return rule, err
+		}
+	}
+	return "", nil
+}
+
+func (sp *SorobanPolicy) checkInvocation(args xdr.InvokeContractArgs) error {
+	contract, err := args.ContractAddress.String()
+	if err != nil {
+		return fmt.Errorf("invalid contract address: %s", err)
+	}
+	function := string(args.FunctionName)
+
+	// Any matching rule allows the call, the arguments rejected by the first one are
+	// reported when none does
+	var argsErr error
+	for _, rule := range sp.Contracts {
+		if rule.Contract != contract {
+			continue
+		}
+		if len(rule.Functions) > 0 && !containsString(rule.Functions, function) {
+			continue
+		}
+		err = rule.checkArgs(function, args.Args)
+		if err == nil {
+			return nil
+		}
+		if argsErr == nil {
+			argsErr = fmt.Errorf("call to %s.%s: %s", contract, function, err)
+		}
+	}
+	if argsErr != nil {
+		return argsErr
+	}
+	return fmt.Errorf("call to %s.%s is not allowed", contract, function)
+}
+
+func (r ContractRule) checkArgs(function string, args []xdr.ScVal) error {
+	for _, constraint := range r.Args {
+		if constraint.Function != "" && constraint.Function != function {
+			continue
+		}
+		if constraint.Index >= len(args) {
+			return fmt.Errorf("argument %d is missing", constraint.Index)
+		}
+		value, ok := scValString(args[constraint.Index])
+		if !ok {
+			return fmt.Errorf("argument %d has unsupported type %s", constraint.Index, args[constraint.Index].Type)
+		}
+		if !containsString(constraint.Values, value) {
+			return fmt.Errorf("argument %d value %s is not allowed", constraint.Index, value)
+		}
+	}
+	return nil
+}
+
+// scValString renders the ScVal types that argument constraints can match on.
+func scValString(v xdr.ScVal) (string, bool) {
+	switch v.Type {
+	case xdr.ScValTypeScvBool:
+		return strconv.FormatBool(v.MustB()), true
+	case xdr.ScValTypeScvU32:
+		return strconv.FormatUint(uint64(v.MustU32()), 10), true
+	case xdr.ScValTypeScvI32:
+		return strconv.FormatInt(int64(v.MustI32()), 10), true
+	case xdr.ScValTypeScvU64:
+		return strconv.FormatUint(uint64(v.MustU64()), 10), true
+	case xdr.ScValTypeScvI64:
+		return strconv.FormatInt(int64(v.MustI64()), 10), true
+	case xdr.ScValTypeScvU128:
+		parts := v.MustU128()
+		n := new(big.Int).Lsh(new(big.Int).SetUint64(uint64(parts.Hi)), 64)
+		return n.Or(n, new(big.Int).SetUint64(uint64(parts.Lo))).String(), true
+	case xdr.ScValTypeScvI128:
+		parts := v.MustI128()
+		n := new(big.Int).Lsh(big.NewInt(int64(parts.Hi)), 64)
+		return n.Add(n, new(big.Int).SetUint64(uint64(parts.Lo))).String(), true
+	case xdr.ScValTypeScvSymbol:
+		return string(v.MustSym()), true
+	case xdr.ScValTypeScvString:
+		return string(v.MustStr()), true
+	case xdr.ScValTypeScvAddress:
+		address, err := v.MustAddress().String()
+		return address, err == nil
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,84 @@
+package stellar
+
+import (
+	"github.com/stellar/go/xdr"
+)
+
+// anySource allows an account to sign for any ledger account
+const anySource = "*"
+
+// parseAllowedSources validates a list of G- or M-addresses an account may sign for
+func parseAllowedSources(sources []string) ([]string, error) {
+	if len(sources) == 0 {
+		return nil, invalidRequest("allowed_sources must not be empty, use %q to allow any source account", anySource)
+	}
+	for _, source := range sources {
+		if source == anySource {
+			continue
+		}
+		if _, err := xdr.AddressToMuxedAccount(source); err != nil {
+			return nil, invalidRequest("invalid source account %q: %s", source, err)
+		}
+	}
+	return sources, nil
+}
+
+// allowedSources returns the ledger accounts the account may sign for. Accounts stored
+// before source binding existed only sign for themselves.
+func (a *Account) allowedSources() []string {
+	if len(a.AllowedSources) == 0 {
+		return []string{a.PublicKey}
+	}
+	return a.AllowedSources
+}
+
+// checkSourceBinding verifies the transaction source and every operation source are
+// ledger accounts the account is allowed to sign for. A G-address allows the account
+// under any mux ID, an M-address only allows that muxed account.
+func (a *Account) checkSourceBinding(envelope xdr.TransactionEnvelope) error {
+	allowed := a.allowedSources()
+	if containsString(allowed, anySource) {
+		return nil
+	}
+
+	txSource := envelope.SourceAccount()
+	if !sourceAllowed(allowed, txSource) {
+		return forbidden(ErrCodeSourceNotAllowed, "account %s is not allowed to sign for source account %s", a.PublicKey, txSource.Address())
+	}
+	for i, op := range envelope.Operations() {
+		if op.SourceAccount == nil {
+			continue
+		}
+		if !sourceAllowed(allowed, *op.SourceAccount) {
+			return forbidden(ErrCodeSourceNotAllowed, "account %s is not allowed to sign for operation %d source account %s",
+				a.PublicKey, i, op.SourceAccount.Address())
+		}
+	}
+	return nil
+}
+
+func sourceAllowed(allowed []string, source xdr.MuxedAccount) bool {
+	accountID := source.ToAccountId()
+	return containsString(allowed, source.Address()) || containsString(allowed, accountID.Address())
+}
+
+// checkChannelSourceBinding verifies a transaction built on a channel account. The
+// channel is the transaction source and only pays the fee, so every operation must
+// name a source account the account is allowed to sign for.
+func (a *Account) checkChannelSourceBinding(channel string, envelope xdr.TransactionEnvelope) error {
+	txSource := envelope.SourceAccount().ToAccountId()
+	if txSource.Address() != channel {
+		return forbidden(ErrCodeSourceNotAllowed, "transaction source account %s is not the channel %s", txSource.Address(), channel)
+	}
+	allowed := a.allowedSources()
+	for i, op := range envelope.Operations() {
+		if op.SourceAccount == nil {
+			return forbidden(ErrCodeSourceNotAllowed, "operation %d must set a source account, the channel only pays the fee", i)
+		}
+		if !containsString(allowed, anySource) && !sourceAllowed(allowed, *op.SourceAccount) {
+			return forbidden(ErrCodeSourceNotAllowed, "account %s is not allowed to sign for operation %d source account %s",
+				a.PublicKey, i, op.SourceAccount.Address())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,332 @@
+package stellar
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"github.com/hashicorp/vault/sdk/logical"
+	"golang.org/x/crypto/hkdf"
+	"io"
+	"strings"
+)
+
+const (
+	// secretKeysPrefix holds the encrypted seeds of the accounts, separately from their
+	// metadata so that seal wrapping only applies to secret material
+	secretKeysPrefix = "stellar/keys/"
+	// keyringPath holds the data encryption key of the mount, wrapped with the key
+	// encryption secret
+	keyringPath = "stellar/keyring"
+	schemaPath  = "stellar/schema"
+
+	// storageSchemaVersion is the version of the storage layout this code reads and writes
+	storageSchemaVersion = 2
+
+	// KeyEncryptionSecretEnv names the operator-supplied secret the data encryption key is
+	// wrapped with. It is set in the plugin environment, so that a copy of the plugin
+	// storage alone does not decrypt the seeds.
+	KeyEncryptionSecretEnv = "STELLAR_KEY_ENCRYPTION_SECRET"
+	// minKeyEncryptionSecretLength is the length of 32 random bytes encoded in base64
+	minKeyEncryptionSecretLength = 44
+)
+
+// SealWrapPaths are the storage prefixes holding secret material, seal-wrapped on
+// Vault Enterprise
+var SealWrapPaths = []string{secretKeysPrefix, keyringPath}
+
+// StorageSchema records which migrations have been applied to the storage of the mount
+type StorageSchema struct {
+	Version int `json:"version"`
+}
+
+// keyring holds the AES-256 key the seeds are encrypted with, sealed with a key derived
+// from the key encryption secret and the salt
+type keyring struct {
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	WrappedKey []byte `json:"wrapped_key"`
+}
+
+// encryptedSecretKey is an account seed sealed with the data encryption key of the
+// mount. The public key of the account is the additional data.
+type encryptedSecretKey struct {
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// migration upgrades the storage to its version
+type migration struct {
+	version int
+	migrate func(ctx context.Context, storage logical.Storage) error
+}
+
+func (m *Manager) migrations() []migration {
+	return []migration{
+		{version: 1, migrate: m.migrateSecretKeys},
+		{version: 2, migrate: m.migrateAccountIndex},
+	}
+}
+
+// Initialize runs the storage migrations the mount has not applied yet, in order. It is
+// registered as the backend's initialize function and only runs where storage is writable.
+// Storage written by a newer version of the plugin is refused rather than misread.
+func (m *Manager) Initialize(ctx context.Context, req *logical.InitializationRequest) error {
+	schema, err := m.retrieveStorageSchema(ctx, req.Storage)
+	if err != nil {
+		return err
+	}
+	if schema.Version > storageSchemaVersion {
+		m.logger.Error("Storage was written by a newer version of the plugin", "version", schema.Version, "supported", storageSchemaVersion)
+		return fmt.Errorf("storage schema version %d is newer than the version %d this plugin supports", schema.Version, storageSchemaVersion)
+	}
+	for _, mig := range m.migrations() {
+		if mig.version <= schema.Version {
+			continue
+		}
+		m.logger.Info("Migrating storage", "from", schema.Version, "to", mig.version)
+		if err = mig.migrate(ctx, req.Storage); err != nil {
+			m.logger.Error("Storage migration failed", "version", mig.version, "error", err)
+			return fmt.Errorf("storage migration to version %d failed: %s", mig.version, err)
+		}
+		schema.Version = mig.version
+		entry, err := logical.StorageEntryJSON(schemaPath, schema)
+		if err != nil {
+			return err
+		}
+		if err = req.Storage.Put(ctx, entry); err != nil {
+			return fmt.Errorf("failed to record the storage schema version: %s", err)
+		}
+	}
+	return nil
+}
+
+func (m *Manager) retrieveStorageSchema(ctx context.Context, storage logical.Storage) (*StorageSchema, error) {
+	entry, err := storage.Get(ctx, schemaPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve the storage schema version: %s", err)
+	}
+	schema := &StorageSchema{}
+	if entry == nil {
+		return schema, nil
+	}
+	if err = entry.DecodeJSON(schema); err != nil {
+		return nil, fmt.Errorf("failed to decode the storage schema version: %s", err)
+	}
+	return schema, nil
+}
+
+// migrateSecretKeys moves the seeds stored in plaintext with the account metadata to
+// the encrypted, seal-wrapped secret keys prefix
+func (m *Manager) migrateSecretKeys(ctx context.Context, storage logical.Storage) error {
+	publicKeys, err := storage.List(ctx, "stellar/accounts/")
+	if err != nil {
+		return fmt.Errorf("failed to list stellar accounts: %s", err)
+	}
+	for _, publicKey := range publicKeys {
+		account, err := m.retrieveAccount(ctx, storage, publicKey)
+		if err != nil {
+			return err
+		}
+		if account == nil || account.SecretKey == "" {
+			continue
+		}
+		if err = m.storeSecretKey(ctx, storage, account); err != nil {
+			return err
+		}
+		if err = m.saveAccount(ctx, storage, account); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createAccount stores the seed, the metadata and the index key of a new account
+func (m *Manager) createAccount(ctx context.Context, storage logical.Storage, account *Account) error {
+	if err := m.storeSecretKey(ctx, storage, account); err != nil {
+		return err
+	}
+	if err := m.saveAccount(ctx, storage, account); err != nil {
+		return err
+	}
+	return m.indexAccount(ctx, storage, account)
+}
+
+// deleteAccount removes the metadata, the seed and the index key of an account
+func (m *Manager) deleteAccount(ctx context.Context, storage logical.Storage, account *Account) error {
+	if err := storage.Delete(ctx, fmt.Sprintf("stellar/accounts/%s", account.PublicKey)); err != nil {
+		return err
+	}
+	if err := storage.Delete(ctx, secretKeysPrefix+account.PublicKey); err != nil {
+		return err
+	}
+	if err := m.unindexAccount(ctx, storage, account); err != nil {
+		return err
+	}
+	return m.rememberDeletedAccount(ctx, storage, account)
+}
+
+// rollBackAccounts deletes the accounts stored by a request creating several of them
+// that failed, and returns the error of the creation with the accounts that could not
+// be deleted if any
+func (m *Manager) rollBackAccounts(ctx context.Context, storage logical.Storage, accounts []*Account, cause error) error {
+	var remaining []string
+	for _, account := range accounts {
+		if err := m.deleteAccount(ctx, storage, account); err != nil {
+			m.logger.Error("Failed to delete an account of a failed creation", "publicKey", account.PublicKey, "error", err)
+			remaining = append(remaining, account.PublicKey)
+		}
+	}
+	if len(remaining) > 0 {
+		return fmt.Errorf("failed to create the accounts: %w; accounts %s were created but could not be deleted",
+			cause, strings.Join(remaining, ", "))
+	}
+	return fmt.Errorf("failed to create the accounts, none were kept: %w", cause)
+}
+
+func (m *Manager) storeSecretKey(ctx context.Context, storage logical.Storage, account *Account) error {
+	aead, err := m.dataKey(ctx, storage)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return fmt.Errorf("failed to generate a nonce: %s", err)
+	}
+	sealed := &encryptedSecretKey{
+		Nonce:      nonce,
+		Ciphertext: aead.Seal(nil, nonce, []byte(account.SecretKey), []byte(account.PublicKey)),
+	}
+	entry, err := logical.StorageEntryJSON(secretKeysPrefix+account.PublicKey, sealed)
+	if err != nil {
+		return err
+	}
+	if err = storage.Put(ctx, entry); err != nil {
+		m.logger.Error("Failed to save the secret key to storage", "publicKey", account.PublicKey, "error", err)
+		return err
+	}
+	return nil
+}
+
+// retrieveSecretKey returns the decrypted seed of an account, or an empty string if none is stored
+func (m *Manager) retrieveSecretKey(ctx context.Context, storage logical.Storage, publicKey string) (string, error) {
+	entry, err := storage.Get(ctx, secretKeysPrefix+publicKey)
+	if err != nil {
+		m.logger.Error("Failed to retrieve the secret key", "publicKey", publicKey, "error", err)
+		return "", err
+	}
+	if entry == nil {
+		return "", nil
+	}
+	var sealed encryptedSecretKey
+	if err = entry.DecodeJSON(&sealed); err != nil {
+		return "", fmt.Errorf("failed to decode the secret key: %s", err)
+	}
+	aead, err := m.dataKey(ctx, storage)
+	if err != nil {
+		return "", err
+	}
+	secretKey, err := aead.Open(nil, sealed.Nonce, sealed.Ciphertext, []byte(publicKey))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt the secret key of %s: %s", publicKey, err)
+	}
+	return string(secretKey), nil
+}
+
+// dataKey returns the cipher of the mount's data encryption key, generating the key
+// the first time a seed is stored
+func (m *Manager) dataKey(ctx context.Context, storage logical.Storage) (cipher.AEAD, error) {
+	m.keyringLock.Lock()
+	defer m.keyringLock.Unlock()
+	if m.keyring != nil {
+		return m.keyring, nil
+	}
+	if len(m.keyEncryptionSecret) < minKeyEncryptionSecretLength {
+		return nil, fmt.Errorf("%s must be set to at least %d characters in the plugin environment to store or use seeds",
+			KeyEncryptionSecretEnv, minKeyEncryptionSecretLength)
+	}
+
+	entry, err := storage.Get(ctx, keyringPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve the keyring: %s", err)
+	}
+	var key []byte
+	if entry == nil {
+		key = make([]byte, 32)
+		if _, err = io.ReadFull(rand.Reader, key); err != nil {
+			return nil, fmt.Errorf("failed to generate the data encryption key: %s", err)
+		}
+		err = m.saveKeyring(ctx, storage, key)
+	} else {
+		kr := &keyring{}
+		if err = entry.DecodeJSON(kr); err != nil {
+			return nil, fmt.Errorf("failed to decode the keyring: %s", err)
+		}
+		key, err = m.unwrapDataKey(kr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if m.keyring, err = cipher.NewGCM(block); err != nil {
+		return nil, err
+	}
+	return m.keyring, nil
+}
+
+// saveKeyring stores the data encryption key wrapped under a new salt
+func (m *Manager) saveKeyring(ctx context.Context, storage logical.Storage, key []byte) error {
+	kr := &keyring{Salt: make([]byte, 32)}
+	if _, err := io.ReadFull(rand.Reader, kr.Salt); err != nil {
+		return fmt.Errorf("failed to generate the keyring salt: %s", err)
+	}
+	kek, err := m.keyEncryptionKey(kr.Salt)
+	if err != nil {
+		return err
+	}
+	kr.Nonce = make([]byte, kek.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, kr.Nonce); err != nil {
+		return fmt.Errorf("failed to generate a nonce: %s", err)
+	}
+	kr.WrappedKey = kek.Seal(nil, kr.Nonce, key, []byte(keyringPath))
+	entry, err := logical.StorageEntryJSON(keyringPath, kr)
+	if err != nil {
+		return err
+	}
+	if err = storage.Put(ctx, entry); err != nil {
+		return fmt.Errorf("failed to save the keyring: %s", err)
+	}
+	return nil
+}
+
+func (m *Manager) unwrapDataKey(kr *keyring) ([]byte, error) {
+	kek, err := m.keyEncryptionKey(kr.Salt)
+	if err != nil {
+		return nil, err
+	}
+	key, err := kek.Open(nil, kr.Nonce, kr.WrappedKey, []byte(keyringPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap the data encryption key, %s does not match the one it was wrapped with", KeyEncryptionSecretEnv)
+	}
+	return key, nil
+}
+
+// keyEncryptionKey derives the cipher wrapping the data encryption key from the key
+// encryption secret
+func (m *Manager) keyEncryptionKey(salt []byte) (cipher.AEAD, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, m.keyEncryptionSecret, salt, []byte("stellar-sign keyring")), key); err != nil {
+		return nil, fmt.Errorf("failed to derive the key encryption key: %s", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
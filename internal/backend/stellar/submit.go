@@ -0,0 +1,413 @@
+package stellar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stellar/go/xdr"
+	"strings"
+	"time"
+	"vault-plugin-stellar-sign/internal/backend/horizon"
+)
+
+const (
+	SubmissionPending = "pending"
+	SubmissionSuccess = "success"
+	SubmissionFailed  = "failed"
+
+	submissionTimeout = 60 * time.Second
+
+	// submissionsPrefix holds the submissions as <publicKey>/<hash>, as accounts co-signing
+	// the same transaction each submit it
+	submissionsPrefix = "stellar/submissions/"
+	// pendingSubmissionsPrefix indexes the submissions whose outcome is unknown as
+	// <publicKey>.<hash>, for the periodic function to settle
+	pendingSubmissionsPrefix = "stellar/submissions-pending/"
+	// completedSubmissionsPrefix indexes settled submissions by the day they completed,
+	// as <day>/<publicKey>.<hash>, so that they expire after submissionRetentionDays
+	completedSubmissionsPrefix = "stellar/submissions-completed/"
+	submissionRetentionDays    = 30
+)
+
+// txResultDescriptions explains the transaction result codes returned by Stellar Core
+var txResultDescriptions = map[xdr.TransactionResultCode]struct {
+	code        string
+	description string
+}{
+	xdr.TransactionResultCodeTxFailed:              {"tx_failed", "one of the operations failed"},
+	xdr.TransactionResultCodeTxTooEarly:            {"tx_too_early", "the ledger close time is before the transaction min time"},
+	xdr.TransactionResultCodeTxTooLate:             {"tx_too_late", "the ledger close time is after the transaction max time"},
+	xdr.TransactionResultCodeTxMissingOperation:    {"tx_missing_operation", "the transaction has no operations"},
+	xdr.TransactionResultCodeTxBadSeq:              {"tx_bad_seq", "the sequence number does not match the source account"},
+	xdr.TransactionResultCodeTxBadAuth:             {"tx_bad_auth", "too few valid signatures or wrong network"},
+	xdr.TransactionResultCodeTxInsufficientBalance: {"tx_insufficient_balance", "the fee would bring the source account below the reserve"},
+	xdr.TransactionResultCodeTxNoAccount:           {"tx_no_account", "the source account does not exist"},
+	xdr.TransactionResultCodeTxInsufficientFee:     {"tx_insufficient_fee", "the fee is too small"},
+	xdr.TransactionResultCodeTxBadAuthExtra:        {"tx_bad_auth_extra", "the transaction has unused signatures"},
+	xdr.TransactionResultCodeTxInternalError:       {"tx_internal_error", "an unknown error occurred in Stellar Core"},
+	xdr.TransactionResultCodeTxNotSupported:        {"tx_not_supported", "the transaction type is not supported"},
+	xdr.TransactionResultCodeTxFeeBumpInnerFailed:  {"tx_fee_bump_inner_failed", "the inner transaction of the fee bump failed"},
+	xdr.TransactionResultCodeTxBadSponsorship:      {"tx_bad_sponsorship", "sponsorship is not confirmed or not ended"},
+	xdr.TransactionResultCodeTxBadMinSeqAgeOrGap:   {"tx_bad_min_seq_age_or_gap", "the min sequence age or ledger gap precondition is not met"},
+	xdr.TransactionResultCodeTxMalformed:           {"tx_malformed", "the transaction is malformed"},
+	xdr.TransactionResultCodeTxSorobanInvalid:      {"tx_soroban_invalid", "the Soroban transaction data is invalid"},
+}
+
+// Submission tracks a signed transaction submitted to Horizon
+type Submission struct {
+	TxHash         string    `json:"tx_hash"`
+	PublicKey      string    `json:"public_key"`
+	Network        string    `json:"network"`
+	TxEnvelope     string    `json:"signed_transaction"`
+	Status         string    `json:"status"`
+	Ledger         int32     `json:"ledger,omitempty"`
+	ResultCode     string    `json:"result_code,omitempty"`
+	OperationCodes []string  `json:"operation_codes,omitempty"`
+	Error          string    `json:"error,omitempty"`
+	SubmittedAt    time.Time `json:"submitted_at"`
+	CompletedAt    time.Time `json:"completed_at,omitempty"`
+	// Channel is the channel lease released once the outcome is known
+	Channel *ChannelLease `json:"channel,omitempty"`
+}
+
+func submissionPath(publicKey string, txHash string) string {
+	return fmt.Sprintf("%s%s/%s", submissionsPrefix, publicKey, txHash)
+}
+
+// indexKey is the key of a submission in the pending and completed indexes
+func (s *Submission) indexKey() string {
+	return s.PublicKey + "." + s.TxHash
+}
+
+// parseSubmissionIndexKey returns the public key and the hash of an index key
+func parseSubmissionIndexKey(key string) (string, string, bool) {
+	return strings.Cut(key, ".")
+}
+
+func completedSubmissionPath(submission *Submission) string {
+	return fmt.Sprintf("%s%s/%s", completedSubmissionsPrefix, submission.CompletedAt.UTC().Format(historyDayLayout), submission.indexKey())
+}
+
+// submit posts a signed transaction to the Horizon configured for the network. Async
+// submissions are recorded as pending and completed in the background with the storage
+// of the backend, as the storage of the request is only valid until it returns. Without
+// it, they are left to the periodic function.
+func (m *Manager) submit(ctx context.Context, storage logical.Storage, config *Config, submission *Submission, async bool) error {
+	client, err := config.horizonClient(submission.Network)
+	if err != nil {
+		return err
+	}
+
+	submission.Status = SubmissionPending
+	submission.SubmittedAt = time.Now()
+	if err = m.saveSubmission(ctx, storage, submission); err != nil {
+		return err
+	}
+
+	if async {
+		if m.storage == nil {
+			return nil
+		}
+		pending := *submission
+		go func() {
+			bgCtx, cancel := context.WithTimeout(context.Background(), submissionTimeout)
+			defer cancel()
+			if err := m.completeSubmission(bgCtx, m.storage, client, &pending); err != nil {
+				m.logger.Error("Failed to complete async submission", "txHash", submission.TxHash, "error", err)
+			}
+		}()
+		return nil
+	}
+	return m.completeSubmission(ctx, storage, client, submission)
+}
+
+// completeSubmission submits the transaction and records the outcome on the submission
+// and in the signing history. Submissions Horizon could not settle stay pending, the ones
+// it rejected without a transaction result fail with the problem type as result code.
+func (m *Manager) completeSubmission(ctx context.Context, storage logical.Storage, client *horizon.Client, submission *Submission) error {
+	tx, err := client.SubmitTransaction(ctx, submission.TxEnvelope)
+	var problem *horizon.Problem
+	switch {
+	case err == nil:
+		submission.Status = SubmissionSuccess
+		submission.Ledger = tx.Ledger
+		submission.CompletedAt = time.Now()
+	case errors.As(err, &problem) && problem.ResultXDR() != "":
+		submission.Status = SubmissionFailed
+		submission.ResultCode, submission.Error = describeTransactionResult(problem.ResultXDR())
+		submission.OperationCodes = problem.OperationResultCodes()
+		if len(submission.OperationCodes) > 0 {
+			submission.Error = fmt.Sprintf("%s (operations: %s)", submission.Error, strings.Join(submission.OperationCodes, ", "))
+		}
+		submission.CompletedAt = time.Now()
+		if submission.ResultCode == "tx_bad_seq" {
+			m.invalidateSubmissionSequence(ctx, storage, submission)
+		}
+	case errors.As(err, &problem) && problem.Rejected():
+		submission.Status = SubmissionFailed
+		submission.ResultCode = problem.Code()
+		submission.Error = problem.Error()
+		submission.CompletedAt = time.Now()
+	default:
+		// Timeouts and connection errors leave the outcome unknown, the periodic function retries
+		submission.Error = err.Error()
+	}
+
+	if err := m.saveSubmission(ctx, storage, submission); err != nil {
+		return err
+	}
+	if err := m.recordSubmissionHistory(ctx, storage, submission); err != nil {
+		return err
+	}
+	if submission.Status != SubmissionPending && submission.Channel != nil {
+		if err := m.releaseChannel(ctx, storage, submission.Channel); err != nil {
+			return err
+		}
+	}
+
+	switch submission.Status {
+	case SubmissionFailed:
+		return badRequest(ErrCodeTransactionFailed, "transaction %s failed: %s", submission.TxHash, submission.Error)
+	case SubmissionPending:
+		return submissionPending("transaction %s submission is pending: %s", submission.TxHash, submission.Error)
+	}
+	return nil
+}
+
+// describeTransactionResult decodes a base64 TransactionResult into its result code and a readable message
+func describeTransactionResult(resultXDR string) (string, string) {
+	var result xdr.TransactionResult
+	if err := xdr.SafeUnmarshalBase64(resultXDR, &result); err != nil {
+		return "unknown", fmt.Sprintf("undecodable transaction result: %s", err)
+	}
+	if d, ok := txResultDescriptions[result.Result.Code]; ok {
+		return d.code, fmt.Sprintf("%s: %s", d.code, d.description)
+	}
+	return result.Result.Code.String(), result.Result.Code.String()
+}
+
+// invalidateSubmissionSequence drops the tracked sequence number of the source of a
+// transaction rejected with tx_bad_seq, so the next build resyncs from Horizon
+func (m *Manager) invalidateSubmissionSequence(ctx context.Context, storage logical.Storage, submission *Submission) {
+	var envelope xdr.TransactionEnvelope
+	if err := xdr.SafeUnmarshalBase64(submission.TxEnvelope, &envelope); err != nil {
+		return
+	}
+	source := envelope.SourceAccount().ToAccountId()
+	if err := m.invalidateSequence(ctx, storage, submission.Network, source.Address()); err != nil {
+		m.logger.Warn("Failed to resync sequence number after tx_bad_seq", "txHash", submission.TxHash, "error", err)
+	}
+}
+
+func (m *Manager) recordSubmissionHistory(ctx context.Context, storage logical.Storage, submission *Submission) error {
+	record, err := m.retrieveHistory(ctx, storage, submission.PublicKey, submission.TxHash)
+	if err != nil || record == nil {
+		return err
+	}
+	record.SubmissionStatus = submission.Status
+	record.Ledger = submission.Ledger
+	record.ResultCode = submission.ResultCode
+	return m.recordHistory(ctx, storage, submission.PublicKey, record)
+}
+
+// saveSubmission stores the submission and moves it from the pending index to the
+// completed one once settled
+func (m *Manager) saveSubmission(ctx context.Context, storage logical.Storage, submission *Submission) error {
+	entry, err := logical.StorageEntryJSON(submissionPath(submission.PublicKey, submission.TxHash), submission)
+	if err != nil {
+		return err
+	}
+	if err = storage.Put(ctx, entry); err != nil {
+		m.logger.Error("Failed to save submission", "txHash", submission.TxHash, "error", err)
+		return err
+	}
+	if err = m.indexSubmission(ctx, storage, submission); err != nil {
+		m.logger.Error("Failed to index submission", "txHash", submission.TxHash, "error", err)
+		return err
+	}
+	return nil
+}
+
+func (m *Manager) indexSubmission(ctx context.Context, storage logical.Storage, submission *Submission) error {
+	if submission.Status == SubmissionPending {
+		return storage.Put(ctx, &logical.StorageEntry{Key: pendingSubmissionsPrefix + submission.indexKey(), Value: []byte{}})
+	}
+	if err := storage.Put(ctx, &logical.StorageEntry{Key: completedSubmissionPath(submission), Value: []byte{}}); err != nil {
+		return err
+	}
+	return storage.Delete(ctx, pendingSubmissionsPrefix+submission.indexKey())
+}
+
+func (m *Manager) retrieveSubmission(ctx context.Context, storage logical.Storage, publicKey string, txHash string) (*Submission, error) {
+	entry, err := storage.Get(ctx, submissionPath(publicKey, txHash))
+	if err != nil {
+		m.logger.Error("Failed to retrieve submission", "txHash", txHash, "error", err)
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+	var submission Submission
+	if err = entry.DecodeJSON(&submission); err != nil {
+		return nil, err
+	}
+	return &submission, nil
+}
+
+func (m *Manager) ReadSubmission(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	publicKey, _, err := resolveAddress(data.Get("publicKey").(string))
+	if err != nil {
+		return nil, err
+	}
+	txHash := data.Get("hash").(string)
+
+	submission, err := m.retrieveSubmission(ctx, req.Storage, publicKey, txHash)
+	if err != nil {
+		return nil, err
+	}
+	if submission != nil {
+		// Submissions are hidden from callers that cannot use the account
+		account, _, err := m.retrieveAccessibleAccount(ctx, req, publicKey)
+		if err != nil {
+			return nil, err
+		}
+		if account == nil {
+			submission = nil
+		}
+	}
+	if submission == nil {
+		return nil, notFound(ErrCodeSubmissionNotFound, "submission does not exist")
+	}
+
+	return &logical.Response{
+		Data: submission.responseData(),
+	}, nil
+}
+
+// ProcessPendingSubmissions settles submissions whose outcome is still unknown, e.g.
+// after a Horizon timeout or a restart during an async submission. It is run by the
+// backend's periodic function.
+func (m *Manager) ProcessPendingSubmissions(ctx context.Context, req *logical.Request) error {
+	keys, err := req.Storage.List(ctx, pendingSubmissionsPrefix)
+	if err != nil {
+		return err
+	}
+	config, err := m.retrieveConfig(ctx, req.Storage)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		publicKey, hash, _ := parseSubmissionIndexKey(key)
+		submission, err := m.retrieveSubmission(ctx, req.Storage, publicKey, hash)
+		if err != nil {
+			return err
+		}
+		if submission == nil || submission.Status != SubmissionPending {
+			// The index outlived its submission
+			if err = req.Storage.Delete(ctx, pendingSubmissionsPrefix+key); err != nil {
+				return err
+			}
+			continue
+		}
+		if time.Since(submission.SubmittedAt) < submissionTimeout {
+			continue
+		}
+		client, err := config.horizonClient(submission.Network)
+		if err != nil {
+			m.logger.Warn("Cannot settle pending submission", "txHash", hash, "error", err)
+			continue
+		}
+
+		// The transaction may have made it into a ledger even if the submission timed out
+		tx, err := client.Transaction(ctx, hash)
+		switch {
+		case err == nil:
+			submission.Status = SubmissionSuccess
+			if !tx.Successful {
+				submission.Status = SubmissionFailed
+				submission.ResultCode, submission.Error = describeTransactionResult(tx.ResultXDR)
+			}
+			submission.Ledger = tx.Ledger
+			submission.CompletedAt = time.Now()
+			if err = m.saveSubmission(ctx, req.Storage, submission); err != nil {
+				return err
+			}
+			if err = m.recordSubmissionHistory(ctx, req.Storage, submission); err != nil {
+				return err
+			}
+			if submission.Channel != nil {
+				if err = m.releaseChannel(ctx, req.Storage, submission.Channel); err != nil {
+					return err
+				}
+			}
+		case errors.Is(err, horizon.ErrNotFound):
+			if err = m.completeSubmission(ctx, req.Storage, client, submission); err != nil {
+				m.logger.Warn("Resubmission of pending transaction did not succeed", "txHash", hash, "error", err)
+			}
+		default:
+			m.logger.Warn("Failed to look up pending submission", "txHash", hash, "error", err)
+		}
+	}
+	return nil
+}
+
+// PruneSubmissions deletes the submissions settled over submissionRetentionDays ago.
+// Their outcome stays in the signing history. It is run by the backend's periodic function.
+func (m *Manager) PruneSubmissions(ctx context.Context, req *logical.Request) error {
+	cutoff := time.Now().UTC().AddDate(0, 0, -submissionRetentionDays).Format(historyDayLayout)
+	days, err := req.Storage.List(ctx, completedSubmissionsPrefix)
+	if err != nil {
+		return err
+	}
+	pruned := 0
+	for _, day := range days {
+		if !strings.HasSuffix(day, "/") || strings.TrimSuffix(day, "/") >= cutoff {
+			continue
+		}
+		keys, err := req.Storage.List(ctx, completedSubmissionsPrefix+day)
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			publicKey, hash, _ := parseSubmissionIndexKey(key)
+			if err = req.Storage.Delete(ctx, submissionPath(publicKey, hash)); err != nil {
+				return err
+			}
+			if err = req.Storage.Delete(ctx, completedSubmissionsPrefix+day+key); err != nil {
+				return err
+			}
+			pruned++
+		}
+	}
+	if pruned > 0 {
+		m.logger.Debug("Pruned settled submissions", "count", pruned)
+	}
+	return nil
+}
+
+func (s *Submission) responseData() map[string]interface{} {
+	respData := map[string]interface{}{
+		"transaction_hash": s.TxHash,
+		"public_key":       s.PublicKey,
+		"network":          s.Network,
+		"status":           s.Status,
+		"submitted_at":     s.SubmittedAt.Format(time.RFC3339),
+	}
+	if s.Ledger != 0 {
+		respData["ledger"] = s.Ledger
+	}
+	if s.ResultCode != "" {
+		respData["result_code"] = s.ResultCode
+		respData["operation_codes"] = s.OperationCodes
+	}
+	if s.Error != "" {
+		respData["error"] = s.Error
+	}
+	if !s.CompletedAt.IsZero() {
+		respData["completed_at"] = s.CompletedAt.Format(time.RFC3339)
+	}
+	return respData
+}
@@ -0,0 +1,146 @@
+package stellar
+
+import (
+	"fmt"
+	"github.com/stellar/go/txnbuild"
+	"github.com/stellar/go/xdr"
+	"strings"
+	"unicode"
+)
+
+// OperationSummary is the decoded form of an operation shown to the people deciding on
+// a transaction. Only the fields relevant to the operation type are set.
+type OperationSummary struct {
+	Type        string `json:"type"`
+	Source      string `json:"source,omitempty"`
+	Destination string `json:"destination,omitempty"`
+	Asset       string `json:"asset,omitempty"`
+	Amount      string `json:"amount,omitempty"`
+}
+
+// TransactionSummary is the decoded form of a transaction envelope
+type TransactionSummary struct {
+	Source     string             `json:"source"`
+	Sequence   int64              `json:"sequence"`
+	Fee        int64              `json:"fee"`
+	Memo       string             `json:"memo,omitempty"`
+	Operations []OperationSummary `json:"operations"`
+}
+
+func summarizeTransaction(tx *txnbuild.Transaction) *TransactionSummary {
+	envelope := tx.ToXDR()
+	source := envelope.SourceAccount()
+	summary := &TransactionSummary{
+		Source:   source.Address(),
+		Sequence: tx.SequenceNumber(),
+		Fee:      tx.MaxFee(),
+	}
+	if memo := envelope.Memo(); memo.Type != xdr.MemoTypeMemoNone {
+		summary.Memo = memoString(memo)
+	}
+
+	xdrOps := envelope.Operations()
+	for i, op := range tx.Operations() {
+		opSummary := summarizeOperation(op)
+		opSummary.Type = operationTypeName(xdrOps[i].Body.Type)
+		if xdrOps[i].SourceAccount != nil {
+			opSummary.Source = xdrOps[i].SourceAccount.Address()
+		}
+		summary.Operations = append(summary.Operations, opSummary)
+	}
+	return summary
+}
+
+// summarizeOperation extracts the destination, asset and amount of the operations
+// moving funds
+func summarizeOperation(op txnbuild.Operation) OperationSummary {
+	switch o := op.(type) {
+	case *txnbuild.Payment:
+		return OperationSummary{Destination: o.Destination, Asset: assetString(o.Asset), Amount: o.Amount}
+	case *txnbuild.PathPaymentStrictReceive:
+		return OperationSummary{Destination: o.Destination, Asset: assetString(o.SendAsset), Amount: o.SendMax}
+	case *txnbuild.PathPaymentStrictSend:
+		return OperationSummary{Destination: o.Destination, Asset: assetString(o.SendAsset), Amount: o.SendAmount}
+	case *txnbuild.CreateAccount:
+		return OperationSummary{Destination: o.Destination, Asset: "native", Amount: o.Amount}
+	case *txnbuild.AccountMerge:
+		return OperationSummary{Destination: o.Destination}
+	case *txnbuild.CreateClaimableBalance:
+		return OperationSummary{Asset: assetString(o.Asset), Amount: o.Amount}
+	case *txnbuild.ChangeTrust:
+		return OperationSummary{Asset: assetString(o.Line), Amount: o.Limit}
+	}
+	return OperationSummary{}
+}
+
+// assetString formats an asset the way operation descriptions accept it
+func assetString(asset txnbuild.BasicAsset) string {
+	if asset == nil {
+		return ""
+	}
+	if asset.IsNative() {
+		return "native"
+	}
+	if asset.GetCode() == "" {
+		return "liquidity_pool_shares"
+	}
+	return fmt.Sprintf("%s:%s", asset.GetCode(), asset.GetIssuer())
+}
+
+func memoString(memo xdr.Memo) string {
+	switch memo.Type {
+	case xdr.MemoTypeMemoText:
+		return memo.MustText()
+	case xdr.MemoTypeMemoId:
+		return fmt.Sprintf("%d", memo.MustId())
+	case xdr.MemoTypeMemoHash:
+		return fmt.Sprintf("%x", memo.MustHash())
+	case xdr.MemoTypeMemoReturn:
+		return fmt.Sprintf("%x", memo.MustRetHash())
+	}
+	return ""
+}
+
+// operationTypeName turns OperationTypePathPaymentStrictSend into path_payment_strict_send
+func operationTypeName(opType xdr.OperationType) string {
+	name := strings.TrimPrefix(opType.String(), "OperationType")
+	var b strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func (s *TransactionSummary) responseData() map[string]interface{} {
+	operations := make([]map[string]interface{}, 0, len(s.Operations))
+	for _, op := range s.Operations {
+		opData := map[string]interface{}{"type": op.Type}
+		for key, value := range map[string]string{
+			"source":      op.Source,
+			"destination": op.Destination,
+			"asset":       op.Asset,
+			"amount":      op.Amount,
+		} {
+			if value != "" {
+				opData[key] = value
+			}
+		}
+		operations = append(operations, opData)
+	}
+	respData := map[string]interface{}{
+		"source":     s.Source,
+		"sequence":   s.Sequence,
+		"fee":        s.Fee,
+		"operations": operations,
+	}
+	if s.Memo != "" {
+		respData["memo"] = s.Memo
+	}
+	return respData
+}
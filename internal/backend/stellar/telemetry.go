@@ -0,0 +1,65 @@
+package stellar
+
+import (
+	"errors"
+	"github.com/armon/go-metrics"
+)
+
+// Values of the metrics_account_label setting, which decides how signatures and
+// denials are labelled. Large mounts aggregate per role or not at all to keep the
+// number of label values bounded.
+const (
+	MetricsLabelPublicKey = "public_key"
+	MetricsLabelRole      = "role"
+	MetricsLabelNone      = "none"
+)
+
+func validateMetricsAccountLabel(label string) error {
+	switch label {
+	case "", MetricsLabelPublicKey, MetricsLabelRole, MetricsLabelNone:
+		return nil
+	}
+	return invalidRequest("invalid metrics_account_label %q, must be one of %s, %s or %s",
+		label, MetricsLabelPublicKey, MetricsLabelRole, MetricsLabelNone)
+}
+
+// accountLabels returns the labels identifying the signer at the configured cardinality
+func (c *Config) accountLabels(account *Account, sr *signRequest) []metrics.Label {
+	switch c.metricsAccountLabel() {
+	case MetricsLabelRole:
+		role := sr.role
+		if role == "" {
+			role = account.IssuedBy
+		}
+		return []metrics.Label{{Name: "role", Value: role}}
+	case MetricsLabelNone:
+		return nil
+	}
+	return []metrics.Label{{Name: "public_key", Value: account.PublicKey}}
+}
+
+func (c *Config) metricsAccountLabel() string {
+	if c.MetricsAccountLabel == "" {
+		return MetricsLabelPublicKey
+	}
+	return c.MetricsAccountLabel
+}
+
+// countSignature counts a signature per network and signer
+func countSignature(config *Config, account *Account, sr *signRequest) {
+	labels := append([]metrics.Label{{Name: "network", Value: sr.network}}, config.accountLabels(account, sr)...)
+	metrics.IncrCounterWithLabels([]string{"stellar", "signatures"}, 1, labels)
+}
+
+// countDenial counts a policy denial per policy and rule. Other errors are not counted.
+func countDenial(config *Config, account *Account, sr *signRequest, err error) {
+	var denial *PolicyDenial
+	if !errors.As(err, &denial) {
+		return
+	}
+	labels := append([]metrics.Label{
+		{Name: "policy", Value: denial.Policy},
+		{Name: "rule", Value: denial.Rule},
+	}, config.accountLabels(account, sr)...)
+	metrics.IncrCounterWithLabels([]string{"stellar", "policy", "denials"}, 1, labels)
+}
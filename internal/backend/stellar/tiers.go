@@ -0,0 +1,195 @@
+package stellar
+
+import (
+	"fmt"
+	"github.com/hashicorp/vault/sdk/framework"
+	"math/big"
+	"sort"
+)
+
+// ApprovalTier routes the transactions whose value is at most MaxValue, in XLM, to the
+// number of approvals they need. A tier without MaxValue covers any value; a tier
+// with zero approvals signs right away. Tiers without approvers use the approvers of
+// the account's approval rule.
+type ApprovalTier struct {
+	MaxValue       string   `json:"max_value,omitempty"`
+	Approvals      int      `json:"approvals"`
+	Approvers      []string `json:"approvers,omitempty"`
+	ApproverGroups []string `json:"approver_groups,omitempty"`
+	DistinctGroups bool     `json:"distinct_groups,omitempty"`
+}
+
+// valuedOperations move funds whose amount is known before the transaction is applied
+var valuedOperations = map[string]bool{
+	"payment":                     true,
+	"path_payment_strict_receive": true,
+	"path_payment_strict_send":    true,
+	"create_account":              true,
+	"create_claimable_balance":    true,
+}
+
+// freeOperations move no funds and change no control over the account. Every other
+// operation that is not valued, such as offers, pool withdrawals, merges or signer and
+// threshold changes, makes the value of the transaction unknown so that it falls into
+// the highest tier.
+var freeOperations = map[string]bool{
+	"bump_sequence": true,
+	"manage_data":   true,
+}
+
+func approvalTiersFromFieldData(data *framework.FieldData) ([]ApprovalTier, error) {
+	raw, ok := data.GetOk("approval_tiers")
+	if !ok {
+		return nil, nil
+	}
+	var tiers []ApprovalTier
+	if err := decodeObjectList(raw.([]interface{}), &tiers); err != nil {
+		return nil, invalidRequest("invalid approval_tiers: %s", err)
+	}
+
+	unbounded := 0
+	for i := range tiers {
+		tier := &tiers[i]
+		tier.Approvers, tier.ApproverGroups = uniqueStrings(tier.Approvers), uniqueStrings(tier.ApproverGroups)
+		if tier.MaxValue == "" {
+			unbounded++
+		} else if value, ok := new(big.Rat).SetString(tier.MaxValue); !ok || value.Sign() < 0 {
+			return nil, invalidRequest("invalid max_value %q in approval tier %d", tier.MaxValue, i)
+		}
+		if tier.Approvals < 0 {
+			return nil, invalidRequest("approvals must not be negative in approval tier %d", i)
+		}
+		if tier.DistinctGroups && tier.Approvals > len(tier.ApproverGroups) {
+			return nil, invalidRequest("approvals cannot exceed the number of approver_groups in approval tier %d", i)
+		}
+		if len(tier.ApproverGroups) == 0 && len(tier.Approvers) > 0 && tier.Approvals > len(tier.Approvers) {
+			return nil, invalidRequest("approvals cannot exceed the number of approvers in approval tier %d", i)
+		}
+	}
+	if unbounded > 1 {
+		return nil, invalidRequest("only one approval tier may omit max_value")
+	}
+
+	sort.SliceStable(tiers, func(i, j int) bool {
+		if tiers[j].MaxValue == "" {
+			return tiers[i].MaxValue != ""
+		}
+		if tiers[i].MaxValue == "" {
+			return false
+		}
+		a, _ := new(big.Rat).SetString(tiers[i].MaxValue)
+		b, _ := new(big.Rat).SetString(tiers[j].MaxValue)
+		return a.Cmp(b) < 0
+	})
+	return tiers, nil
+}
+
+// approvalTier returns the tier of a transaction of the given value. An unknown value
+// falls into the highest tier. Values above every tier are denied.
+func (p *Policy) approvalTier(value *big.Rat) (*ApprovalTier, error) {
+	highest := &p.ApprovalTiers[len(p.ApprovalTiers)-1]
+	if value == nil {
+		if highest.MaxValue != "" {
+			return nil, &PolicyDenial{Policy: p.Name, Rule: "approval_tiers",
+				Reason: "the value of the transaction cannot be determined and the highest tier is bounded"}
+		}
+		return highest, nil
+	}
+	for i, tier := range p.ApprovalTiers {
+		if tier.MaxValue == "" {
+			return &p.ApprovalTiers[i], nil
+		}
+		maxValue, _ := new(big.Rat).SetString(tier.MaxValue)
+		if value.Cmp(maxValue) <= 0 {
+			return &p.ApprovalTiers[i], nil
+		}
+	}
+	return nil, &PolicyDenial{Policy: p.Name, Rule: "approval_tiers",
+		Reason: fmt.Sprintf("transaction value of %s XLM exceeds the highest approval tier", value.FloatString(7))}
+}
+
+// rule turns the tier into the approval rule a transaction must satisfy
+func (t *ApprovalTier) rule(policyName string, accountRule *ApprovalRule) (*ApprovalRule, error) {
+	rule := &ApprovalRule{
+		Approvers:      t.Approvers,
+		ApproverGroups: t.ApproverGroups,
+		Quorum:         t.Approvals,
+		DistinctGroups: t.DistinctGroups,
+	}
+	if accountRule != nil {
+		rule.TTL = accountRule.TTL
+	}
+	if len(rule.Approvers) == 0 && len(rule.ApproverGroups) == 0 {
+		if accountRule == nil {
+			return nil, forbidden(ErrCodeApprovalRequired, "the approval tier of policy %q requires approvals, but neither the tier nor the account names approvers", policyName)
+		}
+		rule.Approvers = accountRule.Approvers
+		rule.ApproverGroups = accountRule.ApproverGroups
+		rule.DistinctGroups = accountRule.DistinctGroups
+	}
+	return rule, nil
+}
+
+// transactionValue sums the value in XLM of the funds moved by the operations of the
+// transaction. It is nil when the value cannot be determined, because an operation
+// moves an asset missing from the price table, an amount only known on-ledger, or is
+// not valued at all.
+func transactionValue(summary *TransactionSummary, prices map[string]string) *big.Rat {
+	total := new(big.Rat)
+	for _, op := range summary.Operations {
+		if freeOperations[op.Type] {
+			continue
+		}
+		if !valuedOperations[op.Type] {
+			return nil
+		}
+		amount, ok := new(big.Rat).SetString(op.Amount)
+		if !ok {
+			return nil
+		}
+		price := big.NewRat(1, 1)
+		if op.Asset != "native" {
+			if price, ok = new(big.Rat).SetString(prices[op.Asset]); !ok {
+				return nil
+			}
+		}
+		total.Add(total, amount.Mul(amount, price))
+	}
+	return total
+}
+
+// parsePrices validates a price table of assets to their value in XLM
+func parsePrices(raw map[string]string) (map[string]string, error) {
+	prices := map[string]string{}
+	for asset, price := range raw {
+		parsed, err := parseAsset(asset)
+		if err != nil || parsed.IsNative() {
+			return nil, invalidRequest("invalid asset in prices: %s", asset)
+		}
+		if price == "" {
+			continue
+		}
+		if value, ok := new(big.Rat).SetString(price); !ok || value.Sign() < 0 {
+			return nil, invalidRequest("invalid price for %s: %s", asset, price)
+		}
+		prices[assetString(parsed)] = price
+	}
+	return prices, nil
+}
+
+func (t *ApprovalTier) responseData() map[string]interface{} {
+	respData := map[string]interface{}{
+		"approvals": t.Approvals,
+	}
+	if t.MaxValue != "" {
+		respData["max_value"] = t.MaxValue
+	}
+	if len(t.Approvers) > 0 {
+		respData["approvers"] = t.Approvers
+	}
+	if len(t.ApproverGroups) > 0 {
+		respData["approver_groups"] = t.ApproverGroups
+		respData["distinct_groups"] = t.DistinctGroups
+	}
+	return respData
+}
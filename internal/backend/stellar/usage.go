@@ -0,0 +1,299 @@
+package stellar
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"sort"
+	"time"
+)
+
+const (
+	defaultUnusedKeyDays = 90
+	unusedKeysReportPath = "stellar/reports/unused-keys"
+	// unusedKeysReportInterval is how often the periodic function regenerates the report
+	unusedKeysReportInterval = 24 * time.Hour
+)
+
+// UnusedKey is an account that has not signed within the reporting period
+type UnusedKey struct {
+	PublicKey      string     `json:"public_key"`
+	CreatedAt      time.Time  `json:"created_at"`
+	LastSignedAt   *time.Time `json:"last_signed_at,omitempty"`
+	SignatureCount uint64     `json:"signature_count"`
+}
+
+// UnusedKeysReport lists the accounts that have not signed for Days days
+type UnusedKeysReport struct {
+	Days        int64       `json:"days"`
+	GeneratedAt time.Time   `json:"generated_at"`
+	Accounts    []UnusedKey `json:"accounts"`
+}
+
+// accountLimitsFromFieldData applies the signature quota and validity period of the
+// request to the account. Empty timestamps clear the bound.
+func accountLimitsFromFieldData(account *Account, data *framework.FieldData) error {
+	if maxSignatures, ok := data.GetOk("max_signatures"); ok {
+		if maxSignatures.(int) < 0 {
+			return invalidRequest("max_signatures must not be negative")
+		}
+		account.MaxSignatures = uint64(maxSignatures.(int))
+	}
+	var err error
+	if notBefore, ok := data.GetOk("not_before"); ok {
+		if account.NotBefore, err = parseTimestamp("not_before", notBefore.(string)); err != nil {
+			return err
+		}
+	}
+	if notAfter, ok := data.GetOk("not_after"); ok {
+		if account.NotAfter, err = parseTimestamp("not_after", notAfter.(string)); err != nil {
+			return err
+		}
+	}
+	if account.NotBefore != nil && account.NotAfter != nil && !account.NotAfter.After(*account.NotBefore) {
+		return invalidRequest("not_after must be later than not_before")
+	}
+	return nil
+}
+
+func parseTimestamp(field string, value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, invalidRequest("%s must be an RFC 3339 timestamp: %s", field, value)
+	}
+	return &t, nil
+}
+
+// checkUsable refuses accounts outside of their validity period or out of signatures
+func (a *Account) checkUsable(now time.Time) error {
+	if a.NotBefore != nil && now.Before(*a.NotBefore) {
+		return forbidden(ErrCodeAccountUnusable, "the account is not valid before %s", a.NotBefore.Format(time.RFC3339))
+	}
+	if a.NotAfter != nil && !now.Before(*a.NotAfter) {
+		return forbidden(ErrCodeAccountUnusable, "the account expired at %s", a.NotAfter.Format(time.RFC3339))
+	}
+	if a.MaxSignatures != 0 && a.SignatureCount >= a.MaxSignatures {
+		return forbidden(ErrCodeAccountUnusable, "the account reached its quota of %d signatures", a.MaxSignatures)
+	}
+	return nil
+}
+
+// lastUsed returns when the account last signed, or when it was created if it never did
+func (a *Account) lastUsed() time.Time {
+	if a.LastSignedAt != nil {
+		return *a.LastSignedAt
+	}
+	return a.CreatedAt
+}
+
+// checkStoredAccountUsable checks the stored state of the account, which concurrent
+// signatures may have moved past the quota. It must be called with the replay lock held.
+func (m *Manager) checkStoredAccountUsable(ctx context.Context, storage logical.Storage, publicKey string, now time.Time) error {
+	account, err := m.retrieveAccount(ctx, storage, publicKey)
+	if err != nil {
+		return err
+	}
+	if account == nil {
+		return notFound(ErrCodeAccountNotFound, "account not found")
+	}
+	return account.checkUsable(now)
+}
+
+// recordUsage counts a signature of each signer. It must be called with the replay lock held.
+func (m *Manager) recordUsage(ctx context.Context, storage logical.Storage, network string, signers []*Account, now time.Time) error {
+	for _, signer := range signers {
+		account, err := m.retrieveAccount(ctx, storage, signer.PublicKey)
+		if err != nil {
+			return err
+		}
+		if account == nil {
+			continue
+		}
+		account.SignatureCount++
+		if account.NetworkSignatures == nil {
+			account.NetworkSignatures = map[string]uint64{}
+		}
+		account.NetworkSignatures[network]++
+		account.LastSignedAt = &now
+		if err = m.saveAccount(ctx, storage, account); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Manager) ReadUnusedKeysReport(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := m.retrieveConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	days := config.unusedKeyDays()
+	if requestedDays, ok := data.GetOk("days"); ok {
+		if requestedDays.(int) <= 0 {
+			return nil, invalidRequest("days must be positive")
+		}
+		days = int64(requestedDays.(int))
+	}
+
+	// The report of the periodic function is used when it covers the requested period
+	now := time.Now()
+	report, err := m.storedUnusedKeysReport(ctx, req.Storage, days, now)
+	if err != nil {
+		return nil, err
+	}
+	if report == nil {
+		if report, err = m.unusedKeysReport(ctx, req.Storage, days, now); err != nil {
+			return nil, err
+		}
+	}
+
+	keys := make([]string, 0, len(report.Accounts))
+	for _, key := range report.Accounts {
+		keys = append(keys, key.PublicKey)
+	}
+	if keys, err = m.filterAccessibleAccounts(ctx, req, keys); err != nil {
+		return nil, err
+	}
+	return &logical.Response{
+		Data: report.responseData(keys),
+	}, nil
+}
+
+// ReportUnusedKeys regenerates the unused keys report once a day, or when the reporting
+// period of the configuration changed.
+func (m *Manager) ReportUnusedKeys(ctx context.Context, req *logical.Request) error {
+	config, err := m.retrieveConfig(ctx, req.Storage)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	report, err := m.storedUnusedKeysReport(ctx, req.Storage, config.unusedKeyDays(), now)
+	if err != nil || report != nil {
+		return err
+	}
+	if report, err = m.unusedKeysReport(ctx, req.Storage, config.unusedKeyDays(), now); err != nil {
+		return err
+	}
+	entry, err := logical.StorageEntryJSON(unusedKeysReportPath, report)
+	if err != nil {
+		return err
+	}
+	if err = req.Storage.Put(ctx, entry); err != nil {
+		m.logger.Error("Failed to save the unused keys report", "error", err)
+		return err
+	}
+	return nil
+}
+
+// storedUnusedKeysReport returns the report of the periodic function, or nil if it is
+// stale or covers another number of days
+func (m *Manager) storedUnusedKeysReport(ctx context.Context, storage logical.Storage, days int64, now time.Time) (*UnusedKeysReport, error) {
+	entry, err := storage.Get(ctx, unusedKeysReportPath)
+	if err != nil {
+		m.logger.Error("Failed to retrieve the unused keys report", "error", err)
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+	var report UnusedKeysReport
+	if err = entry.DecodeJSON(&report); err != nil {
+		return nil, err
+	}
+	if report.Days != days || now.Sub(report.GeneratedAt) >= unusedKeysReportInterval {
+		return nil, nil
+	}
+	return &report, nil
+}
+
+// unusedKeysReport lists the accounts that have not signed for the given number of
+// days. Accounts stored before usage was tracked count as unused until they sign.
+func (m *Manager) unusedKeysReport(ctx context.Context, storage logical.Storage, days int64, now time.Time) (*UnusedKeysReport, error) {
+	publicKeys, err := storage.List(ctx, "stellar/accounts/")
+	if err != nil {
+		m.logger.Error("Failed to list stellar accounts", "error", err)
+		return nil, fmt.Errorf("failed to list stellar accounts: %s", err)
+	}
+
+	cutoff := now.Add(-time.Duration(days) * 24 * time.Hour)
+	report := &UnusedKeysReport{Days: days, GeneratedAt: now, Accounts: []UnusedKey{}}
+	for _, publicKey := range publicKeys {
+		account, err := m.retrieveAccount(ctx, storage, publicKey)
+		if err != nil {
+			return nil, err
+		}
+		if account == nil || account.lastUsed().After(cutoff) {
+			continue
+		}
+		report.Accounts = append(report.Accounts, UnusedKey{
+			PublicKey:      account.PublicKey,
+			CreatedAt:      account.CreatedAt,
+			LastSignedAt:   account.LastSignedAt,
+			SignatureCount: account.SignatureCount,
+		})
+	}
+	sort.Slice(report.Accounts, func(i, j int) bool {
+		return report.Accounts[i].PublicKey < report.Accounts[j].PublicKey
+	})
+	return report, nil
+}
+
+// unusedKeyDays returns after how many days without a signature a key is reported as unused
+func (c *Config) unusedKeyDays() int64 {
+	if c.UnusedKeyDays == 0 {
+		return defaultUnusedKeyDays
+	}
+	return c.UnusedKeyDays
+}
+
+// usageResponseData describes the usage and limits of the account
+func (a *Account) usageResponseData() map[string]interface{} {
+	respData := map[string]interface{}{
+		"signature_count": a.SignatureCount,
+	}
+	if len(a.NetworkSignatures) > 0 {
+		respData["network_signatures"] = a.NetworkSignatures
+	}
+	if !a.CreatedAt.IsZero() {
+		respData["created_at"] = a.CreatedAt.Format(time.RFC3339)
+	}
+	if a.LastSignedAt != nil {
+		respData["last_signed_at"] = a.LastSignedAt.Format(time.RFC3339)
+	}
+	if a.MaxSignatures != 0 {
+		respData["max_signatures"] = a.MaxSignatures
+	}
+	if a.NotBefore != nil {
+		respData["not_before"] = a.NotBefore.Format(time.RFC3339)
+	}
+	if a.NotAfter != nil {
+		respData["not_after"] = a.NotAfter.Format(time.RFC3339)
+	}
+	return respData
+}
+
+// responseData describes the report, limited to the given accounts
+func (r *UnusedKeysReport) responseData(publicKeys []string) map[string]interface{} {
+	keyInfo := map[string]interface{}{}
+	for _, key := range r.Accounts {
+		info := map[string]interface{}{
+			"signature_count": key.SignatureCount,
+		}
+		if !key.CreatedAt.IsZero() {
+			info["created_at"] = key.CreatedAt.Format(time.RFC3339)
+		}
+		if key.LastSignedAt != nil {
+			info["last_signed_at"] = key.LastSignedAt.Format(time.RFC3339)
+		}
+		keyInfo[key.PublicKey] = info
+	}
+	respData := logical.ListResponseWithInfo(publicKeys, keyInfo).Data
+	respData["days"] = r.Days
+	respData["generated_at"] = r.GeneratedAt.Format(time.RFC3339)
+	return respData
+}
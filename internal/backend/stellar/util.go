@@ -0,0 +1,46 @@
+package stellar
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAny(list []string, items []string) bool {
+	for _, item := range items {
+		if containsString(list, item) {
+			return true
+		}
+	}
+	return false
+}
+
+// uniqueStrings returns the list without its repeated strings, in order
+func uniqueStrings(list []string) []string {
+	var unique []string
+	for _, item := range list {
+		if !containsString(unique, item) {
+			unique = append(unique, item)
+		}
+	}
+	return unique
+}
+
+// sameStrings reports whether both lists hold the same set of strings, in any order
+// and however many times each
+func sameStrings(a []string, b []string) bool {
+	for _, item := range a {
+		if !containsString(b, item) {
+			return false
+		}
+	}
+	for _, item := range b {
+		if !containsString(a, item) {
+			return false
+		}
+	}
+	return true
+}
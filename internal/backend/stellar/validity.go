@@ -0,0 +1,137 @@
+package stellar
+
+import (
+	"fmt"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/stellar/go/xdr"
+	"time"
+)
+
+// ValidityPolicy puts guardrails on how long a signed transaction stays valid and
+// on the CAP-21 preconditions it must carry.
+type ValidityPolicy struct {
+	RequireTimeBounds    bool     `json:"require_time_bounds"`
+	MaxValidityWindow    int64    `json:"max_validity_window"`
+	RequireLedgerBounds  bool     `json:"require_ledger_bounds"`
+	MinSequenceAge       uint64   `json:"min_sequence_age"`
+	MinSequenceLedgerGap uint32   `json:"min_sequence_ledger_gap"`
+	RequiredExtraSigners []string `json:"required_extra_signers"`
+}
+
+func validityPolicyFromFieldData(data *framework.FieldData) (*ValidityPolicy, error) {
+	requireTimeBounds, hasTimeBounds := data.GetOk("require_time_bounds")
+	maxValidityWindow, hasWindow := data.GetOk("max_validity_window")
+	requireLedgerBounds, hasLedgerBounds := data.GetOk("require_ledger_bounds")
+	minSequenceAge, hasSeqAge := data.GetOk("min_sequence_age")
+	minSequenceLedgerGap, hasSeqGap := data.GetOk("min_sequence_ledger_gap")
+	extraSigners, hasExtraSigners := data.GetOk("required_extra_signers")
+	if !hasTimeBounds && !hasWindow && !hasLedgerBounds && !hasSeqAge && !hasSeqGap && !hasExtraSigners {
+		return nil, nil
+	}
+
+	vp := &ValidityPolicy{RequiredExtraSigners: []string{}}
+	if hasTimeBounds {
+		vp.RequireTimeBounds = requireTimeBounds.(bool)
+	}
+	if hasWindow {
+		if maxValidityWindow.(int) < 0 {
+			return nil, invalidRequest("max_validity_window must not be negative")
+		}
+		vp.MaxValidityWindow = int64(maxValidityWindow.(int))
+	}
+	if hasLedgerBounds {
+		vp.RequireLedgerBounds = requireLedgerBounds.(bool)
+	}
+	if hasSeqAge {
+		if minSequenceAge.(int) < 0 {
+			return nil, invalidRequest("min_sequence_age must not be negative")
+		}
+		vp.MinSequenceAge = uint64(minSequenceAge.(int))
+	}
+	if hasSeqGap {
+		if minSequenceLedgerGap.(int) < 0 {
+			return nil, invalidRequest("min_sequence_ledger_gap must not be negative")
+		}
+		vp.MinSequenceLedgerGap = uint32(minSequenceLedgerGap.(int))
+	}
+	if hasExtraSigners {
+		for _, signer := range extraSigners.([]string) {
+			var key xdr.SignerKey
+			if err := key.SetAddress(signer); err != nil {
+				return nil, invalidRequest("invalid extra signer %q: %s", signer, err)
+			}
+			vp.RequiredExtraSigners = append(vp.RequiredExtraSigners, signer)
+		}
+	}
+	return vp, nil
+}
+
+// checkNotExpired rejects a transaction whose max time is already past, whether or not
+// a validity policy applies, as it can never be included in a ledger
+func checkNotExpired(envelope xdr.TransactionEnvelope, now time.Time) error {
+	timeBounds := envelope.TimeBounds()
+	if timeBounds == nil || timeBounds.MaxTime == 0 || int64(timeBounds.MaxTime) >= now.Unix() {
+		return nil
+	}
+	return badRequest(ErrCodeTransactionExpired, "transaction max time %s is already past",
+		time.Unix(int64(timeBounds.MaxTime), 0).UTC().Format(time.RFC3339))
+}
+
+func (vp *ValidityPolicy) evaluate(policyName string, envelope xdr.TransactionEnvelope, now time.Time) error {
+	deny := func(rule, format string, args ...interface{}) error {
+		return &PolicyDenial{Policy: policyName, Rule: rule, Reason: fmt.Sprintf(format, args...)}
+	}
+
+	timeBounds := envelope.TimeBounds()
+	var maxTime int64
+	if timeBounds != nil {
+		maxTime = int64(timeBounds.MaxTime)
+	}
+	if vp.RequireTimeBounds && maxTime == 0 {
+		return deny("require_time_bounds", "transaction must set a max time")
+	}
+	if maxTime != 0 && maxTime < now.Unix() {
+		return deny("max_time", "transaction max time %s is already past",
+			time.Unix(maxTime, 0).UTC().Format(time.RFC3339))
+	}
+	if vp.MaxValidityWindow > 0 {
+		if maxTime == 0 {
+			return deny("max_validity_window", "transaction without a max time exceeds the validity window of %ds",
+				vp.MaxValidityWindow)
+		}
+		if window := maxTime - now.Unix(); window > vp.MaxValidityWindow {
+			return deny("max_validity_window", "transaction is valid for %ds, maximum is %ds", window, vp.MaxValidityWindow)
+		}
+	}
+
+	if vp.RequireLedgerBounds {
+		if ledgerBounds := envelope.LedgerBounds(); ledgerBounds == nil || ledgerBounds.MaxLedger == 0 {
+			return deny("require_ledger_bounds", "transaction must set a max ledger")
+		}
+	}
+	if vp.MinSequenceAge > 0 {
+		if age := envelope.MinSeqAge(); age == nil || uint64(*age) < vp.MinSequenceAge {
+			return deny("min_sequence_age", "transaction must set a min sequence age of at least %ds", vp.MinSequenceAge)
+		}
+	}
+	if vp.MinSequenceLedgerGap > 0 {
+		if gap := envelope.MinSeqLedgerGap(); gap == nil || uint32(*gap) < vp.MinSequenceLedgerGap {
+			return deny("min_sequence_ledger_gap", "transaction must set a min sequence ledger gap of at least %d",
+				vp.MinSequenceLedgerGap)
+		}
+	}
+	if len(vp.RequiredExtraSigners) > 0 {
+		present := []string{}
+		for _, signer := range envelope.ExtraSigners() {
+			if address, err := signer.GetAddress(); err == nil {
+				present = append(present, address)
+			}
+		}
+		for _, required := range vp.RequiredExtraSigners {
+			if !containsString(present, required) {
+				return deny("required_extra_signers", "transaction is missing extra signer %s", required)
+			}
+		}
+	}
+	return nil
+}
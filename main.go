@@ -1,21 +1,50 @@
 package main
 
 import (
+	"fmt"
+	"github.com/armon/go-metrics"
+	"github.com/armon/go-metrics/prometheus"
 	"github.com/hashicorp/vault/api"
 	"github.com/hashicorp/vault/sdk/plugin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"log"
+	"net"
+	"net/http"
 	"os"
+	"strings"
 	"vault-plugin-stellar-sign/internal/backend"
+	"vault-plugin-stellar-sign/internal/backend/paths"
+)
+
+const (
+	// metricsSinkEnv names the sink the plugin sends its metrics to, as a go-metrics sink
+	// URL such as statsd://127.0.0.1:8125. The plugin runs in its own process, so its
+	// metrics do not reach Vault's telemetry.
+	metricsSinkEnv = "STELLAR_METRICS_SINK"
+	// metricsPrometheusAddressEnv names the address, such as 127.0.0.1:9102, the plugin
+	// serves its metrics on at /metrics for Prometheus to scrape
+	metricsPrometheusAddressEnv = "STELLAR_METRICS_PROMETHEUS_ADDRESS"
 )
 
 func main() {
 	apiClientMeta := &api.PluginAPIClientMeta{}
 	flags := apiClientMeta.FlagSet()
+	auditTuneFlags := flags.Bool("audit-tune-flags", false,
+		"Print the flags of vault secrets enable or tune keeping the audit-safe fields readable, and exit.")
 	err := flags.Parse(os.Args[1:])
 	if err != nil {
 		log.Println(err)
 		os.Exit(1)
 	}
+	if *auditTuneFlags {
+		fmt.Println(strings.Join(paths.AuditTuneFlags(), " "))
+		return
+	}
+
+	if err = configureMetrics(os.Getenv(metricsSinkEnv), os.Getenv(metricsPrometheusAddressEnv)); err != nil {
+		log.Println(err)
+		os.Exit(1)
+	}
 
 	tlsConfig := apiClientMeta.GetTLSConfig()
 	tlsProviderFunc := api.VaultPluginTLSProvider(tlsConfig)
@@ -29,3 +58,43 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// configureMetrics sends the metrics of the plugin to the sink at sinkURL and serves
+// them for Prometheus at prometheusAddress. Without either, go-metrics discards them.
+func configureMetrics(sinkURL string, prometheusAddress string) error {
+	var sinks metrics.FanoutSink
+	if sinkURL != "" {
+		sink, err := metrics.NewMetricSinkFromURL(sinkURL)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %s", metricsSinkEnv, err)
+		}
+		sinks = append(sinks, sink)
+	}
+	if prometheusAddress != "" {
+		sink, err := prometheus.NewPrometheusSink()
+		if err != nil {
+			return fmt.Errorf("failed to configure the Prometheus sink: %s", err)
+		}
+		listener, err := net.Listen("tcp", prometheusAddress)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %s", metricsPrometheusAddressEnv, err)
+		}
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.Handler())
+		go func() {
+			log.Println(http.Serve(listener, mux))
+		}()
+		sinks = append(sinks, sink)
+	}
+	if len(sinks) == 0 {
+		return nil
+	}
+
+	config := metrics.DefaultConfig("")
+	config.EnableHostname = false
+	config.EnableRuntimeMetrics = false
+	if _, err := metrics.NewGlobal(config, sinks); err != nil {
+		return fmt.Errorf("failed to configure metrics: %s", err)
+	}
+	return nil
+}